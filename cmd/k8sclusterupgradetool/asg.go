@@ -1,8 +1,13 @@
 package k8sclusterupgradetool
 
 import (
+	"context"
 	"fmt"
+	awsSdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"log"
 )
 
 var asgCmd = &cobra.Command{
@@ -16,3 +21,35 @@ var asgCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(asgCmd)
 }
+
+// verifyAsgOwnership refuses to go any further unless the ASG is tagged as belonging to the cluster and, when a k8s
+// client is passed, every instance of the ASG is running and is a node of the cluster
+func verifyAsgOwnership(k8sClient kubernetes.Interface, cluster string, asg aws.AutoScalingGroup) {
+	if err := asg.VerifyClusterOwnership(cluster); err != nil {
+		log.Fatalf("Refusing to touch the autoscaling group: %v", err)
+	}
+	if k8sClient == nil {
+		return
+	}
+	// only the running instances are matched with the nodes, the ones which are not would be silently left out
+	if err := asg.VerifyInstancesRunning(); err != nil {
+		log.Fatalf("Refusing to touch the autoscaling group: %v", err)
+	}
+	if err := asg.Instances.VerifyNodesInCluster(k8sClient, cluster); err != nil {
+		log.Fatalf("Refusing to touch the autoscaling group: %v", err)
+	}
+}
+
+// resolveAsgName returns the name of the ASG passed with -a, which can either be the ASG name or the name of the EKS
+// managed node group it backs
+func resolveAsgName(cfg awsSdk.Config, cluster, name string) string {
+	finder := &aws.AutoscalingGroupFinder{ListAutoscalingGroupsInterface: &aws.ClusterAutoScalingGroupsClient{}}
+	asg, err := finder.ResolveAutoScalingGroupName(context.TODO(), cfg, cluster, name)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if asg != name {
+		log.Printf("Managed node group %s is backed by autoscaling group %s\n", name, asg)
+	}
+	return asg
+}
@@ -0,0 +1,137 @@
+package k8sclusterupgradetool
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/config"
+	toolConfig "github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var asgListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the ASGs and managed node groups of a cluster",
+	Long: `Lists the ASGs of a cluster, found by their kubernetes.io/cluster/CLUSTER_NAME or eks:cluster-name=CLUSTER_NAME
+tag, along with the EKS managed node group they back, their instance count, launch template version and the kubelet
+versions of their nodes.
+
+Either the ASG name or the managed node group name listed can be passed with -a to the other asg commands.
+
+Usage:
+$ k8sclusterupgradetool asg list -c=CLUSTER_NAME
+
+Example:
+$ k8sclusterupgradetool asg list -c=valid-cluster-name
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+
+		// Read config from file
+		configFileName, configFileType, configFilePath := toolConfig.FileMetadata()
+		configuration, err := toolConfig.Read(configFileName, configFileType, configFilePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Config file used:", viper.ConfigFileUsed())
+
+		if !configuration.IsClusterNameValid(cluster) {
+			log.Fatalln("Please pass a valid clusterName or check if the AWS account has a mapping inside the tool for the account and the region")
+		}
+		awsAccount, awsRegion, err := configuration.GetAwsAccountAndRegionForCluster(cluster)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		k8sClient, err := k8s.KubeClientInit(cluster)
+		if err != nil {
+			log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
+		}
+
+		awsGetterObj := &aws.ConfigGetter{ConfigClientInterface: &aws.Config{}}
+		cfg, err := awsGetterObj.GetConfig(context.TODO(), config.WithRegion(awsRegion), config.WithSharedConfigProfile(awsAccount))
+		if err != nil {
+			log.Fatalln("there was an error while initializing the aws config, please check your aws credentials")
+		}
+
+		finder := &aws.AutoscalingGroupFinder{ListAutoscalingGroupsInterface: &aws.ClusterAutoScalingGroupsClient{}}
+		groups, err := finder.ClusterAutoScalingGroups(context.TODO(), cfg, cluster)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		kubeletVersions, err := k8s.GetNodeKubeletVersions(k8sClient)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ASG\tNODE GROUP\tINSTANCES\tMIN/MAX/DESIRED\tLAUNCH TEMPLATE\tKUBELET VERSIONS")
+		for _, group := range groups {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d/%d/%d\t%s\t%s\n", group.AsgName, orNone(group.NodeGroupName()),
+				group.Instances.Count(), group.MinInstances, group.MaxInstances, group.DesiredInstances,
+				launchTemplate(group), strings.Join(asgKubeletVersions(group, kubeletVersions), ","))
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	asgCmd.AddCommand(asgListCmd)
+
+	asgListCmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	//nolint
+	asgListCmd.MarkFlagRequired("cluster")
+}
+
+// asgKubeletVersions returns the distinct kubelet versions of the nodes of the ASG, sorted
+func asgKubeletVersions(group aws.AutoScalingGroup, kubeletVersions map[string]string) []string {
+	seen := map[string]bool{}
+	var versions []string
+	for _, instance := range group.Instances {
+		version, ok := kubeletVersions[instance.PrivateDNS]
+		if !ok {
+			version = "not-registered"
+		}
+		if !seen[version] {
+			seen[version] = true
+			versions = append(versions, version)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// asgDrained tells whether all the instances of the ASG are nodes which are cordoned, as left by 'asg taint-and-drain'
+func asgDrained(group aws.AutoScalingGroup, cordonedNodes map[string]bool) bool {
+	if len(group.Instances) == 0 {
+		return false
+	}
+	for _, instance := range group.Instances {
+		if !cordonedNodes[instance.PrivateDNS] {
+			return false
+		}
+	}
+	return true
+}
+
+func launchTemplate(group aws.AutoScalingGroup) string {
+	if group.LaunchTemplateName == "" {
+		return "<none>"
+	}
+	return group.LaunchTemplateName + ":" + group.LaunchTemplateVersion
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+package k8sclusterupgradetool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/config"
+	toolConfig "github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/state"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+)
+
+var asgRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restores the original min, max and desired count of an ASG",
+	Long: `Restores the min, max and desired count of an ASG to the values it had before k8sclusterupgradetool changed them,
+for when an upgrade was interrupted or failed midway, or once the nodes of the ASG have been replaced after a taint-and-drain.
+
+The original values are read from the snapshot saved locally in $HOME/.k8sclusterupgradetool/state/CLUSTER_NAME by the
+first run of 'asg taint-and-drain' or 'asg rotate' against the ASG, the snapshot is removed once the ASG is restored.
+Until then, a new run of 'asg taint-and-drain' or 'asg rotate' which doesn't resume the earlier one refuses to reuse it.
+
+Usage:
+$ k8sclusterupgradetool asg restore -c=CLUSTER_NAME -a=ASG_NAME
+
+Example:
+$ k8sclusterupgradetool asg restore -c=valid-cluster-name -a=valid-cluster-name-spot-hash
+$ k8sclusterupgradetool asg restore -c=valid-cluster-name -a=valid-cluster-name-spot-hash --dry-run=false
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		asg, _ := cmd.Flags().GetString("autoscaling-group")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		// Read config from file
+		configFileName, configFileType, configFilePath := toolConfig.FileMetadata()
+		configuration, err := toolConfig.Read(configFileName, configFileType, configFilePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Config file used:", viper.ConfigFileUsed())
+
+		if !configuration.IsClusterNameValid(cluster) {
+			log.Fatalln("Please pass a valid clusterName or check if the AWS account has a mapping inside the tool for the account and the region")
+		}
+		awsAccount, awsRegion, err := configuration.GetAwsAccountAndRegionForCluster(cluster)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		awsGetterObj := &aws.ConfigGetter{ConfigClientInterface: &aws.Config{}}
+		cfg, err := awsGetterObj.GetConfig(context.TODO(), config.WithRegion(awsRegion), config.WithSharedConfigProfile(awsAccount))
+		if err != nil {
+			log.Fatalln("there was an error while initializing the aws config, please check your aws credentials")
+		}
+		asg = resolveAsgName(cfg, cluster, asg)
+
+		store := state.NewStore()
+		snapshot, err := store.LoadAsgSnapshot(cluster, asg)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		awsUpdateAsgObj := &aws.AutoscalingGroupUpdater{
+			UpdateAutoscalingGroupInterface: &aws.AutoScalingGroupClient{Asg: aws.AutoScalingGroup{AsgName: asg}},
+		}
+		asgObject, err := awsUpdateAsgObj.DescribeAutoScalingGroup(context.TODO(), cfg)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		// only the tags are checked, the instances of an interrupted upgrade may not have registered as nodes
+		verifyAsgOwnership(nil, cluster, asgObject)
+		current := asgObject.Capacity()
+		log.Printf("Autoscaling group %s currently has min: %d, max: %d, desired: %d\n", asg, current.Min, current.Max, current.Desired)
+		log.Printf("Autoscaling group %s will be restored to min: %d, max: %d, desired: %d from the snapshot taken at %s\n",
+			asg, snapshot.Min, snapshot.Max, snapshot.Desired, snapshot.CapturedAt)
+
+		if dryRun {
+			log.Println("Running restore command in dry mode, no changes were made")
+			return
+		}
+
+		journal := openJournal(cmd, cluster, "restore", asg)
+		err = awsUpdateAsgObj.Restore(context.TODO(), cfg, snapshotCapacity(snapshot))
+		if err != nil {
+			log.Fatalf("Restoring autoscaling group %s failed: %v", asg, err)
+		}
+		if err := journal.Record(aws.StepAsgCapacityRestored); err != nil {
+			log.Println(err)
+		}
+		finishJournal(journal)
+		if err := store.DeleteAsgSnapshot(cluster, asg); err != nil {
+			log.Println(err)
+		}
+		log.Printf("Autoscaling group %s has been restored\n", asg)
+	},
+}
+
+func init() {
+	asgCmd.AddCommand(asgRestoreCmd)
+
+	asgRestoreCmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	asgRestoreCmd.Flags().StringP("autoscaling-group", "a", "",
+		"ASG name or EKS managed node group name, eg: valid-cluster-name-spot-hash, run asg list to see the ones of the cluster")
+	asgRestoreCmd.Flags().Bool("dry-run", true,
+		"will only show the capacity the ASG would be restored to")
+	//nolint
+	asgRestoreCmd.MarkFlagRequired("cluster")
+	//nolint
+	asgRestoreCmd.MarkFlagRequired("autoscaling-group")
+}
+
+// saveAsgSnapshot stores the capacity of the ASG before it is changed, keeping the snapshot taken earlier in the run
+// recorded by the journal, the interrupted one it resumes included. A snapshot left behind by an earlier run is refused
+func saveAsgSnapshot(cluster, asg string, capacity aws.AutoScalingGroupCapacity, journal *state.Journal) (state.AsgSnapshot, error) {
+	snapshot, err := state.NewStore().SaveAsgSnapshot(state.AsgSnapshot{
+		ClusterName: cluster,
+		AsgName:     asg,
+		Min:         capacity.Min,
+		Max:         capacity.Max,
+		Desired:     capacity.Desired,
+	}, journal.StartedAt)
+	if errors.Is(err, state.ErrStaleSnapshot) {
+		return state.AsgSnapshot{}, fmt.Errorf("%v: put the original capacity of the ASG back with 'k8sclusterupgradetool asg restore' "+
+			"first, continue the earlier run with --resume, or remove the snapshot if the current capacity of the ASG is its original one", err)
+	}
+	return snapshot, err
+}
+
+func snapshotCapacity(snapshot state.AsgSnapshot) aws.AutoScalingGroupCapacity {
+	return aws.AutoScalingGroupCapacity{Min: snapshot.Min, Max: snapshot.Max, Desired: snapshot.Desired}
+}
@@ -0,0 +1,155 @@
+package k8sclusterupgradetool
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/config"
+	toolConfig "github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/state"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+	"os"
+	"time"
+)
+
+var asgRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replaces all the nodes of an ASG with new ones",
+	Long: `Rotates all the instances of an ASG, to roll out a new launch template version or AMI to the node group.
+
+The ASG has to carry the kubernetes.io/cluster/CLUSTER_NAME or the eks:cluster-name=CLUSTER_NAME tag and all its
+instances have to be nodes of the cluster, otherwise the command refuses to go any further.
+
+It first doubles the desired count of the ASG and waits for the new instances to register as Ready nodes.
+taints and drains the old nodes
+terminates the old instances while decrementing the desired count of the ASG
+restores the original min, max and desired count of the ASG
+
+Every completed step is recorded in a journal in $HOME/.k8sclusterupgradetool/state/CLUSTER_NAME, if the command is
+interrupted, running it again with --resume continues from the last completed step with the old instances recorded when
+the ASG was scaled out.
+
+Usage:
+$ k8sclusterupgradetool asg rotate -c=CLUSTER_NAME -a=ASG_NAME
+
+Example:
+$ k8sclusterupgradetool asg rotate -c=valid-cluster-name -a=valid-cluster-name-spot-hash
+$ k8sclusterupgradetool asg rotate -c=valid-cluster-name -a=valid-cluster-name-spot-hash --dry-run=false
+$ k8sclusterupgradetool asg rotate -c=valid-cluster-name -a=valid-cluster-name-spot-hash --dry-run=false --resume
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		asg, _ := cmd.Flags().GetString("autoscaling-group")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		nodeReadyTimeout, _ := cmd.Flags().GetDuration("node-ready-timeout")
+		drainOptions, err := drainOptionsFromFlags(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		drainBatchOptions, err := drainBatchOptionsFromFlags(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		// Read config from file
+		configFileName, configFileType, configFilePath := toolConfig.FileMetadata()
+		configuration, err := toolConfig.Read(configFileName, configFileType, configFilePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Config file used:", viper.ConfigFileUsed())
+
+		if !configuration.IsClusterNameValid(cluster) {
+			log.Fatalln("Please pass a valid clusterName or check if the AWS account has a mapping inside the tool for the account and the region")
+		}
+		awsAccount, awsRegion, err := configuration.GetAwsAccountAndRegionForCluster(cluster)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		k8sClient, err := k8s.KubeClientInit(cluster)
+		if err != nil {
+			log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
+		}
+
+		awsGetterObj := &aws.ConfigGetter{ConfigClientInterface: &aws.Config{}}
+		cfg, err := awsGetterObj.GetConfig(context.TODO(), config.WithRegion(awsRegion), config.WithSharedConfigProfile(awsAccount))
+		if err != nil {
+			log.Fatalln("there was an error while initializing the aws config, please check your aws credentials")
+		}
+		asg = resolveAsgName(cfg, cluster, asg)
+
+		awsAsgClient := &aws.AutoScalingGroupClient{Asg: aws.AutoScalingGroup{AsgName: asg}}
+		asgObject, err := awsAsgClient.DescribeAutoScalingGroup(context.TODO(), cfg)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		resume, _ := cmd.Flags().GetBool("resume")
+		if resume {
+			// the replacement instances may not have registered as nodes yet when resuming
+			verifyAsgOwnership(nil, cluster, asgObject)
+		} else {
+			verifyAsgOwnership(k8sClient, cluster, asgObject)
+		}
+		log.Printf("Autoscaling group %s currently has min: %d, max: %d, desired: %d\n",
+			asg, asgObject.MinInstances, asgObject.MaxInstances, asgObject.DesiredInstances)
+		log.Println("Instances which are going to be replaced from the ASG passed")
+		asgObject.Instances.PrettyPrint()
+
+		if dryRun {
+			log.Println("Running rotate command in dry mode, no changes were made")
+			return
+		}
+
+		journal := openJournal(cmd, cluster, "rotate", asg)
+		snapshot, err := saveAsgSnapshot(cluster, asg, asgObject.Capacity(), journal)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		rotator := &aws.AutoscalingGroupRotator{
+			RotateAutoscalingGroupInterface: awsAsgClient,
+			K8sClient:                       k8sClient,
+			DrainOptions:                    drainOptions,
+			DrainBatchOptions:               drainBatchOptions,
+			NodeReadyTimeout:                nodeReadyTimeout,
+			PollInterval:                    15 * time.Second,
+			Journal:                         journal,
+		}
+		result, err := rotator.Rotate(context.TODO(), cfg, snapshotCapacity(snapshot))
+		printDrainReport(result.DrainResults)
+		if err != nil {
+			log.Printf("Rotation of autoscaling group %s failed: %v\n", asg, err)
+			log.Println("Run the command again with --resume to continue from the last completed step, or put the original" +
+				" capacity of the ASG back with 'k8sclusterupgradetool asg restore'")
+			os.Exit(1)
+		}
+		finishJournal(journal)
+		if err := state.NewStore().DeleteAsgSnapshot(cluster, asg); err != nil {
+			log.Println(err)
+		}
+		log.Printf("Autoscaling group %s has been rotated, %d instances were replaced\n", asg, result.TerminatedInstances.Count())
+	},
+}
+
+func init() {
+	asgCmd.AddCommand(asgRotateCmd)
+
+	asgRotateCmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	asgRotateCmd.Flags().StringP("autoscaling-group", "a", "",
+		"ASG name or EKS managed node group name, eg: valid-cluster-name-spot-hash, run asg list to see the ones of the cluster")
+	asgRotateCmd.Flags().Bool("dry-run", true,
+		"will only show the instances which will be replaced")
+	asgRotateCmd.Flags().Duration("node-ready-timeout", 15*time.Minute,
+		"maximum time to wait for the new instances to register as Ready nodes")
+	addDrainFlags(asgRotateCmd)
+	addDrainBatchFlags(asgRotateCmd)
+	addResumeFlag(asgRotateCmd)
+	//nolint
+	asgRotateCmd.MarkFlagRequired("cluster")
+	//nolint
+	asgRotateCmd.MarkFlagRequired("autoscaling-group")
+}
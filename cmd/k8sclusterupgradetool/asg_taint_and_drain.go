@@ -2,13 +2,19 @@ package k8sclusterupgradetool
 
 import (
 	"context"
+	"errors"
+	awsSdk "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	toolConfig "github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
 	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
 	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
 	"log"
+	"os"
+	"time"
 )
 
 var DryRunFlag bool
@@ -19,25 +25,53 @@ var nodeTaintAndDrainCmd = &cobra.Command{
 	Long: `k8sclusterupgradetool helps you taint and drain an ASG in an automated fashion by taking input of the ASG name, nodes of
 which you would want to drain and taint later.
 
-It first sets the max instance count of the ASG to the current desired count.
+It first checks that the ASG carries the kubernetes.io/cluster/CLUSTER_NAME or the eks:cluster-name=CLUSTER_NAME tag
+and that all its instances are nodes of the cluster, refusing to go any further otherwise.
+
+It then sets the max instance count of the ASG to the current desired count, the original capacity of the ASG is saved
+in a local snapshot and can be put back with 'k8sclusterupgradetool asg restore' once the upgrade is done.
 taints the nodes in the ASG
 drains the nodes in the ASG
 
+Pods are evicted honouring their PodDisruptionBudgets, evictions refused by a PodDisruptionBudget are retried with a
+backoff until --node-drain-timeout, after which --drain-timeout-policy decides whether to abort, skip the node or
+delete the blocked pods. A report of the pods left behind along with the PodDisruptionBudget blocking them is printed
+at the end.
+
+Every completed step is recorded in a journal in $HOME/.k8sclusterupgradetool/state/CLUSTER_NAME, if the command is
+interrupted, running it again with --resume continues from the last completed step, the nodes already tainted and drained
+are skipped and the ASG bounds are not captured again.
+
+Nodes are drained in batches of --max-unavailable (a count or a percentage of the nodes) at the same time, before
+moving on to the next batch the workloads evicted in the batch are waited for to be ready again.
+
 Usage:
 $ k8sclusterupgradetool asg taint-and-drain -c=CLUSTER_NAME -a=ASG_NAME
 
 Example:
 $ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=valid-cluster-name-spot-hash
 $ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=valid-cluster-name-spot-hash --dry-run=false
+$ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=valid-cluster-name-spot-hash --dry-run=false --node-drain-timeout=10m --drain-timeout-policy=skip
+$ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=valid-cluster-name-spot-hash --dry-run=false --max-unavailable=25% --pause-between-batches=1m
+$ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=valid-cluster-name-spot-hash --dry-run=false --resume
 
-For a managed node group, we need to pass the exact ASG resource name, rather than the one which shows up on the EKS console
-$ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=valid-cluster-name-foo-name // incorrect
-$ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=eks-hash-value-asg-name // correct
+For a managed node group, either the node group name which shows up on the EKS console or the ASG resource name can be
+passed, 'k8sclusterupgradetool asg list' shows both for all the node groups of the cluster
+$ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=valid-node-group-name
+$ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=eks-hash-value-asg-name
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		cluster, _ := cmd.Flags().GetString("cluster")
 		asg, _ := cmd.Flags().GetString("autoscaling-group")
 		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		drainOptions, err := drainOptionsFromFlags(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		drainBatchOptions, err := drainBatchOptionsFromFlags(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
 
 		// Read config from file
 		configFileName, configFileType, configFilePath := toolConfig.FileMetadata()
@@ -47,21 +81,19 @@ $ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=eks-hash-va
 		}
 
 		log.Println("Config file used:", viper.ConfigFileUsed())
-		log.Printf("aws-node version read from config: %s\n", viper.Get("components.aws-node"))
-		log.Printf("coredns version read from config: %s", viper.Get("components.coredns"))
-		log.Printf("kube-proxy version read from config: %s", viper.Get("components.kube-proxy"))
-		log.Printf("cluster-autoscaler version read from config: %s", viper.Get("components.cluster-autoscaler"))
 
 		// validate the cluster name and mapping if it's present
-		if configuration.IsClusterNameValid(cluster) {
-			_, _, err := configuration.GetAwsAccountAndRegionForCluster(cluster)
-			if err == nil {
-				log.Println("Setting kubernetes context to", cluster)
-				k8s.SetK8sContext(cluster)
-			}
-		} else {
+		if !configuration.IsClusterNameValid(cluster) {
 			log.Fatalln("Please pass a valid clusterName or check if the AWS account has a mapping inside the tool for the account and the region")
 		}
+		if _, _, err := configuration.GetAwsAccountAndRegionForCluster(cluster); err != nil {
+			log.Fatalln(err)
+		}
+
+		k8sClient, err := k8s.KubeClientInit(cluster)
+		if err != nil {
+			log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
+		}
 
 		// storing all the instances with their private DNS's for the passed ASG for the AWS profile mapped for the cluster passed
 		awsAccount, awsRegion, _ := configuration.GetAwsAccountAndRegionForCluster(cluster)
@@ -72,9 +104,15 @@ $ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=eks-hash-va
 		if err != nil {
 			log.Fatalln("there was an error while initializing the aws config, please check your aws credentials")
 		}
+		asg = resolveAsgName(cfg, cluster, asg)
 
-		awsInstances := aws.AwsInstances{}
-		awsInstances.GetInstancesForASG(cfg, asg, awsRegion, awsAccount)
+		awsAsgClient := &aws.AutoScalingGroupClient{Asg: aws.AutoScalingGroup{AsgName: asg}}
+		asgObject, err := awsAsgClient.DescribeAutoScalingGroup(context.TODO(), cfg)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		verifyAsgOwnership(k8sClient, cluster, asgObject)
+		awsInstances := asgObject.Instances
 
 		if dryRun {
 			log.Println("Running taint and drain nodes command in dry mode")
@@ -87,36 +125,8 @@ $ k8sclusterupgradetool asg taint-and-drain -c=valid-cluster-name -a=eks-hash-va
 			log.Println("Instances which are going to be tainted and drained from the ASG passed")
 			awsInstances.PrettyPrint()
 
-			// add logic which modifies the ASG's Max size to the current desired count to prevent the ASG to scaling up
-			asgObject := aws.AutoScalingGroup{
-				AsgName:          asg,
-				Instances:        awsInstances,
-				DesiredInstances: awsInstances.Count(),
-			}
-			awsAsgClient := &aws.AutoScalingGroupClient{Asg: asgObject}
-			// call the autoscaling group update call
-			awsUpdateAsgObj := &aws.AutoscalingGroupUpdater{
-				UpdateAutoscalingGroupInterface: awsAsgClient,
-			}
-			_, err := awsUpdateAsgObj.Update(context.TODO(), cfg)
-			if err != nil {
-				log.Fatalln("Updation of the Autoscaling group to make the maximum nodes to be equal to the current number of nodes failed," +
-					" skipping, tainting and draining of the ASG")
-			}
-			log.Printf("The ASG's max size was set to the current desired size, current max size after updation: %d\n",
-				awsInstances.Count())
-
-			// iterate over the nodes now to run kubectl taint
-			err = awsInstances.TaintNodes()
-			if err != nil {
-				log.Printf("Error tainting the nodes %s", err)
-			}
-
-			// iterate over the nodes now to run kubectl drain
-			err = awsInstances.DrainNodes()
-			if err != nil {
-				log.Printf("Error draining the nodes %s", err)
-			}
+			runPreflight(cmd, k8sClient, cluster)
+			taintAndDrainAsg(cmd, k8sClient, cfg, cluster, awsAsgClient, awsInstances, drainOptions, drainBatchOptions)
 		}
 	},
 }
@@ -127,11 +137,167 @@ func init() {
 	nodeTaintAndDrainCmd.Flags().StringP("cluster", "c", "",
 		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
 	nodeTaintAndDrainCmd.Flags().StringP("autoscaling-group", "a", "",
-		"Example cluster name input being valid-cluster-name and the asg name passed being valid-cluster-name-spot-hash")
+		"ASG name or EKS managed node group name, eg: valid-cluster-name-spot-hash, run asg list to see the ones of the cluster")
 	nodeTaintAndDrainCmd.Flags().BoolVar(&DryRunFlag, "dry-run", true,
 		"will only show the nodes which will be fed to taint and drain")
+	addDrainFlags(nodeTaintAndDrainCmd)
+	addDrainBatchFlags(nodeTaintAndDrainCmd)
+	addResumeFlag(nodeTaintAndDrainCmd)
+	addPreflightFlag(nodeTaintAndDrainCmd)
 	//nolint
 	nodeTaintAndDrainCmd.MarkFlagRequired("cluster")
 	//nolint
 	nodeTaintAndDrainCmd.MarkFlagRequired("autoscaling-group")
 }
+
+// taintAndDrainAsg caps the max size of the ASG to its desired size, saving its original capacity in a snapshot first,
+// then taints and drains its nodes, recording the steps completed in a journal. It exits with a non-zero status code
+// when one of the nodes could not be fully drained
+func taintAndDrainAsg(cmd *cobra.Command, k8sClient kubernetes.Interface, cfg awsSdk.Config, cluster string,
+	awsAsgClient *aws.AutoScalingGroupClient, awsInstances aws.AwsInstances, drainOptions k8s.DrainOptions,
+	drainBatchOptions aws.DrainBatchOptions) {
+	asg := awsAsgClient.Asg.AsgName
+	journal := openJournal(cmd, cluster, "taint-and-drain", asg)
+
+	// modifies the ASG's Max size to the current desired count to prevent the ASG to scaling up, the original
+	// capacity is stored in a snapshot first so that it can be restored with `asg restore` after the upgrade
+	if journal.IsCompleted(aws.StepAsgCapacityUpdated) {
+		log.Println("The ASG's max size was already set to the current desired size, skipping")
+	} else {
+		awsUpdateAsgObj := &aws.AutoscalingGroupUpdater{
+			UpdateAutoscalingGroupInterface: awsAsgClient,
+		}
+		original, err := awsUpdateAsgObj.Capture(context.TODO(), cfg)
+		if err != nil {
+			log.Fatalf("Describing the Autoscaling group failed, skipping, tainting and draining of the ASG: %v", err)
+		}
+		snapshot, err := saveAsgSnapshot(cluster, asg, original, journal)
+		if err != nil {
+			log.Fatalf("Saving the snapshot of the Autoscaling group failed, skipping, tainting and draining of the ASG: %v", err)
+		}
+		log.Printf("Original capacity of the ASG, min: %d, max: %d, desired: %d saved to the snapshot taken at %s\n",
+			snapshot.Min, snapshot.Max, snapshot.Desired, snapshot.CapturedAt)
+
+		applied, err := awsUpdateAsgObj.Update(context.TODO(), cfg, aws.AutoScalingGroupCapacity{
+			Min:     original.Min,
+			Max:     original.Desired,
+			Desired: original.Desired,
+		})
+		if err != nil {
+			log.Fatalln("Updation of the Autoscaling group to make the maximum nodes to be equal to the current number of nodes failed," +
+				" skipping, tainting and draining of the ASG")
+		}
+		if err := journal.Record(aws.StepAsgCapacityUpdated); err != nil {
+			log.Fatalln(err)
+		}
+		log.Printf("The ASG's max size was set to the current desired size, current max size after updation: %d\n",
+			applied.Max)
+	}
+
+	// iterate over the nodes now to taint them
+	err := awsInstances.TaintNodes(k8sClient, journal)
+	if err != nil {
+		log.Printf("Error tainting the nodes %s", err)
+	}
+
+	// iterate over the nodes now to drain them
+	drainResults, err := awsInstances.DrainNodes(k8sClient, drainOptions, drainBatchOptions, journal)
+	if err != nil {
+		log.Printf("Error draining the nodes %s", err)
+	}
+	if !printDrainReport(drainResults) || err != nil {
+		log.Println("Run the command again with --resume to continue from the last completed step")
+		os.Exit(1)
+	}
+	finishJournal(journal)
+}
+
+// addDrainFlags registers the flags controlling the pod evictions for the commands draining nodes
+func addDrainFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("eviction-retry-interval", 5*time.Second,
+		"initial wait before retrying the evictions refused because of a PodDisruptionBudget, doubled on every retry")
+	cmd.Flags().Duration("eviction-max-retry-interval", time.Minute,
+		"maximum wait between the retries of the evictions refused because of a PodDisruptionBudget")
+	cmd.Flags().Duration("node-drain-timeout", 15*time.Minute,
+		"maximum time spent draining a single node, 0 means no timeout")
+	cmd.Flags().String("drain-timeout-policy", string(k8s.DrainTimeoutAbort),
+		"what to do with the pods still blocked when the node drain times out, one of abort, skip or delete")
+}
+
+// addDrainBatchFlags registers the flags controlling how many nodes are drained at the same time
+func addDrainBatchFlags(cmd *cobra.Command) {
+	cmd.Flags().String("max-unavailable", "1",
+		"number or percentage (eg: 25%) of the nodes drained at the same time in a batch")
+	cmd.Flags().String("batch-size", "",
+		"alias for --max-unavailable")
+	cmd.Flags().Duration("pause-between-batches", 0,
+		"time to wait before draining the next batch of nodes")
+	cmd.Flags().Duration("workload-ready-timeout", 10*time.Minute,
+		"maximum time to wait for the workloads evicted in a batch to be ready again before draining the next batch, 0 skips the wait")
+}
+
+func drainBatchOptionsFromFlags(cmd *cobra.Command) (aws.DrainBatchOptions, error) {
+	maxUnavailable, _ := cmd.Flags().GetString("max-unavailable")
+	batchSize, _ := cmd.Flags().GetString("batch-size")
+	pause, _ := cmd.Flags().GetDuration("pause-between-batches")
+	workloadReadyTimeout, _ := cmd.Flags().GetDuration("workload-ready-timeout")
+
+	if batchSize != "" {
+		if cmd.Flags().Changed("max-unavailable") {
+			return aws.DrainBatchOptions{}, errors.New("please pass only one of --max-unavailable or --batch-size")
+		}
+		maxUnavailable = batchSize
+	}
+
+	return aws.DrainBatchOptions{
+		MaxUnavailable:       intstr.Parse(maxUnavailable),
+		PauseBetweenBatches:  pause,
+		WorkloadReadyTimeout: workloadReadyTimeout,
+		PollInterval:         10 * time.Second,
+	}, nil
+}
+
+func drainOptionsFromFlags(cmd *cobra.Command) (k8s.DrainOptions, error) {
+	retryInterval, _ := cmd.Flags().GetDuration("eviction-retry-interval")
+	maxRetryInterval, _ := cmd.Flags().GetDuration("eviction-max-retry-interval")
+	timeout, _ := cmd.Flags().GetDuration("node-drain-timeout")
+	policy, _ := cmd.Flags().GetString("drain-timeout-policy")
+
+	timeoutPolicy, err := k8s.ParseDrainTimeoutPolicy(policy)
+	if err != nil {
+		return k8s.DrainOptions{}, err
+	}
+	return k8s.NewDrainOptions(retryInterval, maxRetryInterval, timeout, timeoutPolicy), nil
+}
+
+// printDrainReport logs the pods left behind on the drained nodes along with the PodDisruptionBudget blocking them,
+// returns false if any of the nodes was not fully drained
+func printDrainReport(results []k8s.NodeDrainResult) bool {
+	drained := true
+	log.Println("Drain report:")
+	for _, result := range results {
+		if result.Drained() {
+			log.Printf("node/%s: drained\n", result.NodeName)
+			continue
+		}
+		drained = false
+		log.Printf("node/%s: not drained\n", result.NodeName)
+		for _, pod := range result.Blocked() {
+			switch {
+			case pod.BlockingPDB != "":
+				log.Printf("  pod/%s/%s blocked by pdb %s\n", pod.Namespace, pod.Name, pod.BlockingPDB)
+			case pod.Error != nil:
+				log.Printf("  pod/%s/%s blocked by a pdb which could not be found: %v\n", pod.Namespace, pod.Name, pod.Error)
+			default:
+				log.Printf("  pod/%s/%s blocked by a pdb\n", pod.Namespace, pod.Name)
+			}
+		}
+		for _, pod := range result.Failed() {
+			log.Printf("  pod/%s/%s failed: %v\n", pod.Namespace, pod.Name, pod.Error)
+		}
+		for _, pod := range result.Terminating() {
+			log.Printf("  pod/%s/%s still terminating\n", pod.Namespace, pod.Name)
+		}
+	}
+	return drained
+}
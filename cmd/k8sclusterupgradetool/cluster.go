@@ -0,0 +1,18 @@
+package k8sclusterupgradetool
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var clusterCmd = &cobra.Command{
+	Use: "cluster",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Cluster operations")
+		fmt.Println("Run 'k8sclusterupgradetool cluster --help' to see the available commands")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(clusterCmd)
+}
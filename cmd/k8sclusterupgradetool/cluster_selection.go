@@ -0,0 +1,77 @@
+package k8sclusterupgradetool
+
+import (
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/spf13/cobra"
+	"log"
+	"sync"
+)
+
+// addClusterSelectionFlags registers the flags of the commands which can run against several clusters of the clusterlist
+func addClusterSelectionFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	cmd.Flags().Bool("all", false, "run against all the clusters of the clusterlist")
+	cmd.Flags().String("clusters", "",
+		"run against the clusters of the clusterlist matching a comma separated list of globs, eg: prod-*, or of regular expressions wrapped in slashes, eg: /^prod-(eu|us)-.*$/")
+	cmd.Flags().Int("concurrency", 5, "how many clusters to run against at the same time")
+}
+
+// selectClusters returns the clusters passed with either -c, --all or --clusters
+func selectClusters(cmd *cobra.Command, configuration config.Configurations) []string {
+	cluster, _ := cmd.Flags().GetString("cluster")
+	all, _ := cmd.Flags().GetBool("all")
+	selector, _ := cmd.Flags().GetString("clusters")
+
+	passed := 0
+	for _, isPassed := range []bool{cluster != "", all, selector != ""} {
+		if isPassed {
+			passed++
+		}
+	}
+	if passed != 1 {
+		log.Fatal("Please pass exactly one of -c, --all or --clusters")
+	}
+
+	switch {
+	case all:
+		return configuration.ClusterNames()
+	case selector != "":
+		clusters, err := configuration.SelectClusterNames(selector)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return clusters
+	default:
+		if !configuration.IsClusterNameValid(cluster) {
+			log.Fatal("Please pass a valid clusterName")
+		}
+		return []string{cluster}
+	}
+}
+
+// forEachCluster runs the function against each cluster, at most --concurrency clusters at the same time, the index of
+// the cluster being passed for the function to store its result in the order of the clusters
+func forEachCluster(cmd *cobra.Command, clusters []string, run func(i int, cluster string)) {
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < concurrency && worker < len(clusters); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				run(i, clusters[i])
+			}
+		}()
+	}
+	for i := range clusters {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
@@ -0,0 +1,95 @@
+package k8sclusterupgradetool
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/config"
+	toolConfig "github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/report"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	controlPlaneUpdatePollInterval = 30 * time.Second
+	defaultControlPlaneTimeout     = 60 * time.Minute
+)
+
+var upgradeControlPlaneCmd = &cobra.Command{
+	Use:   "upgrade-control-plane",
+	Short: "Upgrades the control plane of an EKS cluster to the next Kubernetes minor version",
+	Long: `Upgrades the control plane of an EKS cluster to the Kubernetes version passed, which has to be exactly one
+minor version ahead of the version the control plane runs, waits for the update to complete and then checks the
+version of the components for the new Kubernetes version
+
+Running the command again once the control plane runs the version passed only checks the version of the components.
+
+Usage:
+$ k8sclusterupgradetool cluster upgrade-control-plane -c=CLUSTER_NAME --to=KUBERNETES_VERSION
+
+Example:
+$ k8sclusterupgradetool cluster upgrade-control-plane -c=valid-cluster-name --to=1.22
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		targetVersion, _ := cmd.Flags().GetString("to")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		// Read config from file
+		configFileName, configFileType, configFilePath := toolConfig.FileMetadata()
+		configuration, err := toolConfig.Read(configFileName, configFileType, configFilePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Config file used:", viper.ConfigFileUsed())
+
+		if !configuration.IsClusterNameValid(cluster) {
+			log.Fatalln("Please pass a valid clusterName or check if the AWS account has a mapping inside the tool for the account and the region")
+		}
+		awsAccount, awsRegion, err := configuration.GetAwsAccountAndRegionForCluster(cluster)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		awsGetterObj := &aws.ConfigGetter{ConfigClientInterface: &aws.Config{}}
+		cfg, err := awsGetterObj.GetConfig(context.TODO(), config.WithRegion(awsRegion), config.WithSharedConfigProfile(awsAccount))
+		if err != nil {
+			log.Fatalln("there was an error while initializing the aws config, please check your aws credentials")
+		}
+
+		upgrader := &aws.ControlPlaneUpgrader{EksClusterInterface: &aws.EksClient{}, Timeout: timeout,
+			PollInterval: controlPlaneUpdatePollInterval}
+		if _, err := upgrader.Upgrade(context.TODO(), cfg, cluster, targetVersion); err != nil {
+			log.Fatalln(err)
+		}
+
+		log.Println("running post upgrade checks")
+		componentVersions := checkCluster(cluster, configuration)
+		if drifted := report.ComponentsWithStatus(componentVersions, report.Drifted); len(drifted) > 0 {
+			log.Printf("components to be upgraded for Kubernetes %s: %s, run k8sclusterupgradetool component version sync -c=%s\n",
+				targetVersion, strings.Join(drifted, ", "), cluster)
+		}
+		exitWithCheckResult(cmd, componentVersions)
+	},
+}
+
+func init() {
+	clusterCmd.AddCommand(upgradeControlPlaneCmd)
+
+	upgradeControlPlaneCmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	upgradeControlPlaneCmd.Flags().String("to", "",
+		"Kubernetes version to upgrade the control plane to, exactly one minor version ahead of the current one, eg: 1.22")
+	upgradeControlPlaneCmd.Flags().Duration("timeout", defaultControlPlaneTimeout,
+		"how long to wait for the update of the control plane to complete")
+	upgradeControlPlaneCmd.Flags().Bool("fail-on-drift", false,
+		fmt.Sprintf("exit with status code %d when one of the components is not on the version of the config file once the control plane is upgraded", report.ExitDrift))
+	//nolint
+	upgradeControlPlaneCmd.MarkFlagRequired("cluster")
+	//nolint
+	upgradeControlPlaneCmd.MarkFlagRequired("to")
+}
@@ -0,0 +1,69 @@
+package k8sclusterupgradetool
+
+import (
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/state"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"log"
+	"time"
+)
+
+const (
+	// rolloutPollInterval is how often the rollout of the k8s object is checked with --wait
+	rolloutPollInterval = 5 * time.Second
+	// defaultRolloutTimeout is how long the rollout of the k8s object is waited for by default with --wait
+	defaultRolloutTimeout = 5 * time.Minute
+)
+
+// addRolloutFlags registers the flags of the commands waiting for the rollout of the k8s objects they update
+func addRolloutFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("wait", true,
+		"wait for the rollout of the k8s object to complete, failing if it stalls or its new pods crash loop or keep failing to pull their image")
+	cmd.Flags().Duration("rollout-timeout", defaultRolloutTimeout,
+		"how long to wait for the rollout of the k8s object to complete with --wait")
+}
+
+// addRollbackOnFailureFlag registers the --rollback-on-failure flag for the commands setting the images of components
+func addRollbackOnFailureFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("rollback-on-failure", true,
+		"set the images the k8s object ran before back when its rollout does not complete with --wait")
+}
+
+// waitForComponentRollout waits for the rollout of the component to the version it was set to with --wait, rolling it
+// back to its previous images when the rollout does not complete unless --rollback-on-failure=false is passed. It
+// exits with a non-zero status code when the rollout does not complete
+func waitForComponentRollout(cmd *cobra.Command, k8sClient kubernetes.Interface, cluster, componentName, version string,
+	k8sObject config.K8sObject, journal *state.Journal) {
+	if wait, _ := cmd.Flags().GetBool("wait"); !wait {
+		return
+	}
+
+	rolloutTimeout, _ := cmd.Flags().GetDuration("rollout-timeout")
+	err := k8s.WaitForRollout(k8sClient, k8sObject.ObjectType, k8sObject.DeploymentName, k8sObject.Namespace,
+		rolloutTimeout, rolloutPollInterval)
+	if err == nil {
+		return
+	}
+
+	rollbackOnFailure, _ := cmd.Flags().GetBool("rollback-on-failure")
+	if !rollbackOnFailure {
+		log.Fatalf("%s has been set to %s but its rollout did not complete: %v", componentName, version, err)
+	}
+
+	log.Printf("%s has been set to %s but its rollout did not complete, rolling it back: %v", componentName, version, err)
+	if err := rollbackComponentVersion(k8sClient, cluster, k8sObject); err != nil {
+		log.Fatalf("there was an error while rolling back %s: %v", componentName, err)
+	}
+	if err := journal.Record("rolled-back/" + componentName); err != nil {
+		log.Println(err)
+	}
+	finishJournal(journal)
+	err = k8s.WaitForRollout(k8sClient, k8sObject.ObjectType, k8sObject.DeploymentName, k8sObject.Namespace,
+		rolloutTimeout, rolloutPollInterval)
+	if err != nil {
+		log.Fatalf("%s has been rolled back but the rollout of its previous images did not complete either: %v", componentName, err)
+	}
+	log.Fatalf("%s has been rolled back to its previous images after its rollout to %s failed", componentName, version)
+}
@@ -2,7 +2,11 @@ package k8sclusterupgradetool
 
 import (
 	"fmt"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"log"
 )
 
 var componentVersionCmd = &cobra.Command{
@@ -16,3 +20,56 @@ var componentVersionCmd = &cobra.Command{
 func init() {
 	componentCmd.AddCommand(componentVersionCmd)
 }
+
+func logComponentVersions(configuration config.Configurations) {
+	for _, componentName := range configuration.ComponentNames() {
+		version, _ := configuration.GetComponentVersion(componentName)
+		log.Printf("%s version read from config: %s\n", componentName, version)
+	}
+}
+
+// clusterKubernetesVersion returns the Kubernetes version the control plane of the cluster runs, the components
+// versions are resolved from the compatibility matrix for, logging the warnings of the compatibility matrix for it
+func clusterKubernetesVersion(k8sClient kubernetes.Interface, configuration config.Configurations, cluster string) (string, error) {
+	kubernetesVersion, err := k8s.GetServerVersion(k8sClient)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("cluster %s runs Kubernetes %s\n", cluster, kubernetesVersion)
+	for _, warning := range configuration.CompatibilityWarnings(cluster, kubernetesVersion) {
+		log.Printf("warning: %s\n", warning)
+	}
+	return kubernetesVersion, nil
+}
+
+// componentContainerImages returns the images of the containers and init containers declared for the component,
+// in the order they are declared in
+func componentContainerImages(k8sClient kubernetes.Interface, k8sObject config.K8sObject) ([]k8s.ContainerImage, error) {
+	images, err := k8s.GetContainerImagesForK8sObject(k8sClient, k8sObject.ObjectType, k8sObject.DeploymentName, k8sObject.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := []k8s.ContainerImage{}
+	for _, name := range k8sObject.ContainerNames() {
+		declared = append(declared, k8s.ContainerImage{Name: name})
+	}
+	for _, name := range k8sObject.InitContainers {
+		declared = append(declared, k8s.ContainerImage{Name: name, Init: true})
+	}
+
+	for i, container := range declared {
+		found := false
+		for _, image := range images {
+			if image.Name == container.Name && image.Init == container.Init {
+				declared[i].Image = image.Image
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("container %s was %w in %s %s in namespace %s",
+				container, k8s.ErrNotFound, k8sObject.ObjectType, k8sObject.DeploymentName, k8sObject.Namespace)
+		}
+	}
+	return declared, nil
+}
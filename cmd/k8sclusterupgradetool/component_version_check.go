@@ -1,32 +1,50 @@
 package k8sclusterupgradetool
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
 	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/registry"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/report"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/client-go/kubernetes"
 	"log"
+	"os"
+	"strings"
 )
 
 func init() {
 	componentVersionCmd.AddCommand(postUpgradeCheckCmd)
 
-	postUpgradeCheckCmd.Flags().StringP("cluster", "c", "",
-		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
-	//nolint
-	postUpgradeCheckCmd.MarkFlagRequired("cluster")
+	addClusterSelectionFlags(postUpgradeCheckCmd)
+	postUpgradeCheckCmd.Flags().StringP("output", "o", "",
+		"print a report of the version of each component to stdout, one of json, yaml, table or matrix, defaults to matrix when several clusters are checked")
+	postUpgradeCheckCmd.Flags().Bool("fail-on-drift", false,
+		fmt.Sprintf("exit with status code %d when one of the components is not on the version of the config file", report.ExitDrift))
 }
 
 var postUpgradeCheckCmd = &cobra.Command{
 	Use:   "check",
-	Short: "Runs post upgrade checks on a cluster",
-	Long: `Just checks for a cluster to see whether all the components have been upgraded or not
+	Short: "Runs post upgrade checks on one or several clusters",
+	Long: `Just checks for a cluster to see whether all the components set in the config file have been upgraded or not
+Exits with status code 1 when one of the components could not be checked and, with --fail-on-drift,
+with status code 2 when one of them is not on the version of the config file
 Usage:
-$ k8sclusterupgradetool component version check -c=valid-cluster-name`,
+$ k8sclusterupgradetool component version check -c=valid-cluster-name
+$ k8sclusterupgradetool component version check -c=valid-cluster-name --fail-on-drift
+$ k8sclusterupgradetool component version check -c=valid-cluster-name -o=json
+$ k8sclusterupgradetool component version check --all
+$ k8sclusterupgradetool component version check --clusters='prod-*,/^stg-(eu|us)-.*$/' --concurrency=10`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cluster, _ := cmd.Flags().GetString("cluster")
+		output, _ := cmd.Flags().GetString("output")
+		if output != "" {
+			if err := report.ValidateFormat(output); err != nil {
+				log.Fatalln(err)
+			}
+		}
 		// Read config from file
 		configFileName, configFileType, configFilePath := config.FileMetadata()
 		configuration, err := config.Read(configFileName, configFileType, configFilePath)
@@ -35,53 +53,151 @@ $ k8sclusterupgradetool component version check -c=valid-cluster-name`,
 		}
 
 		log.Println("Config file used:", viper.ConfigFileUsed())
-		log.Printf("aws-node version read from config: %s\n", viper.Get("components.aws-node"))
-		log.Printf("coredns version read from config: %s", viper.Get("components.coredns"))
-		log.Printf("kube-proxy version read from config: %s", viper.Get("components.kube-proxy"))
-		log.Printf("cluster-autoscaler version read from config: %s", viper.Get("components.cluster-autoscaler"))
-
-		if configuration.IsClusterNameValid(cluster) {
-			log.Println("running post upgrade checks")
-			k8sClient, err := k8s.KubeClientInit(cluster)
-			if err != nil {
-				log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
-			}
+		logComponentVersions(configuration)
 
-			components := []string{"aws-node", "kube-proxy", "coredns", "cluster-autoscaler"}
-			for _, componentName := range components {
-				err = checkComponentVersion(componentName, cluster, configuration, k8sClient)
-				if err != nil {
-					log.Fatalf("error while checking for %s component version: %v", componentName, err)
-				}
+		clusters := selectClusters(cmd, configuration)
+		log.Printf("running post upgrade checks on %s", strings.Join(clusters, ", "))
+		results := make([][]report.ComponentVersion, len(clusters))
+		forEachCluster(cmd, clusters, func(i int, cluster string) {
+			results[i] = checkCluster(cluster, configuration)
+		})
+		var componentVersions []report.ComponentVersion
+		for _, result := range results {
+			componentVersions = append(componentVersions, result...)
+		}
+
+		if output == "" && len(clusters) > 1 {
+			output = "matrix"
+		}
+		if output != "" {
+			if err := report.Write(os.Stdout, output, componentVersions); err != nil {
+				log.Fatalln(err)
 			}
-		} else {
-			log.Fatal("Please pass a valid clusterName")
 		}
+		exitWithCheckResult(cmd, componentVersions)
 	},
 }
 
-func checkComponentVersion(componentName, clusterName string, configuration config.Configurations, k8sClient kubernetes.Interface) error {
+// checkCluster checks the version of all the components of the config file in the cluster, the components of a cluster
+// which can't be reached being all reported with the Error status
+func checkCluster(cluster string, configuration config.Configurations) []report.ComponentVersion {
+	var componentVersions []report.ComponentVersion
+	var kubernetesVersion string
+	k8sClient, err := k8s.KubeClientInit(cluster)
+	if err == nil {
+		kubernetesVersion, err = clusterKubernetesVersion(k8sClient, configuration, cluster)
+	}
+	if err != nil {
+		log.Printf("cluster %s can't be reached: %v", cluster, err)
+		for _, componentName := range configuration.ComponentNames() {
+			componentVersion := report.ComponentVersion{Cluster: cluster, Component: componentName, Status: report.Error,
+				Error: fmt.Sprintf("cluster unreachable: %v", err)}
+			if k8sObject, err := configuration.GetK8sObjectForCluster(cluster, componentName); err == nil {
+				componentVersion.Kind, componentVersion.Namespace = k8sObject.ObjectType, k8sObject.Namespace
+			}
+			componentVersion.DesiredTag, _ = configuration.GetComponentVersionForCluster(cluster, "", componentName)
+			componentVersions = append(componentVersions, componentVersion)
+		}
+		return componentVersions
+	}
+
+	resolver := newRegistryClient(configuration, cluster)
+	for _, componentName := range configuration.ComponentNames() {
+		componentVersion, err := checkComponentVersion(componentName, cluster, kubernetesVersion, configuration, k8sClient, resolver)
+		if err != nil {
+			log.Printf("error while checking for %s component version in %s: %v", componentName, cluster, err)
+		}
+		componentVersions = append(componentVersions, componentVersion)
+	}
+	return componentVersions
+}
+
+// exitWithCheckResult exits with ExitError when one of the components could not be checked or is missing and, with
+// --fail-on-drift, with ExitDrift when one of them is not on the version of the config file
+func exitWithCheckResult(cmd *cobra.Command, componentVersions []report.ComponentVersion) {
+	failOnDrift, _ := cmd.Flags().GetBool("fail-on-drift")
+	switch report.ExitCode(componentVersions, failOnDrift) {
+	case report.ExitError:
+		failed := append(report.ComponentsWithStatus(componentVersions, report.Missing),
+			report.ComponentsWithStatus(componentVersions, report.Error)...)
+		log.Fatalf("error while checking the version of components: %s", strings.Join(failed, ", "))
+	case report.ExitDrift:
+		log.Printf("components not on the version of the config file: %s",
+			strings.Join(report.ComponentsWithStatus(componentVersions, report.Drifted), ", "))
+		os.Exit(report.ExitDrift)
+	}
+}
+
+// checkComponentVersion logs whether each container of the component is on the version of the config file for the
+// cluster running the Kubernetes version passed, the
+// containers pinned by digest being on it when the digest the version tag resolves to in the registry is the one
+// they are pinned by. It returns the version the component is on, with the Missing or Error status along with the
+// error when the component could not be checked
+func checkComponentVersion(componentName, clusterName, kubernetesVersion string, configuration config.Configurations, k8sClient kubernetes.Interface,
+	resolver registry.DigestResolverInterface) (report.ComponentVersion, error) {
 	log.Printf("Checking %s version\n", componentName)
+	componentVersion := report.ComponentVersion{Cluster: clusterName, Component: componentName}
+	failed := func(err error) (report.ComponentVersion, error) {
+		componentVersion.Status, componentVersion.Error = report.Error, err.Error()
+		if errors.Is(err, k8s.ErrNotFound) {
+			componentVersion.Status = report.Missing
+		}
+		return componentVersion, err
+	}
+
 	k8sObject, err := configuration.GetK8sObjectForCluster(clusterName, componentName)
 	if err != nil {
-		return err
+		return failed(err)
 	}
+	componentVersion.Kind, componentVersion.Namespace = k8sObject.ObjectType, k8sObject.Namespace
 
-	containerImage, err := k8s.GetContainerImageForK8sObject(k8sClient, k8sObject.DeploymentName, k8sObject.ObjectType, k8sObject.Namespace)
+	desiredVersion, err := configuration.GetComponentVersionForCluster(clusterName, kubernetesVersion, componentName)
 	if err != nil {
-		return err
+		return failed(err)
 	}
+	componentVersion.DesiredTag = desiredVersion
 
-	imageTag, err := k8s.ParseComponentImage(containerImage, "imageTag")
+	images, err := componentContainerImages(k8sClient, k8sObject)
 	if err != nil {
-		return err
+		return failed(err)
 	}
 
-	viperQuery := fmt.Sprintf("components.%s", componentName)
-	if imageTag == viper.Get(viperQuery) {
-		log.Printf("%s Version on %s ✓ \n", componentName, viper.Get(viperQuery))
-	} else {
-		log.Printf("%s needs to be updated, is currently on %s, desired version: %s\n", componentName, imageTag, viper.Get(viperQuery))
+	componentVersion.Status = report.UpToDate
+	for _, image := range images {
+		reference, err := k8s.ParseImageReference(image.Image)
+		if err != nil {
+			return failed(err)
+		}
+		containerVersion := report.ContainerVersion{Container: image.String(), Image: image.Image,
+			CurrentTag: reference.Version(), Status: report.UpToDate}
+
+		if reference.IsOnVersion(desiredVersion) {
+			log.Printf("%s/%s Version on %s ✓ \n", componentName, image, desiredVersion)
+		} else if reference.IsPinnedByDigest() && !k8s.IsDigest(desiredVersion) {
+			digest, err := resolver.ResolveDigest(context.TODO(), reference.Registry, reference.Repository, desiredVersion)
+			if err != nil {
+				return failed(err)
+			}
+			if digest == reference.Digest {
+				log.Printf("%s/%s Version on %s, pinned by digest %s ✓ \n", componentName, image, desiredVersion, digest)
+			} else {
+				containerVersion.Status = report.Drifted
+				log.Printf("%s/%s needs to be updated, is currently pinned by digest %s, desired version: %s (%s)\n",
+					componentName, image, reference.Digest, desiredVersion, digest)
+			}
+		} else {
+			containerVersion.Status = report.Drifted
+			log.Printf("%s/%s needs to be updated, is currently on %s, desired version: %s\n", componentName, image, reference.Version(), desiredVersion)
+		}
+
+		if containerVersion.Status == report.Drifted {
+			componentVersion.Status = report.Drifted
+		}
+		componentVersion.Containers = append(componentVersion.Containers, containerVersion)
 	}
-	return nil
+
+	// the main container is the first one declared
+	componentVersion.CurrentImage = componentVersion.Containers[0].Image
+	componentVersion.CurrentTag = componentVersion.Containers[0].CurrentTag
+	return componentVersion, nil
 }
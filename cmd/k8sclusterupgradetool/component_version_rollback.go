@@ -0,0 +1,100 @@
+package k8sclusterupgradetool
+
+import (
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+	"log"
+)
+
+var rollbackComponentVersionCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Sets the images a component ran before it was last set back",
+	Long: `Sets the images a component ran before it was last set with component version set back,
+as recorded on its k8s object
+Usage:
+$ k8sclusterupgradetool component version rollback -c=valid-cluster-name -o=aws-node`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		componentName, _ := cmd.Flags().GetString("component-object")
+
+		// Read config from file
+		configFileName, configFileType, configFilePath := config.FileMetadata()
+		configuration, err := config.Read(configFileName, configFileType, configFilePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		log.Println("Config file used:", viper.ConfigFileUsed())
+
+		if configuration.IsClusterNameValid(cluster) {
+			log.Println("Cluster name is valid")
+		} else {
+			log.Fatal("Please pass a valid clusterName")
+		}
+
+		k8sObject, err := configuration.GetK8sObjectForCluster(cluster, componentName)
+		if err != nil {
+			log.Fatalf("there was an error reading config from the config file: %v", err)
+		}
+
+		k8sClient, err := k8s.KubeClientInit(cluster)
+		if err != nil {
+			log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
+		}
+
+		journal := openJournal(cmd, cluster, "component-version-rollback", componentName)
+		if err := rollbackComponentVersion(k8sClient, cluster, k8sObject); err != nil {
+			log.Fatalf("there was an error while rolling back %s: %v", componentName, err)
+		}
+		if err := journal.Record("rolled-back"); err != nil {
+			log.Println(err)
+		}
+
+		if wait, _ := cmd.Flags().GetBool("wait"); wait {
+			rolloutTimeout, _ := cmd.Flags().GetDuration("rollout-timeout")
+			err = k8s.WaitForRollout(k8sClient, k8sObject.ObjectType, k8sObject.DeploymentName, k8sObject.Namespace,
+				rolloutTimeout, rolloutPollInterval)
+			if err != nil {
+				log.Fatalf("%s has been rolled back but its rollout did not complete: %v", componentName, err)
+			}
+		}
+		finishJournal(journal)
+	},
+}
+
+func init() {
+	componentVersionCmd.AddCommand(rollbackComponentVersionCmd)
+
+	rollbackComponentVersionCmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	rollbackComponentVersionCmd.Flags().StringP("component-object", "o", "",
+		"K8s cluster component being rolled back, any of the components in the config file eg: aws-node, cluster-autoscaler, kube-proxy, coredns")
+	addRolloutFlags(rollbackComponentVersionCmd)
+	//nolint
+	rollbackComponentVersionCmd.MarkFlagRequired("cluster")
+	//nolint
+	rollbackComponentVersionCmd.MarkFlagRequired("component-object")
+}
+
+// rollbackComponentVersion sets the images recorded on the k8s object of the component before they were last set back,
+// in a single update, the record being removed so that the component can't be rolled back twice to the same images
+func rollbackComponentVersion(k8sClient kubernetes.Interface, cluster string, k8sObject config.K8sObject) error {
+	previous, err := k8s.GetPreviousImages(k8sClient, k8sObject.ObjectType, k8sObject.DeploymentName, k8sObject.Namespace)
+	if err != nil {
+		return err
+	}
+
+	annotations := map[string]string{k8s.PreviousImagesAnnotation: "", k8s.SourceTagAnnotation: previous.SourceTag}
+	err = k8s.SetK8sObjectImages(k8sClient, k8sObject.ObjectType, k8sObject.DeploymentName, k8sObject.Namespace, previous.Images, annotations)
+	if err != nil {
+		return err
+	}
+
+	for _, image := range previous.Images {
+		log.Printf("%s/%s has been rolled back to %s in cluster %s\n", k8sObject.DeploymentName, image, image.Image, cluster)
+	}
+	return nil
+}
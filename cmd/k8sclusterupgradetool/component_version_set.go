@@ -1,21 +1,32 @@
 package k8sclusterupgradetool
 
 import (
+	"context"
+	"fmt"
 	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
 	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/registry"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"k8s.io/client-go/kubernetes"
 	"log"
 )
 
+// stepImagesSet is recorded in the journal of component version set once the images of the component are set, along
+// with the version they were set to
+const stepImagesSet = "images-set"
+
 var setComponentVersionCmd = &cobra.Command{
 	Use:   "set",
 	Short: "Sets the value of a component running in the cluster to the passed value",
 	Long: `Sets the value of a component running in the cluster to the passed value,
-as of now will support setting the value for aws-node, cluster-autoscaler, kube-proxy, coredns
+any of the components set under the components key of the config file can be passed. Without -v, the component
+is set to its version of the config file for the cluster and the Kubernetes version it runs
 Usage:
-$ k8sclusterupgradetool component version set -c=valid-cluster-name -o=aws-node -v=my-version`,
+$ k8sclusterupgradetool component version set -c=valid-cluster-name -o=aws-node -v=my-version
+$ k8sclusterupgradetool component version set -c=valid-cluster-name -o=kube-proxy
+$ k8sclusterupgradetool component version set -c=valid-cluster-name -o=aws-node -v=my-version --pin-digest
+$ k8sclusterupgradetool component version set -c=valid-cluster-name -o=aws-node -v=my-version --resume`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Parse flag values
 		cluster, _ := cmd.Flags().GetString("cluster")
@@ -30,15 +41,7 @@ $ k8sclusterupgradetool component version set -c=valid-cluster-name -o=aws-node
 		}
 
 		log.Println("Config file used:", viper.ConfigFileUsed())
-		log.Printf("aws-node version read from config: %s\n", viper.Get("components.aws-node"))
-		log.Printf("coredns version read from config: %s", viper.Get("components.coredns"))
-		log.Printf("kube-proxy version read from config: %s", viper.Get("components.kube-proxy"))
-		log.Printf("cluster-autoscaler version read from config: %s", viper.Get("components.cluster-autoscaler"))
-
-		err = configuration.ValidatePassedComponentVersions(k8sComponent, k8sComponentVersion)
-		if err != nil {
-			log.Fatalf("%s", err)
-		}
+		logComponentVersions(configuration)
 
 		if configuration.IsClusterNameValid(cluster) {
 			log.Println("Cluster name is valid")
@@ -50,48 +53,52 @@ $ k8sclusterupgradetool component version set -c=valid-cluster-name -o=aws-node
 		if err != nil {
 			log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
 		}
+		kubernetesVersion, err := clusterKubernetesVersion(k8sClient, configuration, cluster)
+		if err != nil {
+			log.Fatalf("there was an error reading the Kubernetes version of the cluster: %v", err)
+		}
 
-		componentName, imageTag := k8sComponent, k8sComponentVersion
-		switch componentName {
-		case "coredns":
-			k8sObject, err := configuration.GetK8sObjectForCluster(cluster, "coredns")
+		if k8sComponentVersion == "" {
+			k8sComponentVersion, err = configuration.GetComponentVersionForCluster(cluster, kubernetesVersion, k8sComponent)
 			if err != nil {
-				log.Fatalf("there was an error reading config from the config file: %v", err)
+				log.Fatalf("%s", err)
 			}
-			err = setComponentVersion(k8sClient, imageTag, componentName, k8sObject.ObjectType, k8sObject.ContainerName, k8sObject.Namespace)
-			if err != nil {
-				log.Fatalf("there was error while setting component version for coredns: %v", err)
-			}
-		case "kube-proxy":
-			k8sObject, err := configuration.GetK8sObjectForCluster(cluster, "kube-proxy")
-			if err != nil {
-				log.Fatalf("there was an error reading config from the config file: %v", err)
-			}
-			err = setComponentVersion(k8sClient, imageTag, componentName, k8sObject.ObjectType, k8sObject.ContainerName, k8sObject.Namespace)
-			if err != nil {
-				log.Fatalf("there was error while setting component version for kube-proxy: %v", err)
-			}
-		case "aws-node":
-			k8sObject, err := configuration.GetK8sObjectForCluster(cluster, "aws-node")
-			if err != nil {
-				log.Fatalf("there was an error reading config from the config file: %v", err)
+			log.Printf("%s version resolved from config for the cluster: %s\n", k8sComponent, k8sComponentVersion)
+		}
+		err = configuration.ValidatePassedComponentVersions(cluster, kubernetesVersion, k8sComponent, k8sComponentVersion)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+
+		runPreflight(cmd, k8sClient, cluster)
+		journal := openJournal(cmd, cluster, "component-version-set", k8sComponent)
+		componentName, imageTag := k8sComponent, k8sComponentVersion
+		k8sObject, err := configuration.GetK8sObjectForCluster(cluster, componentName)
+		if err != nil {
+			log.Fatalf("there was an error reading config from the config file: %v", err)
+		}
+		if journal.IsCompleted(stepImagesSet) {
+			// the images of the interrupted run are not set again, which would record them as the previous images
+			if values := journal.Values(stepImagesSet); len(values) == 0 || values[0] != imageTag {
+				log.Fatalf("the interrupted run set %s to %v, not %s, run the command without --resume", componentName, values, imageTag)
 			}
-			err = setComponentVersion(k8sClient, imageTag, componentName, k8sObject.ObjectType, k8sObject.ContainerName, k8sObject.Namespace)
-			if err != nil {
-				log.Fatalf("there was error while setting component version for aws-node: %v", err)
+			log.Printf("%s has already been set to %s, skipping to its rollout\n", componentName, imageTag)
+		} else {
+			var resolver registry.DigestResolverInterface
+			if pinDigest, _ := cmd.Flags().GetBool("pin-digest"); pinDigest {
+				resolver = newRegistryClient(configuration, cluster)
 			}
-		case "cluster-autoscaler":
-			k8sObject, err := configuration.GetK8sObjectForCluster(cluster, "cluster-autoscaler")
+			err = setComponentVersion(k8sClient, cluster, imageTag, k8sObject, resolver)
 			if err != nil {
-				log.Fatalf("there was an error reading config from the config file: %v", err)
+				log.Fatalf("there was error while setting component version for %s: %v", componentName, err)
 			}
-			err = setComponentVersion(k8sClient, imageTag, componentName, k8sObject.ObjectType, k8sObject.ContainerName, k8sObject.Namespace)
-			if err != nil {
-				log.Fatalf("there was error while setting component version for cluster-autoscaler: %v", err)
+			if err := journal.Record(stepImagesSet, imageTag); err != nil {
+				log.Println(err)
 			}
-		default:
-			log.Println("please check the passed components, the supported components are cluster-autoscaler, kube-proxy, coredns, aws-node")
 		}
+
+		waitForComponentRollout(cmd, k8sClient, cluster, componentName, imageTag, k8sObject, journal)
+		finishJournal(journal)
 	},
 }
 
@@ -101,35 +108,77 @@ func init() {
 	setComponentVersionCmd.Flags().StringP("cluster", "c", "",
 		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
 	setComponentVersionCmd.Flags().StringP("component-object", "o", "",
-		"K8s cluster component being set, currently supported ones: eg: aws-node, cluster-autoscaler, kube-proxy, coredns")
+		"K8s cluster component being set, any of the components in the config file eg: aws-node, cluster-autoscaler, kube-proxy, coredns")
 	setComponentVersionCmd.Flags().StringP("component-object-version", "v", "",
-		"k8s component version to be set for the k8s component, currently supported ones: eg: aws-node, cluster-autoscaler, kube-proxy, coredns")
-	//nolint
-	nodeTaintAndDrainCmd.MarkFlagRequired("cluster")
+		"k8s component version to be set for the k8s component, has to match the version of the component in the config file for the cluster, defaults to it")
+	addRolloutFlags(setComponentVersionCmd)
+	addRollbackOnFailureFlag(setComponentVersionCmd)
+	setComponentVersionCmd.Flags().Bool("pin-digest", false,
+		"resolve the version to the digest of each image in its registry and pin the containers by digest instead of by tag")
+	addPreflightFlag(setComponentVersionCmd)
+	addResumeFlag(setComponentVersionCmd)
 	//nolint
-	nodeTaintAndDrainCmd.MarkFlagRequired("component-object")
+	setComponentVersionCmd.MarkFlagRequired("cluster")
 	//nolint
-	nodeTaintAndDrainCmd.MarkFlagRequired("component-object-version")
+	setComponentVersionCmd.MarkFlagRequired("component-object")
 }
 
-func setComponentVersion(k8sClient kubernetes.Interface, imageTag, componentName, componentK8sObject, containerName, namespace string) error {
-	// get current imagePrefix
-	currentContainerImage, err := k8s.GetContainerImageForK8sObject(k8sClient, componentName, componentK8sObject, namespace)
+// setComponentVersion moves all the containers and init containers declared for the component to the version passed,
+// a tag or a digest, each container keeping its own registry and repository. When a digest resolver is passed, the tag
+// is resolved to the digest of each image which the containers are then pinned by, the tag being recorded in the
+// SourceTagAnnotation of the k8s object
+func setComponentVersion(k8sClient kubernetes.Interface, cluster, version string, k8sObject config.K8sObject, resolver registry.DigestResolverInterface) error {
+	if resolver != nil && k8s.IsDigest(version) {
+		return fmt.Errorf("version %s is already a digest, only tags can be pinned to their digest", version)
+	}
+
+	images, err := componentContainerImages(k8sClient, k8sObject)
 	if err != nil {
 		return err
 	}
+	for i, image := range images {
+		reference, err := k8s.ParseImageReference(image.Image)
+		if err != nil {
+			return err
+		}
+		if resolver != nil {
+			digest, err := resolver.ResolveDigest(context.TODO(), reference.Registry, reference.Repository, version)
+			if err != nil {
+				return err
+			}
+			reference.Tag, reference.Digest = "", digest
+			images[i].Image = reference.String()
+			continue
+		}
+		if reference.IsPinnedByDigest() && !k8s.IsDigest(version) {
+			log.Printf("%s/%s is pinned by digest %s, it will be pulled by the tag %s instead\n",
+				k8sObject.DeploymentName, image, reference.Digest, version)
+		}
+		images[i].Image = reference.WithVersion(version).String()
+	}
 
-	imagePrefix, err := k8s.ParseComponentImage(currentContainerImage, "imagePrefix")
+	annotations, err := k8s.PreviousImagesAnnotations(k8sClient, k8sObject.ObjectType, k8sObject.DeploymentName,
+		k8sObject.Namespace, images)
 	if err != nil {
 		return err
 	}
-	containerImage := imagePrefix + ":" + imageTag
-
-	err = k8s.SetK8sObjectImage(k8sClient, componentK8sObject, componentName, containerName, containerImage, namespace)
+	// the source tag of a previous pinning is stale once the images are set to another version
+	annotations[k8s.SourceTagAnnotation] = ""
+	if resolver != nil {
+		annotations[k8s.SourceTagAnnotation] = version
+	}
+	err = k8s.SetK8sObjectImages(k8sClient, k8sObject.ObjectType, k8sObject.DeploymentName, k8sObject.Namespace, images, annotations)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("%s has been set to %s in cluster \n", componentName, imageTag)
+	for _, image := range images {
+		if resolver != nil {
+			log.Printf("%s/%s has been set to %s, pinned from %s, in cluster %s\n", k8sObject.DeploymentName, image, image.Image,
+				version, cluster)
+			continue
+		}
+		log.Printf("%s/%s has been set to %s in cluster %s\n", k8sObject.DeploymentName, image, version, cluster)
+	}
 	return nil
 }
@@ -0,0 +1,105 @@
+package k8sclusterupgradetool
+
+import (
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/registry"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/report"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+)
+
+var syncComponentVersionCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sets all the components which are not on the version of the config file to it",
+	Long: `Checks all the components of the config file and sets the ones which are not on the version of
+the config file to it, one after the other in the order kube-proxy, aws-node, coredns, cluster-autoscaler
+followed by the other components, waiting for the rollout of each component before moving to the next
+Usage:
+$ k8sclusterupgradetool component version sync -c=valid-cluster-name`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+
+		// Read config from file
+		configFileName, configFileType, configFilePath := config.FileMetadata()
+		configuration, err := config.Read(configFileName, configFileType, configFilePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		log.Println("Config file used:", viper.ConfigFileUsed())
+		logComponentVersions(configuration)
+
+		if configuration.IsClusterNameValid(cluster) {
+			log.Println("Cluster name is valid")
+		} else {
+			log.Fatal("Please pass a valid clusterName")
+		}
+
+		k8sClient, err := k8s.KubeClientInit(cluster)
+		if err != nil {
+			log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
+		}
+		kubernetesVersion, err := clusterKubernetesVersion(k8sClient, configuration, cluster)
+		if err != nil {
+			log.Fatalf("there was an error reading the Kubernetes version of the cluster: %v", err)
+		}
+
+		registryClient := newRegistryClient(configuration, cluster)
+		var pinResolver registry.DigestResolverInterface
+		if pinDigest, _ := cmd.Flags().GetBool("pin-digest"); pinDigest {
+			pinResolver = registryClient
+		}
+
+		journal := openJournal(cmd, cluster, "component-version-sync", cluster)
+		for _, componentName := range configuration.ComponentUpgradeOrder() {
+			if journal.IsCompleted(componentSyncedStep(componentName)) {
+				log.Printf("%s has already been synced, skipping it\n", componentName)
+				continue
+			}
+
+			componentVersion, err := checkComponentVersion(componentName, cluster, kubernetesVersion, configuration, k8sClient, registryClient)
+			if err != nil {
+				log.Fatalf("error while checking for %s component version: %v", componentName, err)
+			}
+			if componentVersion.Status == report.UpToDate {
+				continue
+			}
+
+			version, _ := configuration.GetComponentVersionForCluster(cluster, kubernetesVersion, componentName)
+			k8sObject, err := configuration.GetK8sObjectForCluster(cluster, componentName)
+			if err != nil {
+				log.Fatalf("there was an error reading config from the config file: %v", err)
+			}
+			err = setComponentVersion(k8sClient, cluster, version, k8sObject, pinResolver)
+			if err != nil {
+				log.Fatalf("there was error while setting component version for %s: %v", componentName, err)
+			}
+			waitForComponentRollout(cmd, k8sClient, cluster, componentName, version, k8sObject, journal)
+			if err := journal.Record(componentSyncedStep(componentName), version); err != nil {
+				log.Println(err)
+			}
+		}
+		finishJournal(journal)
+		log.Printf("All the components of cluster %s are on the version of the config file\n", cluster)
+	},
+}
+
+func init() {
+	componentVersionCmd.AddCommand(syncComponentVersionCmd)
+
+	syncComponentVersionCmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	addRolloutFlags(syncComponentVersionCmd)
+	addRollbackOnFailureFlag(syncComponentVersionCmd)
+	syncComponentVersionCmd.Flags().Bool("pin-digest", false,
+		"resolve the versions to the digest of each image in its registry and pin the containers by digest instead of by tag")
+	addResumeFlag(syncComponentVersionCmd)
+	//nolint
+	syncComponentVersionCmd.MarkFlagRequired("cluster")
+}
+
+func componentSyncedStep(componentName string) string {
+	return "component-synced/" + componentName
+}
@@ -0,0 +1,34 @@
+package k8sclusterupgradetool
+
+import (
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/state"
+	"github.com/spf13/cobra"
+	"log"
+)
+
+// addResumeFlag registers the --resume flag for the commands which record their steps in a journal
+func addResumeFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("resume", false,
+		"continue the interrupted run of the command from its last completed step instead of starting over")
+}
+
+// openJournal returns the journal recording the steps of the operation, picking up the one of the interrupted run when
+// --resume is passed
+func openJournal(cmd *cobra.Command, cluster, operation, target string) *state.Journal {
+	resume, _ := cmd.Flags().GetBool("resume")
+	journal, err := state.NewStore().OpenJournal(cluster, operation, target, resume)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if journal.Resumed {
+		log.Printf("Resuming the interrupted %s of %s, last completed step: %s\n", operation, target, journal.LastStep())
+	}
+	return journal
+}
+
+// finishJournal marks the operation recorded in the journal as done
+func finishJournal(journal *state.Journal) {
+	if err := journal.Finish(); err != nil {
+		log.Println(err)
+	}
+}
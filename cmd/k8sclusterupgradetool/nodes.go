@@ -0,0 +1,18 @@
+package k8sclusterupgradetool
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var nodesCmd = &cobra.Command{
+	Use: "nodes",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Node operations")
+		fmt.Println("Run 'k8sclusterupgradetool nodes --help' to see the available commands")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(nodesCmd)
+}
@@ -0,0 +1,96 @@
+package k8sclusterupgradetool
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/config"
+	toolConfig "github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/upgrade"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+	"os"
+)
+
+var nodesVersionsCmd = &cobra.Command{
+	Use:   "versions",
+	Short: "Lists the kubelet versions of the nodes of a cluster and of its ASGs",
+	Long: `Lists the nodes of a cluster grouped by kubelet version, along with the instance and the ASG they run on,
+mapped through the instance id of their provider id, and flags the kubelets outside of the version skew supported by
+the API server. An ASG is only reported as upgraded when every one of its instances is a node running the Kubernetes
+minor version of the API server. Exits with 1 when any kubelet is outside of the supported version skew
+
+Usage:
+$ k8sclusterupgradetool nodes versions -c=CLUSTER_NAME
+
+Example:
+$ k8sclusterupgradetool nodes versions -c=valid-cluster-name
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+
+		// Read config from file
+		configFileName, configFileType, configFilePath := toolConfig.FileMetadata()
+		configuration, err := toolConfig.Read(configFileName, configFileType, configFilePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Config file used:", viper.ConfigFileUsed())
+
+		if !configuration.IsClusterNameValid(cluster) {
+			log.Fatalln("Please pass a valid clusterName or check if the AWS account has a mapping inside the tool for the account and the region")
+		}
+		awsAccount, awsRegion, err := configuration.GetAwsAccountAndRegionForCluster(cluster)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		k8sClient, err := k8s.KubeClientInit(cluster)
+		if err != nil {
+			log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
+		}
+		serverVersion, err := k8s.GetServerVersion(k8sClient)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		nodes, err := k8s.GetNodeVersions(k8sClient)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		awsGetterObj := &aws.ConfigGetter{ConfigClientInterface: &aws.Config{}}
+		cfg, err := awsGetterObj.GetConfig(context.TODO(), config.WithRegion(awsRegion), config.WithSharedConfigProfile(awsAccount))
+		if err != nil {
+			log.Fatalln("there was an error while initializing the aws config, please check your aws credentials")
+		}
+		finder := &aws.AutoscalingGroupFinder{ListAutoscalingGroupsInterface: &aws.ClusterAutoScalingGroupsClient{}}
+		groups, err := finder.ClusterAutoScalingGroups(context.TODO(), cfg, cluster)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		instances := aws.AwsInstances{}
+		for _, group := range groups {
+			instances = append(instances, group.Instances...)
+		}
+
+		report := upgrade.NewNodeVersionReport(cluster, serverVersion, nodes, instances)
+		if err := report.Write(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+		if skewed := report.Skewed(); len(skewed) > 0 {
+			log.Printf("%d nodes of cluster %s run a kubelet outside of the version skew supported by the API server\n",
+				len(skewed), cluster)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	nodesCmd.AddCommand(nodesVersionsCmd)
+
+	nodesVersionsCmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	//nolint
+	nodesVersionsCmd.MarkFlagRequired("cluster")
+}
@@ -0,0 +1,103 @@
+package k8sclusterupgradetool
+
+import (
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/checks"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+	"log"
+	"os"
+)
+
+var preflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Checks that a cluster is ready to be upgraded",
+	Long: `Checks that a cluster is ready to be upgraded, each check reporting pass, warn or fail along with its details:
+- nodes-ready fails when nodes are not ready and warns when nodes are cordoned
+- pending-pods warns when pods have been pending for more than 5 minutes
+- pod-disruption-budgets fails when PodDisruptionBudgets don't allow any disruptions
+- kubelet-skew fails when kubelets are newer than the API server or more than 2 minor versions older than the target version (3 from 1.28 on)
+- deprecated-apis fails when objects are written through API versions removed by the target version, see scan deprecated-apis
+Without --to, the target version is the Kubernetes version following the one of the control plane for deprecated-apis
+and the one of the control plane for kubelet-skew. Exits with 1 when any check fails
+
+Usage:
+$ k8sclusterupgradetool preflight -c=CLUSTER_NAME [--to=KUBERNETES_VERSION] [-o=table|json]
+
+Example:
+$ k8sclusterupgradetool preflight -c=valid-cluster-name --to=1.22 -o=json
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		targetVersion, _ := cmd.Flags().GetString("to")
+		output, _ := cmd.Flags().GetString("output")
+		if err := checks.ValidateFormat(output); err != nil {
+			log.Fatalln(err)
+		}
+
+		configFileName, configFileType, configFilePath := config.FileMetadata()
+		configuration, err := config.Read(configFileName, configFileType, configFilePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Config file used:", viper.ConfigFileUsed())
+
+		if !configuration.IsClusterNameValid(cluster) {
+			log.Fatal("Please pass a valid clusterName")
+		}
+		k8sClient, err := k8s.KubeClientInit(cluster)
+		if err != nil {
+			log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
+		}
+
+		dynamicClient, err := k8s.DynamicClientInit(cluster)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		report := checks.Run(checks.Cluster{Name: cluster, K8sClient: k8sClient, DynamicClient: dynamicClient,
+			TargetVersion: targetVersion}, checks.DefaultChecks())
+		if err := report.Write(os.Stdout, output); err != nil {
+			log.Fatalln(err)
+		}
+		if report.Status == checks.Fail {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(preflightCmd)
+
+	preflightCmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	preflightCmd.Flags().String("to", "",
+		"Kubernetes version the cluster is upgraded to, eg: 1.22, defaults to the version following the one of the control plane")
+	preflightCmd.Flags().StringP("output", "o", "table", "output format, one of table, json")
+	//nolint
+	preflightCmd.MarkFlagRequired("cluster")
+}
+
+// addPreflightFlag adds the flag running the preflight checks before the command mutates anything in the cluster
+func addPreflightFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("preflight", false,
+		"run the preflight checks but deprecated-apis before changing anything in the cluster and stop when any of them fails")
+}
+
+// runPreflight runs the preflight checks but deprecated-apis against the cluster when the preflight flag is passed, and
+// exits when any of them fails
+func runPreflight(cmd *cobra.Command, k8sClient kubernetes.Interface, cluster string) {
+	if preflight, _ := cmd.Flags().GetBool("preflight"); !preflight {
+		return
+	}
+	log.Println("Running the preflight checks")
+	report := checks.Run(checks.Cluster{Name: cluster, K8sClient: k8sClient}, checks.MutationChecks())
+	if err := report.Write(os.Stderr, "table"); err != nil {
+		log.Fatalln(err)
+	}
+	if report.Status == checks.Fail {
+		log.Fatalf("the preflight checks of cluster %s failed, nothing was changed", cluster)
+	}
+}
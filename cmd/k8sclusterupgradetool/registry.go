@@ -0,0 +1,35 @@
+package k8sclusterupgradetool
+
+import (
+	"context"
+	awsSdk "github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/registry"
+)
+
+// newRegistryClient returns the client resolving image tags to their digest, authenticating against the ECR registries
+// with the aws credentials of the cluster, which are only loaded once an ECR registry asks for them
+func newRegistryClient(configuration config.Configurations, cluster string) *registry.Client {
+	var cfg *awsSdk.Config
+	ecrCredentials := &aws.EcrCredentialsGetter{EcrAuthorizationTokenInterface: &aws.EcrClient{}}
+
+	return &registry.Client{
+		Credentials: func(ctx context.Context, registryHost string) (string, string, error) {
+			if cfg == nil {
+				awsAccount, awsRegion, err := configuration.GetAwsAccountAndRegionForCluster(cluster)
+				if err != nil {
+					return "", "", err
+				}
+				awsGetterObj := &aws.ConfigGetter{ConfigClientInterface: &aws.Config{}}
+				loaded, err := awsGetterObj.GetConfig(ctx, awsConfig.WithRegion(awsRegion), awsConfig.WithSharedConfigProfile(awsAccount))
+				if err != nil {
+					return "", "", err
+				}
+				cfg = &loaded
+			}
+			return ecrCredentials.RegistryCredentials(ctx, *cfg, registryHost)
+		},
+	}
+}
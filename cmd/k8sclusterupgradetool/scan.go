@@ -0,0 +1,18 @@
+package k8sclusterupgradetool
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+var scanCmd = &cobra.Command{
+	Use: "scan",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Scans of the objects of a cluster")
+		fmt.Println("Run 'k8sclusterupgradetool scan --help' to see the available commands")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(scanCmd)
+}
@@ -0,0 +1,79 @@
+package k8sclusterupgradetool
+
+import (
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/checks"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"log"
+	"os"
+)
+
+var scanDeprecatedAPIsCmd = &cobra.Command{
+	Use:   "deprecated-apis",
+	Short: "Lists the objects of a cluster written through API versions removed by a Kubernetes version",
+	Long: `Lists the objects of a cluster written through API versions removed after the Kubernetes version of the
+control plane, up to and including the one passed with --target, the next minor version by default.
+Every removed API version the cluster still serves is listed, an object being reported when its
+kubectl.kubernetes.io/last-applied-configuration annotation or the managed fields of one of the clients writing it use
+the removed API version. Exits with 1 when any object is found
+
+Usage:
+$ k8sclusterupgradetool scan deprecated-apis -c=CLUSTER_NAME [--target=KUBERNETES_VERSION] [-o=table|json]
+
+Example:
+$ k8sclusterupgradetool scan deprecated-apis -c=valid-cluster-name --target=1.25
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		targetVersion, _ := cmd.Flags().GetString("target")
+		output, _ := cmd.Flags().GetString("output")
+		if err := checks.ValidateFormat(output); err != nil {
+			log.Fatalln(err)
+		}
+
+		configFileName, configFileType, configFilePath := config.FileMetadata()
+		configuration, err := config.Read(configFileName, configFileType, configFilePath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Println("Config file used:", viper.ConfigFileUsed())
+
+		if !configuration.IsClusterNameValid(cluster) {
+			log.Fatal("Please pass a valid clusterName")
+		}
+		k8sClient, err := k8s.KubeClientInit(cluster)
+		if err != nil {
+			log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
+		}
+		dynamicClient, err := k8s.DynamicClientInit(cluster)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		objects, targetVersion, err := checks.ScanDeprecatedAPIs(k8sClient, dynamicClient, targetVersion)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Printf("%d objects of cluster %s use API versions removed by %s\n", len(objects), cluster, targetVersion)
+		if err := checks.WriteDeprecatedObjects(os.Stdout, output, objects); err != nil {
+			log.Fatalln(err)
+		}
+		if len(objects) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	scanCmd.AddCommand(scanDeprecatedAPIsCmd)
+
+	scanDeprecatedAPIsCmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	scanDeprecatedAPIsCmd.Flags().String("target", "",
+		"Kubernetes version the cluster is upgraded to, eg: 1.25, defaults to the version following the one of the control plane")
+	scanDeprecatedAPIsCmd.Flags().StringP("output", "o", "table", "output format, one of table, json")
+	//nolint
+	scanDeprecatedAPIsCmd.MarkFlagRequired("cluster")
+}
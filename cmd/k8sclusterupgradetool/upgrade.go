@@ -0,0 +1,133 @@
+package k8sclusterupgradetool
+
+import (
+	"context"
+	"fmt"
+	awsSdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	toolConfig "github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/report"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/state"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/upgrade"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+	"log"
+)
+
+var upgradeCmd = &cobra.Command{
+	Use: "upgrade",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("Cluster upgrade operations")
+		fmt.Println("Run 'k8sclusterupgradetool upgrade --help' to see the available commands")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(upgradeCmd)
+}
+
+// addUpgradePlanFlags registers the flags of the commands computing the upgrade plan of a cluster
+func addUpgradePlanFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("cluster", "c", "",
+		"Example cluster name input valid-cluster-name, check with team for a full list of valid clusters")
+	cmd.Flags().String("to", "",
+		"Kubernetes version to upgrade the cluster to, at most one minor version ahead of the control plane, eg: 1.22, defaults to the version of the control plane")
+	//nolint
+	cmd.MarkFlagRequired("cluster")
+}
+
+// upgradeClients reads the config file and returns it along with the k8s client and the aws config of the cluster
+func upgradeClients(cluster string) (toolConfig.Configurations, kubernetes.Interface, awsSdk.Config) {
+	// Read config from file
+	configFileName, configFileType, configFilePath := toolConfig.FileMetadata()
+	configuration, err := toolConfig.Read(configFileName, configFileType, configFilePath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("Config file used:", viper.ConfigFileUsed())
+
+	if !configuration.IsClusterNameValid(cluster) {
+		log.Fatalln("Please pass a valid clusterName or check if the AWS account has a mapping inside the tool for the account and the region")
+	}
+	awsAccount, awsRegion, err := configuration.GetAwsAccountAndRegionForCluster(cluster)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	k8sClient, err := k8s.KubeClientInit(cluster)
+	if err != nil {
+		log.Fatal("There was an error initializing the k8sclient with the passed cluster context")
+	}
+
+	awsGetterObj := &aws.ConfigGetter{ConfigClientInterface: &aws.Config{}}
+	cfg, err := awsGetterObj.GetConfig(context.TODO(), config.WithRegion(awsRegion), config.WithSharedConfigProfile(awsAccount))
+	if err != nil {
+		log.Fatalln("there was an error while initializing the aws config, please check your aws credentials")
+	}
+	return configuration, k8sClient, cfg
+}
+
+// newUpgradePlan computes the plan upgrading the cluster to the target Kubernetes version from the version its control
+// plane runs, the version of its components checked against the versions of the config file for the target
+// Kubernetes version and the kubelet versions of the nodes of its ASGs
+func newUpgradePlan(configuration toolConfig.Configurations, k8sClient kubernetes.Interface, cfg awsSdk.Config,
+	cluster, targetVersion string) upgrade.Plan {
+	eksClient := &aws.EksClient{}
+	eksCluster, err := eksClient.DescribeCluster(context.TODO(), cfg, cluster)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	kubernetesVersion := targetVersion
+	if kubernetesVersion == "" {
+		kubernetesVersion = eksCluster.Version
+	}
+	log.Printf("cluster %s runs Kubernetes %s, planning its upgrade to %s\n", cluster, eksCluster.Version, kubernetesVersion)
+	for _, warning := range configuration.CompatibilityWarnings(cluster, kubernetesVersion) {
+		log.Printf("warning: %s\n", warning)
+	}
+
+	resolver := newRegistryClient(configuration, cluster)
+	var componentVersions []report.ComponentVersion
+	for _, componentName := range configuration.ComponentUpgradeOrder() {
+		componentVersion, err := checkComponentVersion(componentName, cluster, kubernetesVersion, configuration, k8sClient, resolver)
+		if err != nil {
+			log.Printf("error while checking for %s component version: %v", componentName, err)
+		}
+		componentVersions = append(componentVersions, componentVersion)
+	}
+
+	finder := &aws.AutoscalingGroupFinder{ListAutoscalingGroupsInterface: &aws.ClusterAutoScalingGroupsClient{}}
+	groups, err := finder.ClusterAutoScalingGroups(context.TODO(), cfg, cluster)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	kubeletVersions, err := k8s.GetNodeKubeletVersions(k8sClient)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	cordonedNodes, err := k8s.GetCordonedNodeNames(k8sClient)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var nodeGroups []upgrade.NodeGroup
+	for _, group := range groups {
+		// the nodes of an ASG whose taint and drain was interrupted are cordoned too, it is kept in the plan so that the
+		// drain is finished
+		interrupted, err := state.NewStore().IsInterrupted(cluster, "taint-and-drain", group.AsgName)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		nodeGroups = append(nodeGroups, upgrade.NodeGroup{AsgName: group.AsgName,
+			KubeletVersions: asgKubeletVersions(group, kubeletVersions),
+			Drained:         !interrupted && asgDrained(group, cordonedNodes)})
+	}
+
+	plan, err := upgrade.NewPlan(cluster, eksCluster.Version, targetVersion, componentVersions, nodeGroups)
+	if err != nil {
+		log.Fatalf("there was an error computing the upgrade plan of cluster %s: %v", cluster, err)
+	}
+	return plan
+}
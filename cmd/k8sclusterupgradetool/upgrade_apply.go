@@ -0,0 +1,134 @@
+package k8sclusterupgradetool
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/registry"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/upgrade"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"strings"
+)
+
+var upgradeApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Runs the steps upgrading a cluster to a Kubernetes version one after the other",
+	Long: `Computes the plan upgrading a cluster to the Kubernetes version passed, the same as 'upgrade plan', and runs its
+steps one after the other, asking for a confirmation before each of them unless --yes is passed:
+the control plane is upgraded as with 'cluster upgrade-control-plane', the components are set as with
+'component version set' and the ASGs are tainted and drained as with 'asg taint-and-drain --dry-run=false'.
+The version of the components is checked once all the steps are done.
+
+The plan is computed again on every run: running the command again after a failed or declined step skips the control
+plane and the components already on the version and the ASGs whose nodes are all cordoned by an earlier taint and drain.
+An ASG whose drain was interrupted is planned again, continue it with 'asg taint-and-drain --resume' or put its
+capacity back with 'asg restore' before running the command again.
+
+The nodes are not replaced: the drained ASGs keep running the old kubelet until their nodes are replaced, eg. with
+'asg rotate' once their launch template uses the new Kubernetes version.
+
+Usage:
+$ k8sclusterupgradetool upgrade apply -c=CLUSTER_NAME --to=KUBERNETES_VERSION
+
+Example:
+$ k8sclusterupgradetool upgrade apply -c=valid-cluster-name --to=1.22
+$ k8sclusterupgradetool upgrade apply -c=valid-cluster-name --to=1.22 --yes --max-unavailable=25%
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		targetVersion, _ := cmd.Flags().GetString("to")
+		yes, _ := cmd.Flags().GetBool("yes")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		drainOptions, err := drainOptionsFromFlags(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		drainBatchOptions, err := drainBatchOptionsFromFlags(cmd)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		configuration, k8sClient, cfg := upgradeClients(cluster)
+		plan := newUpgradePlan(configuration, k8sClient, cfg, cluster, targetVersion)
+		if err := plan.Write(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+
+		var pinResolver registry.DigestResolverInterface
+		if pinDigest, _ := cmd.Flags().GetBool("pin-digest"); pinDigest {
+			pinResolver = newRegistryClient(configuration, cluster)
+		}
+
+		journal := openJournal(cmd, cluster, "upgrade-apply", cluster)
+		stdin := bufio.NewReader(os.Stdin)
+		for i, step := range plan.Steps {
+			if !yes && !confirm(stdin, fmt.Sprintf("Step %d of %d: %s?", i+1, len(plan.Steps), step)) {
+				log.Printf("Step %d was not confirmed, stopping, run the command again to plan the steps left\n", i+1)
+				os.Exit(1)
+			}
+			log.Printf("Step %d of %d: %s\n", i+1, len(plan.Steps), step)
+
+			switch step.Kind {
+			case upgrade.ControlPlaneStep:
+				upgrader := &aws.ControlPlaneUpgrader{EksClusterInterface: &aws.EksClient{}, Timeout: timeout,
+					PollInterval: controlPlaneUpdatePollInterval}
+				if _, err := upgrader.Upgrade(context.TODO(), cfg, cluster, step.To); err != nil {
+					log.Fatalln(err)
+				}
+			case upgrade.ComponentStep:
+				k8sObject, err := configuration.GetK8sObjectForCluster(cluster, step.Name)
+				if err != nil {
+					log.Fatalf("there was an error reading config from the config file: %v", err)
+				}
+				if err := setComponentVersion(k8sClient, cluster, step.To, k8sObject, pinResolver); err != nil {
+					log.Fatalf("there was error while setting component version for %s: %v", step.Name, err)
+				}
+				waitForComponentRollout(cmd, k8sClient, cluster, step.Name, step.To, k8sObject, journal)
+			case upgrade.NodeGroupStep:
+				awsAsgClient := &aws.AutoScalingGroupClient{Asg: aws.AutoScalingGroup{AsgName: step.Name}}
+				asgObject, err := awsAsgClient.DescribeAutoScalingGroup(context.TODO(), cfg)
+				if err != nil {
+					log.Fatalln(err)
+				}
+				verifyAsgOwnership(k8sClient, cluster, asgObject)
+				log.Println("Instances which are going to be tainted and drained from the ASG")
+				asgObject.Instances.PrettyPrint()
+				taintAndDrainAsg(cmd, k8sClient, cfg, cluster, awsAsgClient, asgObject.Instances, drainOptions, drainBatchOptions)
+			}
+
+			if err := journal.Record(fmt.Sprintf("%s/%s", step.Kind, step.Name), step.To); err != nil {
+				log.Println(err)
+			}
+		}
+		finishJournal(journal)
+
+		log.Println("running post upgrade checks")
+		exitWithCheckResult(cmd, checkCluster(cluster, configuration))
+	},
+}
+
+func init() {
+	upgradeCmd.AddCommand(upgradeApplyCmd)
+
+	addUpgradePlanFlags(upgradeApplyCmd)
+	upgradeApplyCmd.Flags().Bool("yes", false, "run all the steps of the plan without asking for a confirmation")
+	upgradeApplyCmd.Flags().Duration("timeout", defaultControlPlaneTimeout,
+		"how long to wait for the update of the control plane to complete")
+	addRolloutFlags(upgradeApplyCmd)
+	addRollbackOnFailureFlag(upgradeApplyCmd)
+	upgradeApplyCmd.Flags().Bool("pin-digest", false,
+		"resolve the versions to the digest of each image in its registry and pin the containers by digest instead of by tag")
+	addDrainFlags(upgradeApplyCmd)
+	addDrainBatchFlags(upgradeApplyCmd)
+}
+
+// confirm asks the question on stdout and returns true when it is answered with y or yes
+func confirm(stdin *bufio.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,40 @@
+package k8sclusterupgradetool
+
+import (
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+)
+
+var upgradePlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Prints the ordered steps upgrading a cluster to a Kubernetes version",
+	Long: `Prints the ordered steps upgrading a cluster to the Kubernetes version passed, computed from the config file,
+the version of the components running in the cluster and the ASGs of the cluster:
+the control plane first, then the components not on the version of the config file for the Kubernetes version in the
+order kube-proxy, aws-node, coredns, cluster-autoscaler followed by the other components, and finally the taint and
+drain of the ASGs whose nodes don't run the Kubernetes version yet
+
+Usage:
+$ k8sclusterupgradetool upgrade plan -c=CLUSTER_NAME --to=KUBERNETES_VERSION
+
+Example:
+$ k8sclusterupgradetool upgrade plan -c=valid-cluster-name --to=1.22
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cluster, _ := cmd.Flags().GetString("cluster")
+		targetVersion, _ := cmd.Flags().GetString("to")
+
+		configuration, k8sClient, cfg := upgradeClients(cluster)
+		plan := newUpgradePlan(configuration, k8sClient, cfg, cluster, targetVersion)
+		if err := plan.Write(os.Stdout); err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+func init() {
+	upgradeCmd.AddCommand(upgradePlanCmd)
+
+	addUpgradePlanFlags(upgradePlanCmd)
+}
@@ -2,7 +2,12 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"github.com/spf13/viper"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 const (
@@ -12,19 +17,49 @@ const (
 )
 
 type Configurations struct {
-	Components  ComponentVersionConfigurations `mapstructure:"components"`
-	ClusterList []ClusterListConfiguration     `mapstructure:"clusterlist"`
+	Components ComponentConfigurations `mapstructure:"components"`
+	// ClusterGroups maps the name of a group of clusters to its configuration, the names are lowercased when read
+	ClusterGroups map[string]ClusterGroupConfiguration `mapstructure:"clustergroups"`
+	ClusterList   []ClusterListConfiguration           `mapstructure:"clusterlist"`
+	// Compatibility is the compatibility matrix of the components, the versions they have to be on for each
+	// Kubernetes minor version
+	Compatibility []CompatibilityConfiguration `mapstructure:"compatibility"`
+}
+
+// CompatibilityConfiguration is the version the components have to be on in the clusters running a Kubernetes minor
+// version
+type CompatibilityConfiguration struct {
+	// KubernetesVersion is the minor version, eg: 1.21
+	KubernetesVersion string `mapstructure:"KubernetesVersion"`
+	// ComponentVersions is the version of the components for the Kubernetes minor version, the component names are
+	// lowercased when read
+	ComponentVersions map[string]string `mapstructure:"ComponentVersions"`
+}
+
+// kubernetesMinorComponents are the components released along with each Kubernetes minor version, which have to be on
+// the same minor version as the cluster
+var kubernetesMinorComponents = map[string]bool{"kube-proxy": true, "cluster-autoscaler": true}
+
+var minorVersionPattern = regexp.MustCompile(`^v?([0-9]+)\.([0-9]+)`)
+
+// ClusterGroupConfiguration is the configuration shared by the clusters of a group, eg: the canary clusters
+type ClusterGroupConfiguration struct {
+	// ComponentVersions overrides the version of the components for the clusters of the group
+	ComponentVersions map[string]string `mapstructure:"ComponentVersions"`
 }
 
 // reference: https://stackoverflow.com/questions/63889004/how-to-access-specific-items-in-an-array-from-viper
 type ClusterListConfiguration struct {
-	ClusterName             string    `mapstructure:"ClusterName"`
-	AwsRegion               string    `mapstructure:"AwsRegion"`
-	AwsAccount              string    `mapstructure:"AwsAccount"`
-	AwsNodeObject           K8sObject `mapstructure:"AwsNodeObject"`
-	ClusterAutoscalerObject K8sObject `mapstructure:"ClusterAutoscalerObject"`
-	CoreDnsObject           K8sObject `mapstructure:"CoreDnsObject"`
-	KubeProxyObject         K8sObject `mapstructure:"KubeProxyObject"`
+	ClusterName string `mapstructure:"ClusterName"`
+	AwsRegion   string `mapstructure:"AwsRegion"`
+	AwsAccount  string `mapstructure:"AwsAccount"`
+	// Group is the name of the group of clusters the cluster is part of, if any
+	Group string `mapstructure:"Group"`
+	// ComponentVersions overrides the version of the components for the cluster, taking precedence over the one of its
+	// group, the component names are lowercased when read
+	ComponentVersions map[string]string `mapstructure:"ComponentVersions"`
+	// Components overrides the k8s object of the components for the cluster, only the attributes set are overridden
+	Components map[string]K8sObject `mapstructure:"Components"`
 }
 
 type K8sObject struct {
@@ -32,13 +67,165 @@ type K8sObject struct {
 	ObjectType     string `mapstructure:"ObjectType"`
 	ContainerName  string `mapstructure:"ContainerName"`
 	Namespace      string `mapstructure:"Namespace"`
+	// Containers and InitContainers are the other containers of the object, eg: sidecars, whose image is moved to the
+	// same version as the one of ContainerName
+	Containers     []string `mapstructure:"Containers"`
+	InitContainers []string `mapstructure:"InitContainers"`
+}
+
+// ContainerNames returns ContainerName followed by the other Containers of the object
+func (k K8sObject) ContainerNames() []string {
+	names := []string{k.ContainerName}
+	for _, name := range k.Containers {
+		if name != k.ContainerName {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ComponentConfiguration is the version a component has to be on, along with the k8s object running it
+type ComponentConfiguration struct {
+	Version   string `mapstructure:"Version"`
+	K8sObject `mapstructure:",squash"`
+}
+
+// ComponentConfigurations maps the name of a component to its configuration, the names are lowercased when read
+type ComponentConfigurations map[string]ComponentConfiguration
+
+// ComponentNames returns the names of all the components, sorted
+func (c Configurations) ComponentNames() []string {
+	names := make([]string, 0, len(c.Components))
+	for name := range c.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// upgradeOrder is the order the core components are upgraded in, kube-proxy and the CNI first as the other components
+// depend on the pod network, and the cluster-autoscaler last so that it doesn't scale the nodes while they are upgraded
+var upgradeOrder = []string{"kube-proxy", "aws-node", "coredns", "cluster-autoscaler"}
+
+// ComponentUpgradeOrder returns the names of all the components in the order they are upgraded in, the core components
+// first followed by the other components sorted by name
+func (c Configurations) ComponentUpgradeOrder() []string {
+	names := make([]string, 0, len(c.Components))
+	ordered := map[string]bool{}
+	for _, name := range upgradeOrder {
+		if _, ok := c.Components[name]; ok {
+			names = append(names, name)
+			ordered[name] = true
+		}
+	}
+	for _, name := range c.ComponentNames() {
+		if !ordered[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// GetComponentVersion returns the version the component has to be on
+func (c Configurations) GetComponentVersion(componentName string) (string, error) {
+	component, ok := c.Components[componentName]
+	if !ok {
+		return "", c.unknownComponentError(componentName)
+	}
+	return component.Version, nil
+}
+
+// GetComponentVersionForCluster returns the version the component has to be on in the cluster running the Kubernetes
+// version passed, eg: v1.21.14-eks-18ef993. The version set for the cluster takes precedence over the one set for its
+// group, then over the one of the compatibility matrix for the Kubernetes minor version and then over the one of the
+// components key. An empty Kubernetes version skips the compatibility matrix
+func (c Configurations) GetComponentVersionForCluster(clusterName, kubernetesVersion, componentName string) (string, error) {
+	version, err := c.GetComponentVersion(componentName)
+	if err != nil {
+		return "", err
+	}
+	if compatible := c.compatibleComponentVersion(kubernetesVersion, componentName); compatible != "" {
+		version = compatible
+	}
+	for _, cluster := range c.ClusterList {
+		if cluster.ClusterName != clusterName {
+			continue
+		}
+		if override := cluster.ComponentVersions[componentName]; override != "" {
+			return override, nil
+		}
+		if override := c.clusterGroup(cluster).ComponentVersions[componentName]; override != "" {
+			return override, nil
+		}
+		return version, nil
+	}
+	return "", errors.New("please check if you passed a valid cluster name")
+}
+
+func (c Configurations) clusterGroup(cluster ClusterListConfiguration) ClusterGroupConfiguration {
+	return c.ClusterGroups[strings.ToLower(cluster.Group)]
+}
+
+// compatibleComponentVersion returns the version of the component in the compatibility matrix for the minor version
+// of the Kubernetes version passed, if any
+func (c Configurations) compatibleComponentVersion(kubernetesVersion, componentName string) string {
+	compatibility, ok := c.compatibility(kubernetesVersion)
+	if !ok {
+		return ""
+	}
+	return compatibility.ComponentVersions[componentName]
+}
+
+func (c Configurations) compatibility(kubernetesVersion string) (CompatibilityConfiguration, bool) {
+	minor, ok := MinorVersion(kubernetesVersion)
+	if !ok {
+		return CompatibilityConfiguration{}, false
+	}
+	for _, compatibility := range c.Compatibility {
+		if compatibilityMinor, _ := MinorVersion(compatibility.KubernetesVersion); compatibilityMinor == minor {
+			return compatibility, true
+		}
+	}
+	return CompatibilityConfiguration{}, false
+}
+
+// CompatibilityWarnings returns a warning for each component whose version resolved for the cluster running the
+// Kubernetes version passed is not the one of the compatibility matrix, or for the components released along with
+// each Kubernetes minor version, is not on the minor version of the cluster
+func (c Configurations) CompatibilityWarnings(clusterName, kubernetesVersion string) []string {
+	minor, ok := MinorVersion(kubernetesVersion)
+	if !ok {
+		return []string{fmt.Sprintf("unable to read the minor version of the Kubernetes version %s of cluster %s", kubernetesVersion, clusterName)}
+	}
+
+	var warnings []string
+	if _, ok := c.compatibility(kubernetesVersion); !ok && len(c.Compatibility) > 0 {
+		warnings = append(warnings, fmt.Sprintf("the compatibility matrix has no versions for Kubernetes %s of cluster %s", minor, clusterName))
+	}
+	for _, componentName := range c.ComponentNames() {
+		version, err := c.GetComponentVersionForCluster(clusterName, kubernetesVersion, componentName)
+		if err != nil {
+			continue
+		}
+		if compatible := c.compatibleComponentVersion(kubernetesVersion, componentName); compatible != "" && compatible != version {
+			warnings = append(warnings, fmt.Sprintf("%s version %s of cluster %s is overridden, the compatibility matrix has %s for Kubernetes %s",
+				componentName, version, clusterName, compatible, minor))
+		}
+		if componentMinor, ok := MinorVersion(version); ok && kubernetesMinorComponents[componentName] && componentMinor != minor {
+			warnings = append(warnings, fmt.Sprintf("%s version %s is on minor %s while cluster %s runs Kubernetes %s",
+				componentName, version, componentMinor, clusterName, minor))
+		}
+	}
+	return warnings
 }
 
-type ComponentVersionConfigurations struct {
-	AwsNode           string `mapstructure:"aws-node"`
-	ClusterAutoscaler string `mapstructure:"cluster-autoscaler"`
-	CoreDns           string `mapstructure:"coredns"`
-	KubeProxy         string `mapstructure:"kube-proxy"`
+// MinorVersion returns the major and minor version, eg: 1.21, of a version such as v1.21.14-eks-18ef993 or 1.21
+func MinorVersion(version string) (string, bool) {
+	match := minorVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return "", false
+	}
+	return match[1] + "." + match[2], true
 }
 
 func (c Configurations) IsClusterListConfigurationValid() bool {
@@ -50,27 +237,121 @@ func (c Configurations) IsClusterListConfigurationValid() bool {
 		}
 		clusterNameMap[cluster.ClusterName] = "present"
 
-		clusterName := cluster.ClusterName == ""
-		awsRegion := cluster.AwsRegion == ""
-		awsAccount := cluster.AwsAccount == ""
-		awsNodeObject := cluster.AwsNodeObject.DeploymentName == "" || cluster.AwsNodeObject.ObjectType == "" || cluster.AwsNodeObject.ContainerName == "" || cluster.AwsNodeObject.Namespace == ""
-		clusterAutoscaler := cluster.ClusterAutoscalerObject.DeploymentName == "" || cluster.ClusterAutoscalerObject.ObjectType == "" || cluster.ClusterAutoscalerObject.ContainerName == "" || cluster.ClusterAutoscalerObject.Namespace == ""
-		coreDns := cluster.CoreDnsObject.DeploymentName == "" || cluster.CoreDnsObject.ObjectType == "" || cluster.CoreDnsObject.ContainerName == "" || cluster.CoreDnsObject.Namespace == ""
-		kubeProxy := cluster.KubeProxyObject.DeploymentName == "" || cluster.KubeProxyObject.ObjectType == "" || cluster.KubeProxyObject.ContainerName == "" || cluster.KubeProxyObject.Namespace == ""
-
-		if clusterName || awsRegion || awsAccount || awsNodeObject || clusterAutoscaler || coreDns || kubeProxy {
+		if cluster.ClusterName == "" || cluster.AwsRegion == "" || cluster.AwsAccount == "" {
 			valid = false
 		}
+		for componentName := range cluster.Components {
+			if _, ok := c.Components[componentName]; !ok {
+				valid = false
+			}
+		}
+		if _, ok := c.ClusterGroups[strings.ToLower(cluster.Group)]; cluster.Group != "" && !ok {
+			valid = false
+		}
+		if !c.areComponentVersionsValid(cluster.ComponentVersions) {
+			valid = false
+		}
+		for componentName := range c.Components {
+			if !cluster.k8sObject(componentName, c.Components[componentName].K8sObject).isValid() {
+				valid = false
+			}
+		}
 	}
 	return valid
 }
 
+// IsClusterGroupConfigurationValid returns false when a group overrides the version of an unknown component or sets
+// an empty version
+func (c Configurations) IsClusterGroupConfigurationValid() bool {
+	for _, group := range c.ClusterGroups {
+		if !c.areComponentVersionsValid(group.ComponentVersions) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Configurations) areComponentVersionsValid(componentVersions map[string]string) bool {
+	for componentName, version := range componentVersions {
+		if _, ok := c.Components[componentName]; !ok || version == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// IsCompatibilityConfigurationValid returns false when an element of the compatibility matrix has an invalid or
+// duplicated Kubernetes minor version or sets the version of an unknown component or an empty version
+func (c Configurations) IsCompatibilityConfigurationValid() bool {
+	minors := map[string]bool{}
+	for _, compatibility := range c.Compatibility {
+		minor, ok := MinorVersion(compatibility.KubernetesVersion)
+		if !ok || minors[minor] || !c.areComponentVersionsValid(compatibility.ComponentVersions) {
+			return false
+		}
+		minors[minor] = true
+	}
+	return true
+}
+
 func (c Configurations) IsComponentVersionConfigurationsValid() bool {
-	valid := true
-	if c.Components.CoreDns == "" || c.Components.AwsNode == "" || c.Components.ClusterAutoscaler == "" || c.Components.KubeProxy == "" {
-		valid = false
+	if len(c.Components) == 0 {
+		return false
 	}
-	return valid
+	for _, component := range c.Components {
+		if component.Version == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ClusterNames returns the names of all the clusters of the clusterlist, in the order they are listed in
+func (c Configurations) ClusterNames() []string {
+	names := make([]string, 0, len(c.ClusterList))
+	for _, cluster := range c.ClusterList {
+		names = append(names, cluster.ClusterName)
+	}
+	return names
+}
+
+// SelectClusterNames returns the names of the clusters of the clusterlist matching the selector, a comma separated list
+// of glob patterns, eg: prod-*, or of regular expressions wrapped in slashes, eg: /^prod-(eu|us)-[0-9]+$/
+func (c Configurations) SelectClusterNames(selector string) ([]string, error) {
+	var matchers []func(string) bool
+	for _, pattern := range strings.Split(selector, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			expression, err := regexp.Compile(pattern[1 : len(pattern)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid cluster selector %s: %v", pattern, err)
+			}
+			matchers = append(matchers, expression.MatchString)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return nil, fmt.Errorf("invalid cluster selector %s", pattern)
+		}
+		glob := pattern
+		matchers = append(matchers, func(name string) bool {
+			matched, _ := path.Match(glob, name)
+			return matched
+		})
+	}
+
+	var names []string
+	for _, name := range c.ClusterNames() {
+		for _, matches := range matchers {
+			if matches(name) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no cluster of the clusterlist matches %s", selector)
+	}
+	return names, nil
 }
 
 func (c Configurations) IsClusterNameValid(clusterName string) bool {
@@ -83,54 +364,47 @@ func (c Configurations) IsClusterNameValid(clusterName string) bool {
 	return contains
 }
 
-func (c Configurations) GetK8sObjectForCluster(clusterName, k8sObjectType string) (k8sObject K8sObject, err error) {
+// GetK8sObjectForCluster returns the k8s object running the component in the cluster, the object configured for the
+// component with the overrides of the cluster applied
+func (c Configurations) GetK8sObjectForCluster(clusterName, componentName string) (k8sObject K8sObject, err error) {
+	component, ok := c.Components[componentName]
+	if !ok {
+		return K8sObject{}, c.unknownComponentError(componentName)
+	}
 	for _, cluster := range c.ClusterList {
 		if cluster.ClusterName == clusterName {
-			switch k8sObjectType {
-			case "aws-node":
-				return K8sObject{
-					DeploymentName: cluster.AwsNodeObject.DeploymentName,
-					ObjectType:     cluster.AwsNodeObject.ObjectType,
-					ContainerName:  cluster.AwsNodeObject.ContainerName,
-					Namespace:      cluster.AwsNodeObject.Namespace,
-				}, nil
-			case "cluster-autoscaler":
-				return K8sObject{
-					DeploymentName: cluster.ClusterAutoscalerObject.DeploymentName,
-					ObjectType:     cluster.ClusterAutoscalerObject.ObjectType,
-					ContainerName:  cluster.ClusterAutoscalerObject.ContainerName,
-					Namespace:      cluster.ClusterAutoscalerObject.Namespace,
-				}, nil
-			case "kube-proxy":
-				return K8sObject{
-					DeploymentName: cluster.KubeProxyObject.DeploymentName,
-					ObjectType:     cluster.KubeProxyObject.ObjectType,
-					ContainerName:  cluster.KubeProxyObject.ContainerName,
-					Namespace:      cluster.KubeProxyObject.Namespace,
-				}, nil
-			case "coredns":
-				return K8sObject{
-					DeploymentName: cluster.CoreDnsObject.DeploymentName,
-					ObjectType:     cluster.CoreDnsObject.ObjectType,
-					ContainerName:  cluster.CoreDnsObject.ContainerName,
-					Namespace:      cluster.CoreDnsObject.Namespace,
-				}, nil
-			default:
-				return K8sObject{
-					DeploymentName: "",
-					ObjectType:     "",
-					ContainerName:  "",
-					Namespace:      "",
-				}, errors.New("please pass any of the components between aws-node, coredns, cluster-autoscaler, kube-proxy")
-			}
+			return cluster.k8sObject(componentName, component.K8sObject), nil
 		}
 	}
-	return K8sObject{
-		DeploymentName: "",
-		ObjectType:     "",
-		ContainerName:  "",
-		Namespace:      "",
-	}, errors.New("please check if you passed a valid cluster name")
+	return K8sObject{}, errors.New("please check if you passed a valid cluster name")
+}
+
+// k8sObject applies the attributes set in the override of the component for the cluster on top of the object passed
+func (cluster ClusterListConfiguration) k8sObject(componentName string, k8sObject K8sObject) K8sObject {
+	override := cluster.Components[componentName]
+	if override.DeploymentName != "" {
+		k8sObject.DeploymentName = override.DeploymentName
+	}
+	if override.ObjectType != "" {
+		k8sObject.ObjectType = override.ObjectType
+	}
+	if override.ContainerName != "" {
+		k8sObject.ContainerName = override.ContainerName
+	}
+	if override.Namespace != "" {
+		k8sObject.Namespace = override.Namespace
+	}
+	if override.Containers != nil {
+		k8sObject.Containers = override.Containers
+	}
+	if override.InitContainers != nil {
+		k8sObject.InitContainers = override.InitContainers
+	}
+	return k8sObject
+}
+
+func (k K8sObject) isValid() bool {
+	return k.DeploymentName != "" && k.ObjectType != "" && k.ContainerName != "" && k.Namespace != ""
 }
 
 func (c Configurations) GetAwsAccountAndRegionForCluster(clusterName string) (awsAccount, awsRegion string, err error) {
@@ -142,31 +416,25 @@ func (c Configurations) GetAwsAccountAndRegionForCluster(clusterName string) (aw
 	return "", "", errors.New("no awsAccount and awsRegion was found for the passed clusterName")
 }
 
-func (c Configurations) ValidatePassedComponentVersions(componentName, componentVersion string) error {
-	switch componentName {
-	case "aws-node":
-		if componentVersion != c.Components.AwsNode {
-			return errors.New("aws-node component version passed doesn't match the version in config, please check the value in config file")
-		}
-	case "cluster-autoscaler":
-		if componentVersion != c.Components.ClusterAutoscaler {
-			return errors.New("cluster-autoscaler component version passed doesn't match the version in config, please check the value in config file")
-		}
-	case "kube-proxy":
-		if componentVersion != c.Components.KubeProxy {
-			return errors.New("kube-proxy component version passed doesn't match the version in config, please check the value in config file")
-		}
-	case "coredns":
-		if componentVersion != c.Components.CoreDns {
-			return errors.New("coredns component version passed doesn't match the version in config, please check the value in config file")
-		}
-	default:
-		return errors.New("please pass a valid component name from this list [coredns, cluster-autoscaler, kube-proxy, aws-node]")
+// ValidatePassedComponentVersions returns an error when the version passed is not the version the component has to be
+// on in the cluster running the Kubernetes version passed
+func (c Configurations) ValidatePassedComponentVersions(clusterName, kubernetesVersion, componentName, componentVersion string) error {
+	version, err := c.GetComponentVersionForCluster(clusterName, kubernetesVersion, componentName)
+	if err != nil {
+		return err
+	}
+	if componentVersion != version {
+		return fmt.Errorf("%s component version passed doesn't match the version %s in config for cluster %s, please check the value in config file",
+			componentName, version, clusterName)
 	}
-
 	return nil
 }
 
+func (c Configurations) unknownComponentError(componentName string) error {
+	return fmt.Errorf("%s is not a component in the config file, please pass a valid component name from this list [%s]",
+		componentName, strings.Join(c.ComponentNames(), ", "))
+}
+
 func Read(fileName, fileType, filePath string) (config Configurations, err error) {
 	viper.SetConfigName(fileName)
 	viper.SetConfigType(fileType)
@@ -180,23 +448,136 @@ func Read(fileName, fileType, filePath string) (config Configurations, err error
 		}
 	}
 
+	if err := checkLegacyFormat(); err != nil {
+		return Configurations{}, err
+	}
+
 	err = viper.Unmarshal(&config)
 	if err != nil {
 		return Configurations{}, errors.New("error un marshaling config file")
 	}
+	// viper lowercases the keys of maps but not the ones of the maps nested in lists
+	for i, cluster := range config.ClusterList {
+		config.ClusterList[i].ComponentVersions = lowercaseComponentNames(cluster.ComponentVersions)
+		config.ClusterList[i].Components = lowercaseComponentObjects(cluster.Components)
+	}
+	for i, compatibility := range config.Compatibility {
+		config.Compatibility[i].ComponentVersions = lowercaseComponentNames(compatibility.ComponentVersions)
+	}
 
 	// check for the mandatory config file variables being read
 	if !config.IsComponentVersionConfigurationsValid() {
-		return Configurations{}, errors.New("mandatory component version of either aws-node, coredns, kube-proxy or cluster-autoscaler not set in config file")
+		return Configurations{}, errors.New("no components set in config file or one of the components has no Version set")
+	}
+
+	if !config.IsClusterGroupConfigurationValid() {
+		return Configurations{}, errors.New("one of the clustergroups overrides the version of an unknown component or has an empty version")
+	}
+
+	if !config.IsCompatibilityConfigurationValid() {
+		return Configurations{}, errors.New("one of the compatibility elements has an invalid or duplicated KubernetesVersion, " +
+			"sets the version of an unknown component or has an empty version")
 	}
 
 	if !config.IsClusterListConfigurationValid() {
-		return Configurations{}, errors.New("one of the clusterlist elements has either ClusterName, AwsRegion, AwsAccount, AwsNodeObject, ClusterAutoscalerObject, KubeProxyObject, CoreDnsObject is missing")
+		return Configurations{}, errors.New("one of the clusterlist elements has either ClusterName, AwsRegion, AwsAccount missing, " +
+			"overrides an unknown component, is part of an unknown Group or has one of DeploymentName, ObjectType, ContainerName, " +
+			"Namespace missing for a component")
 	}
 
 	return config, nil
 }
 
+func lowercaseComponentNames(componentVersions map[string]string) map[string]string {
+	lowercased := map[string]string{}
+	for componentName, version := range componentVersions {
+		lowercased[strings.ToLower(componentName)] = version
+	}
+	return lowercased
+}
+
+func lowercaseComponentObjects(components map[string]K8sObject) map[string]K8sObject {
+	lowercased := map[string]K8sObject{}
+	for componentName, k8sObject := range components {
+		lowercased[strings.ToLower(componentName)] = k8sObject
+	}
+	return lowercased
+}
+
+// legacyClusterObjectKeys are the keys the k8s objects of the components were set with for each cluster before the
+// components key mapped each component to its version and k8s object
+var legacyClusterObjectKeys = []string{"AwsNodeObject", "ClusterAutoscalerObject", "CoreDnsObject", "KubeProxyObject"}
+
+// checkLegacyFormat returns an error explaining how to migrate the config file read when it is in the format where
+// components mapped each component name to its version and the clusters set the k8s object of each component
+func checkLegacyFormat() error {
+	var found []string
+	var scalarComponents []string
+	for componentName, component := range viper.GetStringMap("components") {
+		if _, ok := component.(string); ok {
+			scalarComponents = append(scalarComponents, componentName)
+		}
+	}
+	if len(scalarComponents) > 0 {
+		sort.Strings(scalarComponents)
+		found = append(found, fmt.Sprintf("components %s are mapped to a version", strings.Join(scalarComponents, ", ")))
+	}
+
+	clusters, _ := viper.Get("clusterlist").([]interface{})
+	for _, cluster := range clusters {
+		keys := mapKeys(cluster)
+		for _, legacyKey := range legacyClusterObjectKeys {
+			if keys[strings.ToLower(legacyKey)] {
+				found = append(found, fmt.Sprintf("cluster %v sets %s", clusterName(cluster), legacyKey))
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the config file is in the old format (%s): move the version of each component under the Version "+
+		"key of the component in components, along with the ObjectType, DeploymentName, ContainerName and Namespace of "+
+		"its k8s object, and replace the %s keys of the clusters by an optional Components key overriding them, "+
+		"see config.sample.yaml", strings.Join(found, "; "), strings.Join(legacyClusterObjectKeys, ", "))
+}
+
+// mapKeys returns the lowercased keys of a map read from the config file, nested maps being read by viper either with
+// string or interface keys
+func mapKeys(value interface{}) map[string]bool {
+	keys := map[string]bool{}
+	switch m := value.(type) {
+	case map[string]interface{}:
+		for key := range m {
+			keys[strings.ToLower(key)] = true
+		}
+	case map[interface{}]interface{}:
+		for key := range m {
+			keys[strings.ToLower(fmt.Sprint(key))] = true
+		}
+	}
+	return keys
+}
+
+// clusterName returns the ClusterName of a cluster of the clusterlist read from the config file
+func clusterName(cluster interface{}) interface{} {
+	switch m := cluster.(type) {
+	case map[string]interface{}:
+		for key, value := range m {
+			if strings.EqualFold(key, "ClusterName") {
+				return value
+			}
+		}
+	case map[interface{}]interface{}:
+		for key, value := range m {
+			if strings.EqualFold(fmt.Sprint(key), "ClusterName") {
+				return value
+			}
+		}
+	}
+	return "<unnamed>"
+}
+
 func FileMetadata() (fileName, filePath, fileType string) {
 	return FileName, FileType, FilePath
 }
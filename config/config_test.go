@@ -10,6 +10,19 @@ import (
 	"testing"
 )
 
+func testComponents() ComponentConfigurations {
+	return ComponentConfigurations{
+		"aws-node": {Version: "aws-node-version",
+			K8sObject: K8sObject{DeploymentName: "aws-node", ObjectType: "daemonset", ContainerName: "aws-node", Namespace: "kube-system"}},
+		"cluster-autoscaler": {Version: "cluster-autoscaler-version",
+			K8sObject: K8sObject{DeploymentName: "cluster-autoscaler", ObjectType: "deployment", ContainerName: "aws-cluster-autoscaler", Namespace: "kube-system"}},
+		"coredns": {Version: "coredns-version",
+			K8sObject: K8sObject{DeploymentName: "coredns", ObjectType: "deployment", ContainerName: "coredns", Namespace: "kube-system"}},
+		"kube-proxy": {Version: "kube-proxy-version",
+			K8sObject: K8sObject{DeploymentName: "kube-proxy", ObjectType: "daemonset", ContainerName: "kube-proxy", Namespace: "kube-system"}},
+	}
+}
+
 func TestConfigurations_IsClusterListConfigurationValid(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -17,200 +30,92 @@ func TestConfigurations_IsClusterListConfigurationValid(t *testing.T) {
 		result        bool
 	}{
 		{
-			name: "when the config passed has all keys and values for awsnode, coredns, clusterautoscaler, kubeproxy present",
+			name: "when the config passed has all the cluster attributes and the k8s objects of all the components",
 			configuration: Configurations{
+				Components: testComponents(),
 				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster1",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
-					{
-						ClusterName: "cluster2",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account"},
+					{ClusterName: "cluster2", AwsRegion: "region", AwsAccount: "account"},
 				},
 			},
 			result: true,
 		},
 		{
-			name: "when the config passed has all keys and values for awsnode, coredns, clusterautoscaler, kubeproxy present but one of the values for the keys is an empty string",
+			name: "when the config passed overrides an attribute of the k8s object of a component for a cluster",
 			configuration: Configurations{
+				Components: testComponents(),
 				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster1",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
-					{
-						ClusterName: "cluster2",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account",
+						Components: map[string]K8sObject{"coredns": {ContainerName: "core-dns"}}},
+				},
+			},
+			result: true,
+		},
+		{
+			name: "when the config passed has a component with one of the attributes of its k8s object missing",
+			configuration: Configurations{
+				Components: ComponentConfigurations{
+					"metrics-server": {Version: "v0.6.1", K8sObject: K8sObject{DeploymentName: "metrics-server", ObjectType: "deployment", Namespace: "kube-system"}},
+				},
+				ClusterList: []ClusterListConfiguration{
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account"},
 				},
 			},
 			result: false,
 		},
 		{
-			name: "when the config passed has one of the k8sObjects keys missing",
+			name: "when the config passed has the missing attribute of the k8s object of a component set by the cluster",
 			configuration: Configurations{
+				Components: ComponentConfigurations{
+					"metrics-server": {Version: "v0.6.1", K8sObject: K8sObject{DeploymentName: "metrics-server", ObjectType: "deployment", Namespace: "kube-system"}},
+				},
 				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster2",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account",
+						Components: map[string]K8sObject{"metrics-server": {ContainerName: "metrics-server"}}},
+				},
+			},
+			result: true,
+		},
+		{
+			name: "when the config passed overrides the k8s object of an unknown component for a cluster",
+			configuration: Configurations{
+				Components: testComponents(),
+				ClusterList: []ClusterListConfiguration{
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account",
+						Components: map[string]K8sObject{"external-dns": {ContainerName: "external-dns"}}},
 				},
 			},
 			result: false,
 		},
 		{
-			name: "when the config passed has one of the attributes of k8sObject attribute missing",
+			name: "when the config passed overrides the version of a component for a cluster and its group",
 			configuration: Configurations{
+				Components:    testComponents(),
+				ClusterGroups: map[string]ClusterGroupConfiguration{"canary": {ComponentVersions: map[string]string{"aws-node": "v1.12.0"}}},
 				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster1",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account", Group: "canary",
+						ComponentVersions: map[string]string{"coredns": "v1.8.7"}},
+				},
+			},
+			result: true,
+		},
+		{
+			name: "when the config passed has a cluster part of an unknown group",
+			configuration: Configurations{
+				Components: testComponents(),
+				ClusterList: []ClusterListConfiguration{
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account", Group: "canary"},
+				},
+			},
+			result: false,
+		},
+		{
+			name: "when the config passed overrides the version of an unknown component for a cluster",
+			configuration: Configurations{
+				Components: testComponents(),
+				ClusterList: []ClusterListConfiguration{
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account",
+						ComponentVersions: map[string]string{"external-dns": "v0.12.0"}},
 				},
 			},
 			result: false,
@@ -218,58 +123,9 @@ func TestConfigurations_IsClusterListConfigurationValid(t *testing.T) {
 		{
 			name: "when the config passed has ClusterName attribute value missing",
 			configuration: Configurations{
+				Components: testComponents(),
 				ClusterList: []ClusterListConfiguration{
-					{
-						AwsRegion:  "region",
-						AwsAccount: "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
-					{
-						ClusterName: "cluster2",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
+					{ClusterName: "", AwsRegion: "region", AwsAccount: "account"},
 				},
 			},
 			result: false,
@@ -277,64 +133,9 @@ func TestConfigurations_IsClusterListConfigurationValid(t *testing.T) {
 		{
 			name: "when the config passed has AwsRegion attribute value missing",
 			configuration: Configurations{
+				Components: testComponents(),
 				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster-1",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
-					{
-						ClusterName: "cluster2",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
+					{ClusterName: "cluster1", AwsRegion: "", AwsAccount: "account"},
 				},
 			},
 			result: false,
@@ -342,64 +143,9 @@ func TestConfigurations_IsClusterListConfigurationValid(t *testing.T) {
 		{
 			name: "when the config passed has AwsAccount attribute value missing",
 			configuration: Configurations{
+				Components: testComponents(),
 				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster-1",
-						AwsRegion:   "region",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
-					{
-						ClusterName: "cluster2",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: ""},
 				},
 			},
 			result: false,
@@ -407,65 +153,10 @@ func TestConfigurations_IsClusterListConfigurationValid(t *testing.T) {
 		{
 			name: "when the config passed has two clusters added with the same clusterName attribute",
 			configuration: Configurations{
+				Components: testComponents(),
 				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster1",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
-					{
-						ClusterName: "cluster1",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "container-name",
-							Namespace:      "kube-system",
-						},
-					},
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account"},
+					{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account"},
 				},
 			},
 			result: false,
@@ -531,6 +222,42 @@ func TestConfigurations_IsClusterNameValid(t *testing.T) {
 	}
 }
 
+func TestConfigurations_SelectClusterNames(t *testing.T) {
+	configuration := Configurations{ClusterList: []ClusterListConfiguration{
+		{ClusterName: "prod-eu-1"}, {ClusterName: "prod-us-1"}, {ClusterName: "staging-eu-1"}, {ClusterName: "prod-eu-canary"},
+	}}
+
+	tests := []struct {
+		name     string
+		selector string
+		want     []string
+		err      error
+	}{
+		{"when the selector is a glob", "prod-*", []string{"prod-eu-1", "prod-us-1", "prod-eu-canary"}, nil},
+		{"when the selector is a regular expression", "/^prod-(eu|us)-[0-9]+$/", []string{"prod-eu-1", "prod-us-1"}, nil},
+		{"when the selector is a list, the clusters keep the order of the clusterlist", "staging-*, prod-eu-1",
+			[]string{"prod-eu-1", "staging-eu-1"}, nil},
+		{"when the selector is a cluster name", "prod-us-1", []string{"prod-us-1"}, nil},
+		{"when no cluster matches", "dev-*", nil, errors.New("no cluster of the clusterlist matches dev-*")},
+		{"when the regular expression is invalid", "/prod-(/", nil,
+			errors.New("invalid cluster selector /prod-(/: error parsing regexp: missing closing ): `prod-(`")},
+		{"when the glob is invalid", "prod-[", nil, errors.New("invalid cluster selector prod-[")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := configuration.SelectClusterNames(tt.selector)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestConfigurations_ClusterNames(t *testing.T) {
+	configuration := Configurations{ClusterList: []ClusterListConfiguration{{ClusterName: "b"}, {ClusterName: "a"}}}
+
+	assert.Equal(t, []string{"b", "a"}, configuration.ClusterNames())
+}
+
 func TestConfigurations_GetAwsAccountAndRegionForCluster(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -595,8 +322,21 @@ func TestConfigurations_GetAwsAccountAndRegionForCluster(t *testing.T) {
 
 func TestConfigurations_ValidatePassedComponentVersions(t *testing.T) {
 	type testArgs struct {
-		componentName    string
-		componentVersion string
+		clusterName       string
+		kubernetesVersion string
+		componentName     string
+		componentVersion  string
+	}
+	overridesConfig := Configurations{
+		Components:    testComponents(),
+		ClusterGroups: map[string]ClusterGroupConfiguration{"canary": {ComponentVersions: map[string]string{"coredns": "canary-version"}}},
+		ClusterList: []ClusterListConfiguration{
+			{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account"},
+			{ClusterName: "cluster2", AwsRegion: "region", AwsAccount: "account", Group: "canary"},
+		},
+		Compatibility: []CompatibilityConfiguration{
+			{KubernetesVersion: "1.22", ComponentVersions: map[string]string{"coredns": "v1.8.7"}},
+		},
 	}
 	tests := []struct {
 		name   string
@@ -605,31 +345,40 @@ func TestConfigurations_ValidatePassedComponentVersions(t *testing.T) {
 		err    error
 	}{
 		{"when passed component version name is valid and the version to be set matches the config file",
-			Configurations{
-				Components: ComponentVersionConfigurations{
-					CoreDns: "rightvalue", ClusterAutoscaler: "rightvalue", KubeProxy: "rightvalue", AwsNode: "rightvalue"}},
-			testArgs{componentName: "coredns", componentVersion: "rightvalue"},
+			overridesConfig,
+			testArgs{clusterName: "cluster1", componentName: "coredns", componentVersion: "coredns-version"},
 			nil,
 		},
 		{"when passed component version name is valid and the version to be set doesn't match the config file",
-			Configurations{
-				Components: ComponentVersionConfigurations{
-					CoreDns: "rightvalue", ClusterAutoscaler: "rightvalue", KubeProxy: "rightvalue", AwsNode: "rightvalue"}},
-			testArgs{componentName: "coredns", componentVersion: "wrongvalue"},
-			errors.New("coredns component version passed doesn't match the version in config, please check the value in config file"),
+			overridesConfig,
+			testArgs{clusterName: "cluster1", componentName: "coredns", componentVersion: "wrongvalue"},
+			errors.New("coredns component version passed doesn't match the version coredns-version in config for cluster cluster1, please check the value in config file"),
+		},
+		{"when the version to be set matches the version overridden for the group of the cluster",
+			overridesConfig,
+			testArgs{clusterName: "cluster2", componentName: "coredns", componentVersion: "canary-version"},
+			nil,
+		},
+		{"when the version to be set is the one of the config file but the group of the cluster overrides it",
+			overridesConfig,
+			testArgs{clusterName: "cluster2", componentName: "coredns", componentVersion: "coredns-version"},
+			errors.New("coredns component version passed doesn't match the version canary-version in config for cluster cluster2, please check the value in config file"),
+		},
+		{"when the version to be set matches the version of the compatibility matrix for the cluster",
+			overridesConfig,
+			testArgs{clusterName: "cluster1", kubernetesVersion: "v1.22.17-eks-0a21954", componentName: "coredns", componentVersion: "v1.8.7"},
+			nil,
 		},
 		{"when passed component version is not valid",
-			Configurations{
-				Components: ComponentVersionConfigurations{
-					CoreDns: "rightvalue", ClusterAutoscaler: "rightvalue", KubeProxy: "rightvalue", AwsNode: "rightvalue"}},
-			testArgs{componentName: "foo", componentVersion: "wrongvalue"},
-			errors.New("please pass a valid component name from this list [coredns, cluster-autoscaler, kube-proxy, aws-node]"),
+			overridesConfig,
+			testArgs{clusterName: "cluster1", componentName: "foo", componentVersion: "wrongvalue"},
+			errors.New("foo is not a component in the config file, please pass a valid component name from this list [aws-node, cluster-autoscaler, coredns, kube-proxy]"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.config.ValidatePassedComponentVersions(tt.args.componentName, tt.args.componentVersion)
+			err := tt.config.ValidatePassedComponentVersions(tt.args.clusterName, tt.args.kubernetesVersion, tt.args.componentName, tt.args.componentVersion)
 
 			assert.Equal(t, err, tt.err)
 		})
@@ -643,28 +392,30 @@ func TestConfigurations_IsComponentVersionConfigurationsValid(t *testing.T) {
 		result        bool
 	}{
 		{
-			name: "when all the passed component version configurations are present",
-			configuration: Configurations{
-				Components: ComponentVersionConfigurations{
-					CoreDns:           "core-dns-version",
-					AwsNode:           "aws-node-version",
-					ClusterAutoscaler: "cluster-autoscaler-version",
-					KubeProxy:         "kube-proxy-version",
-				},
-			},
+			name:          "when all the components have a version",
+			configuration: Configurations{Components: testComponents()},
+			result:        true,
+		},
+		{
+			name: "when any component is passed with a version",
+			configuration: Configurations{Components: ComponentConfigurations{
+				"metrics-server": {Version: "v0.6.1"},
+			}},
 			result: true,
 		},
 		{
-			name: "when one of the required component keys are not passed",
-			configuration: Configurations{
-				Components: ComponentVersionConfigurations{
-					AwsNode:           "aws-node-version",
-					ClusterAutoscaler: "cluster-autoscaler-version",
-					KubeProxy:         "kube-proxy-version",
-				},
-			},
+			name: "when one of the components has no version",
+			configuration: Configurations{Components: ComponentConfigurations{
+				"aws-node":       {Version: "aws-node-version"},
+				"metrics-server": {},
+			}},
 			result: false,
 		},
+		{
+			name:          "when no component is passed",
+			configuration: Configurations{},
+			result:        false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -674,180 +425,269 @@ func TestConfigurations_IsComponentVersionConfigurationsValid(t *testing.T) {
 	}
 }
 
+func TestConfigurations_ComponentNames(t *testing.T) {
+	configuration := Configurations{Components: testComponents()}
+
+	assert.Equal(t, []string{"aws-node", "cluster-autoscaler", "coredns", "kube-proxy"}, configuration.ComponentNames())
+}
+
+func TestConfigurations_ComponentUpgradeOrder(t *testing.T) {
+	components := testComponents()
+	components["metrics-server"] = ComponentConfiguration{Version: "metrics-server-version"}
+	components["external-dns"] = ComponentConfiguration{Version: "external-dns-version"}
+	delete(components, "coredns")
+	configuration := Configurations{Components: components}
+
+	assert.Equal(t, []string{"kube-proxy", "aws-node", "cluster-autoscaler", "external-dns", "metrics-server"},
+		configuration.ComponentUpgradeOrder())
+}
+
+func TestConfigurations_GetComponentVersion(t *testing.T) {
+	configuration := Configurations{Components: testComponents()}
+
+	version, err := configuration.GetComponentVersion("kube-proxy")
+	assert.Nil(t, err)
+	assert.Equal(t, "kube-proxy-version", version)
+
+	_, err = configuration.GetComponentVersion("external-dns")
+	assert.NotNil(t, err)
+}
+
+func TestConfigurations_GetComponentVersionForCluster(t *testing.T) {
+	configuration := Configurations{
+		Components: testComponents(),
+		ClusterGroups: map[string]ClusterGroupConfiguration{
+			"canary": {ComponentVersions: map[string]string{"aws-node": "aws-node-canary-version", "coredns": "coredns-canary-version"}},
+		},
+		ClusterList: []ClusterListConfiguration{
+			{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account"},
+			{ClusterName: "cluster2", AwsRegion: "region", AwsAccount: "account", Group: "Canary",
+				ComponentVersions: map[string]string{"coredns": "coredns-cluster2-version"}},
+		},
+		Compatibility: []CompatibilityConfiguration{
+			{KubernetesVersion: "1.21", ComponentVersions: map[string]string{"kube-proxy": "v1.21.2", "coredns": "v1.8.4"}},
+			{KubernetesVersion: "v1.22", ComponentVersions: map[string]string{"kube-proxy": "v1.22.11"}},
+		},
+	}
+	tests := []struct {
+		name              string
+		clusterName       string
+		kubernetesVersion string
+		componentName     string
+		want              string
+		err               error
+	}{
+		{"when the version is not overridden for the cluster", "cluster1", "", "aws-node", "aws-node-version", nil},
+		{"when the version is overridden for the group of the cluster", "cluster2", "", "aws-node", "aws-node-canary-version", nil},
+		{"when the version is overridden for the cluster and its group, the cluster wins", "cluster2", "", "coredns",
+			"coredns-cluster2-version", nil},
+		{"when the version is overridden for neither the cluster nor its group", "cluster2", "", "kube-proxy", "kube-proxy-version", nil},
+		{"when the version is in the compatibility matrix for the minor version of the cluster", "cluster1", "v1.21.14-eks-18ef993",
+			"kube-proxy", "v1.21.2", nil},
+		{"when the minor version of the compatibility matrix has a v prefix", "cluster1", "v1.22.17-eks-0a21954", "kube-proxy", "v1.22.11", nil},
+		{"when the component is not in the compatibility matrix for the minor version of the cluster", "cluster1",
+			"v1.22.17-eks-0a21954", "coredns", "coredns-version", nil},
+		{"when the minor version of the cluster is not in the compatibility matrix", "cluster1", "v1.23.1", "kube-proxy",
+			"kube-proxy-version", nil},
+		{"when the version is overridden for the cluster and in the compatibility matrix, the cluster wins", "cluster2",
+			"v1.21.14-eks-18ef993", "coredns", "coredns-cluster2-version", nil},
+		{"when the cluster is unknown", "cluster3", "", "aws-node", "", errors.New("please check if you passed a valid cluster name")},
+		{"when the component is unknown", "cluster1", "", "external-dns", "",
+			errors.New("external-dns is not a component in the config file, please pass a valid component name from this list [aws-node, cluster-autoscaler, coredns, kube-proxy]")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := configuration.GetComponentVersionForCluster(tt.clusterName, tt.kubernetesVersion, tt.componentName)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestConfigurations_CompatibilityWarnings(t *testing.T) {
+	configuration := Configurations{
+		Components: ComponentConfigurations{
+			"aws-node":           {Version: "v1.11.4"},
+			"cluster-autoscaler": {Version: "v1.21.2"},
+			"kube-proxy":         {Version: "v1.21.2"},
+		},
+		ClusterList: []ClusterListConfiguration{
+			{ClusterName: "cluster1"},
+			{ClusterName: "cluster2", ComponentVersions: map[string]string{"aws-node": "v1.12.0"}},
+		},
+		Compatibility: []CompatibilityConfiguration{
+			{KubernetesVersion: "1.22", ComponentVersions: map[string]string{"aws-node": "v1.11.4", "kube-proxy": "v1.22.11"}},
+		},
+	}
+	tests := []struct {
+		name              string
+		clusterName       string
+		kubernetesVersion string
+		want              []string
+	}{
+		{"when the versions match the compatibility matrix and the minor version of the cluster", "cluster1", "v1.22.17-eks-0a21954",
+			[]string{"cluster-autoscaler version v1.21.2 is on minor 1.21 while cluster cluster1 runs Kubernetes 1.22"}},
+		{"when a version overridden for the cluster is not the one of the compatibility matrix", "cluster2", "v1.22.17-eks-0a21954",
+			[]string{
+				"aws-node version v1.12.0 of cluster cluster2 is overridden, the compatibility matrix has v1.11.4 for Kubernetes 1.22",
+				"cluster-autoscaler version v1.21.2 is on minor 1.21 while cluster cluster2 runs Kubernetes 1.22",
+			}},
+		{"when the minor version of the cluster is not in the compatibility matrix", "cluster1", "v1.21.14-eks-18ef993",
+			[]string{"the compatibility matrix has no versions for Kubernetes 1.21 of cluster cluster1"}},
+		{"when the Kubernetes version can't be read", "cluster1", "unknown",
+			[]string{"unable to read the minor version of the Kubernetes version unknown of cluster cluster1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, configuration.CompatibilityWarnings(tt.clusterName, tt.kubernetesVersion))
+		})
+	}
+}
+
+func TestConfigurations_IsCompatibilityConfigurationValid(t *testing.T) {
+	tests := []struct {
+		name          string
+		compatibility []CompatibilityConfiguration
+		result        bool
+	}{
+		{"when there is no compatibility matrix", nil, true},
+		{"when the compatibility matrix sets the version of known components", []CompatibilityConfiguration{
+			{KubernetesVersion: "1.21", ComponentVersions: map[string]string{"kube-proxy": "v1.21.2"}},
+			{KubernetesVersion: "1.22", ComponentVersions: map[string]string{"kube-proxy": "v1.22.11"}},
+		}, true},
+		{"when a Kubernetes minor version is duplicated", []CompatibilityConfiguration{
+			{KubernetesVersion: "1.21", ComponentVersions: map[string]string{"kube-proxy": "v1.21.2"}},
+			{KubernetesVersion: "v1.21", ComponentVersions: map[string]string{"coredns": "v1.8.4"}},
+		}, false},
+		{"when a Kubernetes minor version is invalid", []CompatibilityConfiguration{
+			{KubernetesVersion: "latest", ComponentVersions: map[string]string{"kube-proxy": "v1.21.2"}},
+		}, false},
+		{"when the compatibility matrix sets the version of an unknown component", []CompatibilityConfiguration{
+			{KubernetesVersion: "1.21", ComponentVersions: map[string]string{"external-dns": "v0.12.0"}},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configuration := Configurations{Components: testComponents(), Compatibility: tt.compatibility}
+			assert.Equal(t, tt.result, configuration.IsCompatibilityConfigurationValid())
+		})
+	}
+}
+
+func TestMinorVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+		ok      bool
+	}{
+		{"v1.21.14-eks-18ef993", "1.21", true},
+		{"1.22", "1.22", true},
+		{"v1.8.4-eksbuild.1", "1.8", true},
+		{"latest", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, ok := MinorVersion(tt.version)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestConfigurations_IsClusterGroupConfigurationValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups map[string]ClusterGroupConfiguration
+		result bool
+	}{
+		{"when there are no groups", nil, true},
+		{"when the groups override the version of known components",
+			map[string]ClusterGroupConfiguration{"canary": {ComponentVersions: map[string]string{"aws-node": "v1.12.0"}}}, true},
+		{"when a group overrides the version of an unknown component",
+			map[string]ClusterGroupConfiguration{"canary": {ComponentVersions: map[string]string{"external-dns": "v0.12.0"}}}, false},
+		{"when a group overrides the version of a component with an empty version",
+			map[string]ClusterGroupConfiguration{"canary": {ComponentVersions: map[string]string{"aws-node": ""}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configuration := Configurations{Components: testComponents(), ClusterGroups: tt.groups}
+			assert.Equal(t, tt.result, configuration.IsClusterGroupConfigurationValid())
+		})
+	}
+}
+
 func TestConfigurations_GetK8sObjectForCluster(t *testing.T) {
-	type result struct {
-		DeploymentName, ObjectType, ContainerName, Namespace string
+	configuration := Configurations{
+		Components: testComponents(),
+		ClusterList: []ClusterListConfiguration{
+			{ClusterName: "cluster1", AwsRegion: "region", AwsAccount: "account"},
+			{ClusterName: "cluster2", AwsRegion: "region", AwsAccount: "account",
+				Components: map[string]K8sObject{
+					"cluster-autoscaler": {ContainerName: "cluster-autoscaler"},
+					"aws-node":           {InitContainers: []string{"aws-vpc-cni-init"}},
+				}},
+		},
 	}
 	tests := []struct {
 		name                         string
-		configuration                Configurations
 		clusterNameArg, k8sObjectArg string
-		expectedResult               result
+		expectedResult               K8sObject
 		expectedErr                  error
 	}{
 		{
-			name: "when the cluster name is present, k8s object and container name passed is valid",
-			configuration: Configurations{
-				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster1",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "aws-node",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "aws-cluster-autoscaler",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "kube-proxy",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "core-dns",
-							Namespace:      "kube-system",
-						},
-					},
-					{
-						ClusterName: "cluster2",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-							ContainerName:  "aws-node",
-							Namespace:      "kube-system",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-							ContainerName:  "aws-cluster-autoscaler",
-							Namespace:      "kube-system",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-							ContainerName:  "kube-proxy",
-							Namespace:      "kube-system",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-							ContainerName:  "core-dns",
-							Namespace:      "kube-system",
-						},
-					},
-				},
-			},
+			name:           "when the cluster name is present and the component passed is valid",
 			clusterNameArg: "cluster1",
 			k8sObjectArg:   "cluster-autoscaler",
-			expectedResult: result{
-				DeploymentName: "cluster-autoscaler",
-				ObjectType:     "deployment",
-				ContainerName:  "aws-cluster-autoscaler",
-				Namespace:      "kube-system",
-			},
+			expectedResult: K8sObject{DeploymentName: "cluster-autoscaler", ObjectType: "deployment", ContainerName: "aws-cluster-autoscaler", Namespace: "kube-system"},
+			expectedErr:    nil,
+		},
+		{
+			name:           "when the cluster name is present and overrides the k8s object of the component passed",
+			clusterNameArg: "cluster2",
+			k8sObjectArg:   "cluster-autoscaler",
+			expectedResult: K8sObject{DeploymentName: "cluster-autoscaler", ObjectType: "deployment", ContainerName: "cluster-autoscaler", Namespace: "kube-system"},
+			expectedErr:    nil,
+		},
+		{
+			name:           "when the cluster name is present and overrides the init containers of the component passed",
+			clusterNameArg: "cluster2",
+			k8sObjectArg:   "aws-node",
+			expectedResult: K8sObject{DeploymentName: "aws-node", ObjectType: "daemonset", ContainerName: "aws-node", Namespace: "kube-system",
+				InitContainers: []string{"aws-vpc-cni-init"}},
 			expectedErr: nil,
 		},
 		{
-			name: "when the cluster name is present and the k8sobject passed is invalid",
-			configuration: Configurations{
-				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster1",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-						},
-					},
-				},
-			},
+			name:           "when the cluster name is present and the component passed is invalid",
 			clusterNameArg: "cluster1",
 			k8sObjectArg:   "invalid-arg",
-			expectedResult: result{
-				DeploymentName: "",
-				ObjectType:     "",
-				ContainerName:  "",
-				Namespace:      "",
-			},
-			expectedErr: errors.New("please pass any of the components between aws-node, coredns, cluster-autoscaler, kube-proxy"),
+			expectedResult: K8sObject{},
+			expectedErr:    errors.New("invalid-arg is not a component in the config file, please pass a valid component name from this list [aws-node, cluster-autoscaler, coredns, kube-proxy]"),
 		},
 		{
-			name: "when the cluster name is not present",
-			configuration: Configurations{
-				ClusterList: []ClusterListConfiguration{
-					{
-						ClusterName: "cluster2",
-						AwsRegion:   "region",
-						AwsAccount:  "account",
-						AwsNodeObject: K8sObject{
-							DeploymentName: "aws-node",
-							ObjectType:     "daemonset",
-						},
-						ClusterAutoscalerObject: K8sObject{
-							DeploymentName: "cluster-autoscaler",
-							ObjectType:     "deployment",
-						},
-						KubeProxyObject: K8sObject{
-							DeploymentName: "kube-proxy",
-							ObjectType:     "daemonset",
-						},
-						CoreDnsObject: K8sObject{
-							DeploymentName: "coredns",
-							ObjectType:     "deployment",
-						},
-					},
-				},
-			},
+			name:           "when the cluster name is not present",
 			clusterNameArg: "invalid cluster",
 			k8sObjectArg:   "aws-node",
-			expectedResult: result{
-				DeploymentName: "",
-				ObjectType:     "",
-				ContainerName:  "",
-				Namespace:      "",
-			},
-			expectedErr: errors.New("please check if you passed a valid cluster name"),
+			expectedResult: K8sObject{},
+			expectedErr:    errors.New("please check if you passed a valid cluster name"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resultk8sObject, actualError := tt.configuration.GetK8sObjectForCluster(tt.clusterNameArg, tt.k8sObjectArg)
+			resultk8sObject, actualError := configuration.GetK8sObjectForCluster(tt.clusterNameArg, tt.k8sObjectArg)
 
-			assert.Equal(t, tt.expectedResult.DeploymentName, resultk8sObject.DeploymentName)
-			assert.Equal(t, tt.expectedResult.ObjectType, resultk8sObject.ObjectType)
-			assert.Equal(t, tt.expectedResult.ContainerName, resultk8sObject.ContainerName)
-			assert.Equal(t, tt.expectedResult.Namespace, resultk8sObject.Namespace)
+			assert.Equal(t, tt.expectedResult, resultk8sObject)
 			assert.Equal(t, tt.expectedErr, actualError)
 		})
 	}
 }
 
+func TestK8sObject_ContainerNames(t *testing.T) {
+	k8sObject := K8sObject{ContainerName: "aws-node", Containers: []string{"aws-eks-nodeagent", "aws-node"}, InitContainers: []string{"aws-vpc-cni-init"}}
+
+	assert.Equal(t, []string{"aws-node", "aws-eks-nodeagent"}, k8sObject.ContainerNames())
+}
+
 func TestRead(t *testing.T) {
 	type File struct {
 		fileName  string
@@ -856,38 +696,66 @@ func TestRead(t *testing.T) {
 		data      string
 		writeFile bool
 	}
+	components := "---\ncomponents:\n  aws-node:\n    Version: \"aws-node-version\"\n    ObjectType: \"daemonset\"\n    DeploymentName: \"aws-node\"\n    ContainerName: \"aws-node\"\n    Namespace: \"kube-system\"\n  metrics-server:\n    Version: \"v0.6.1\"\n    ObjectType: \"deployment\"\n    DeploymentName: \"metrics-server\"\n    ContainerName: \"metrics-server\"\n    Namespace: \"kube-system\"\n"
 	tests := []struct {
 		name string
 		file File
 		err  error
 	}{
 		{"when the config file is present with all the config keys and read successfully",
-			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  aws-node: \"aws-node-version\"\n  cluster-autoscaler: \"cluster-autoscaler-version\"\n  coredns: \"core-dns-version\"\n  kube-proxy: \"kube-proxy-version\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n  AwsNodeObject:\n    ObjectType: \"daemonset\"\n    DeploymentName: \"aws-node\"\n    ContainerName: \"container-name\"\n    Namespace: \"kube-system\"\n  ClusterAutoscalerObject:\n    ObjectType: \"deployment\"\n    DeploymentName: \"cluster-autoscaler\"\n    ContainerName: \"container-name\"\n    Namespace: \"kube-system\"\n  CoreDnsObject:\n    ObjectType: \"deployment\"\n    DeploymentName: \"coredns\"\n    ContainerName: \"container-name\"\n    Namespace: \"kube-system\"\n  KubeProxyObject:\n    ObjectType: \"daemonset\"\n    DeploymentName: \"kube-proxy\"\n    ContainerName: \"container-name\"\n    Namespace: \"kube-system\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n  AwsNodeObject:\n    ObjectType: \"daemonset\"\n    DeploymentName: \"aws-node\"\n    ContainerName: \"container-name\"\n    Namespace: \"kube-system\"\n  ClusterAutoscalerObject:\n    ObjectType: \"deployment\"\n    DeploymentName: \"cluster-autoscaler\"\n    ContainerName: \"container-name\"\n    Namespace: \"kube-system\"\n  CoreDnsObject:\n    ObjectType: \"deployment\"\n    DeploymentName: \"coredns\"\n    ContainerName: \"container-name\"\n    Namespace: \"kube-system\"\n  KubeProxyObject:\n    ObjectType: \"daemonset\"\n    DeploymentName: \"kube-proxy\"\n    ContainerName: \"container-name\"\n    Namespace: \"kube-system\"", writeFile: true},
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: components + "clusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n  Components:\n    metrics-server:\n      Namespace: \"monitoring\"\n", writeFile: true},
+			nil,
+		},
+		{"when the config file is present with cluster groups and version overrides and read successfully",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: components + "clustergroups:\n  Canary:\n    ComponentVersions:\n      aws-node: \"canary-version\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n  Group: \"Canary\"\n  ComponentVersions:\n    metrics-server: \"v0.6.2\"\n", writeFile: true},
+			nil,
+		},
+		{"when the config file is present with a compatibility matrix and read successfully",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: components + "compatibility:\n- KubernetesVersion: \"1.22\"\n  ComponentVersions:\n    Metrics-Server: \"v0.6.2\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
 			nil,
 		},
+		{"when the config file is present and read successfully, but the compatibility matrix has an invalid Kubernetes version",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: components + "compatibility:\n- KubernetesVersion: \"latest\"\n  ComponentVersions:\n    aws-node: \"v1.11.4\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
+			errors.New("one of the compatibility elements has an invalid or duplicated KubernetesVersion, sets the version of an unknown component or has an empty version"),
+		},
+		{"when the config file is present and read successfully, but a cluster group overrides the version of an unknown component",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: components + "clustergroups:\n  canary:\n    ComponentVersions:\n      external-dns: \"v0.12.0\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
+			errors.New("one of the clustergroups overrides the version of an unknown component or has an empty version"),
+		},
+		{"when the config file is present and read successfully, but a cluster is part of an unknown group",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: components + "clusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n  Group: \"canary\"\n", writeFile: true},
+			errors.New("one of the clusterlist elements has either ClusterName, AwsRegion, AwsAccount missing, overrides an unknown component, is part of an unknown Group or has one of DeploymentName, ObjectType, ContainerName, Namespace missing for a component"),
+		},
 		{"when the config file is present and read successfully, but one of the keys for cluster list config is not present with the value",
-			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  aws-node: \"aws-node-version\"\n  cluster-autoscaler: \"cluster-autoscaler-version\"\n  coredns: \"core-dns-version\"\n  kube-proxy: \"kube-proxy-version\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"\"\n", writeFile: true},
-			errors.New("one of the clusterlist elements has either ClusterName, AwsRegion, AwsAccount, AwsNodeObject, ClusterAutoscalerObject, KubeProxyObject, CoreDnsObject is missing"),
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: components + "clusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"\"\n", writeFile: true},
+			errors.New("one of the clusterlist elements has either ClusterName, AwsRegion, AwsAccount missing, overrides an unknown component, is part of an unknown Group or has one of DeploymentName, ObjectType, ContainerName, Namespace missing for a component"),
 		},
 		{"when the config file is present and read successfully, but one of the keys for cluster list config is not present with the key itself",
-			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  aws-node: \"aws-node-version\"\n  cluster-autoscaler: \"cluster-autoscaler-version\"\n  coredns: \"core-dns-version\"\n  kube-proxy: \"kube-proxy-version\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n", writeFile: true},
-			errors.New("one of the clusterlist elements has either ClusterName, AwsRegion, AwsAccount, AwsNodeObject, ClusterAutoscalerObject, KubeProxyObject, CoreDnsObject is missing"),
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: components + "clusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n", writeFile: true},
+			errors.New("one of the clusterlist elements has either ClusterName, AwsRegion, AwsAccount missing, overrides an unknown component, is part of an unknown Group or has one of DeploymentName, ObjectType, ContainerName, Namespace missing for a component"),
 		},
-		{"when the config file is present and read successfully, but kube-proxy config is not present",
-			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  aws-node: \"aws-node-version\"\n  cluster-autoscaler: \"cluster-autoscaler-version\"\n  coredns: \"core-dns-version\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
-			errors.New("mandatory component version of either aws-node, coredns, kube-proxy or cluster-autoscaler not set in config file"),
+		{"when the config file is present and read successfully, but the k8s object of a component is not complete",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  metrics-server:\n    Version: \"v0.6.1\"\n    ObjectType: \"deployment\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
+			errors.New("one of the clusterlist elements has either ClusterName, AwsRegion, AwsAccount missing, overrides an unknown component, is part of an unknown Group or has one of DeploymentName, ObjectType, ContainerName, Namespace missing for a component"),
 		},
-		{"when the config file is present and read successfully, but aws-node config is not present",
-			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  kube-proxy: \"kube-proxy-version\"\n  cluster-autoscaler: \"cluster-autoscaler-version\"\n  coredns: \"core-dns-version\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
-			errors.New("mandatory component version of either aws-node, coredns, kube-proxy or cluster-autoscaler not set in config file"),
+		{"when the config file is present and read successfully, but the version of a component is not present",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  metrics-server:\n    ObjectType: \"deployment\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
+			errors.New("no components set in config file or one of the components has no Version set"),
 		},
-		{"when the config file is present and read successfully, but cluster-autoscaler config is not present",
-			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  kube-proxy: \"kube-proxy-version\"\n  aws-node: \"aws-node-version\"\n  coredns: \"core-dns-version\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
-			errors.New("mandatory component version of either aws-node, coredns, kube-proxy or cluster-autoscaler not set in config file"),
+		{"when the config file is present and read successfully, but no components are present",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
+			errors.New("no components set in config file or one of the components has no Version set"),
 		},
-		{"when the config file is present and read successfully, but coredns config is not present",
-			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  kube-proxy: \"kube-proxy-version\"\n  aws-node: \"aws-node-version\"\n  cluster-autoscaler: \"cluster-autoscaler-version\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n- ClusterName: \"cluster2\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n", writeFile: true},
-			errors.New("mandatory component version of either aws-node, coredns, kube-proxy or cluster-autoscaler not set in config file"),
+		{"when the config file is present with component overrides of a cluster in mixed case and read successfully",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: components + "clusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n  Components:\n    Metrics-Server:\n      Namespace: \"monitoring\"\n", writeFile: true},
+			nil,
+		},
+		{"when the config file is in the old format with the version of the components and their k8s objects per cluster",
+			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "---\ncomponents:\n  aws-node: \"aws-node-version\"\n  coredns: \"coredns-version\"\nclusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n  AwsNodeObject:\n    ObjectType: \"daemonset\"\n    DeploymentName: \"aws-node\"\n    ContainerName: \"aws-node\"\n    Namespace: \"kube-system\"\n", writeFile: true},
+			errors.New("the config file is in the old format (components aws-node, coredns are mapped to a version; cluster cluster1 sets AwsNodeObject): " +
+				"move the version of each component under the Version key of the component in components, along with the ObjectType, DeploymentName, " +
+				"ContainerName and Namespace of its k8s object, and replace the AwsNodeObject, ClusterAutoscalerObject, CoreDnsObject, KubeProxyObject " +
+				"keys of the clusters by an optional Components key overriding them, see config.sample.yaml"),
 		},
 		{"when the config file is not present",
 			File{fileName: "config", fileType: "yaml", dirName: "/tmp", data: "", writeFile: false},
@@ -918,6 +786,26 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestReadLowercasesTheComponentNamesOfTheClusters(t *testing.T) {
+	data := "---\ncomponents:\n  metrics-server:\n    Version: \"v0.6.1\"\n    ObjectType: \"deployment\"\n    DeploymentName: \"metrics-server\"\n    ContainerName: \"metrics-server\"\n    Namespace: \"kube-system\"\n" +
+		"clusterlist:\n- ClusterName: \"cluster1\"\n  AwsRegion: \"region1\"\n  AwsAccount: \"account1\"\n  ComponentVersions:\n    Metrics-Server: \"v0.6.2\"\n  Components:\n    Metrics-Server:\n      Namespace: \"monitoring\"\n"
+	err := ioutil.WriteFile("/tmp/config.yaml", []byte(data), 0644)
+	if err != nil {
+		log.Fatal("error writing to temp config file for running tests")
+	}
+	defer os.Remove("/tmp/config.yaml")
+
+	config, err := Read("config", "yaml", "/tmp")
+
+	assert.Nil(t, err)
+	k8sObject, err := config.GetK8sObjectForCluster("cluster1", "metrics-server")
+	assert.Nil(t, err)
+	assert.Equal(t, "monitoring", k8sObject.Namespace)
+	version, err := config.GetComponentVersionForCluster("cluster1", "", "metrics-server")
+	assert.Nil(t, err)
+	assert.Equal(t, "v0.6.2", version)
+}
+
 func TestFileMetadata(t *testing.T) {
 	t.Run("returns the correct path, filetype and directory", func(t *testing.T) {
 		gotFileName, gotFileType, gotFilePath := FileMetadata()
@@ -0,0 +1,15 @@
+#!/bin/bash
+
+# the components are installed on versions one below the ones of the config, the check has to exit with the drift status code
+set +e
+./k8sclusterupgradetool component version check -c=kind-k8s-cluster-upgrade-tool-test-cluster --fail-on-drift
+status=$?
+set -e
+
+if [[ $status -eq 2 ]]; then
+  echo "drift detected as expected"
+  exit 0
+else
+  echo "expected the check to exit with status code 2, got $status"
+  exit 1
+fi
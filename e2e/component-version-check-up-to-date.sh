@@ -0,0 +1,6 @@
+#!/bin/bash
+
+set -e
+
+# all the components have been set to the versions of the config, the check has to exit with a zero status code
+./k8sclusterupgradetool component version check -c=kind-k8s-cluster-upgrade-tool-test-cluster --fail-on-drift -o=table
@@ -2,7 +2,9 @@
 
 set -e
 
+# aws-node can't run on a kind cluster, only the update of the k8s object is verified
 ./k8sclusterupgradetool component version set \
 -c=kind-k8s-cluster-upgrade-tool-test-cluster \
 -o=aws-node \
--v=v1.11.1
+-v=v1.11.1 \
+--wait=false
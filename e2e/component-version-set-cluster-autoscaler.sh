@@ -2,7 +2,9 @@
 
 set -e
 
+# cluster-autoscaler can't run on a kind cluster, only the update of the k8s object is verified
 ./k8sclusterupgradetool component version set \
 -c=kind-k8s-cluster-upgrade-tool-test-cluster \
 -o=cluster-autoscaler \
--v=v1.20.1
+-v=v1.20.1 \
+--wait=false
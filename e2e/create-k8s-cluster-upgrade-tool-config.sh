@@ -10,32 +10,33 @@ cat > ~/.k8sclusterupgradetool/config.yaml <<EOF
 ---
 # the values below are just one above from the test values being installed
 components:
-  aws-node: "v1.11.1"
-  cluster-autoscaler: "v1.20.1"
-  coredns: "1.8.4"
-  kube-proxy: "v1.20.14"
-clusterlist:
-- ClusterName: "kind-k8s-cluster-upgrade-tool-test-cluster"
-  AwsRegion: "region1"
-  AwsAccount: "account1"
-  AwsNodeObject:
+  aws-node:
+    Version: "v1.11.1"
     ObjectType: "daemonset"
     DeploymentName: "aws-node"
     ContainerName: "aws-node"
+    InitContainers: ["aws-vpc-cni-init"]
     Namespace: "kube-system"
-  ClusterAutoscalerObject:
+  cluster-autoscaler:
+    Version: "v1.20.1"
     ObjectType: "deployment"
     DeploymentName: "cluster-autoscaler"
     ContainerName: "aws-cluster-autoscaler"
     Namespace: "kube-system"
-  CoreDnsObject:
-    DeploymentName: "coredns"
+  coredns:
+    Version: "1.8.4"
     ObjectType: "deployment"
+    DeploymentName: "coredns"
     ContainerName: "coredns"
     Namespace: "kube-system"
-  KubeProxyObject:
-    DeploymentName: "kube-proxy"
+  kube-proxy:
+    Version: "v1.20.14"
     ObjectType: "daemonset"
+    DeploymentName: "kube-proxy"
     ContainerName: "kube-proxy"
     Namespace: "kube-system"
+clusterlist:
+- ClusterName: "kind-k8s-cluster-upgrade-tool-test-cluster"
+  AwsRegion: "region1"
+  AwsAccount: "account1"
 EOF
@@ -2,9 +2,11 @@ package aws
 
 import (
 	"context"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
-	"log"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"strings"
 )
 
 // TODO: Improve the modelling of cluster and awsinstances to be in the appropriate packages.
@@ -14,42 +16,186 @@ type AutoScalingGroup struct {
 	MinInstances     int
 	MaxInstances     int
 	AsgName          string
+	Tags             map[string]string
+	// LaunchTemplateName and LaunchTemplateVersion are empty for the ASGs using a launch configuration
+	LaunchTemplateName    string
+	LaunchTemplateVersion string
+	// NotRunningInstances are the instances of the ASG which are not running yet, or anymore, along with their state,
+	// eg: i-0a1b2c3d4e5f67890 (pending). They are left out of Instances
+	NotRunningInstances []string
+}
+
+const (
+	// clusterTagPrefix is the prefix of the tag carried by the ASGs of a cluster, followed by the cluster name
+	clusterTagPrefix = "kubernetes.io/cluster/"
+	// eksClusterNameTag is the tag EKS adds to the ASGs of managed node groups, with the cluster name as value
+	eksClusterNameTag = "eks:cluster-name"
+	// eksNodeGroupNameTag is the tag EKS adds to the ASGs of managed node groups, with the node group name as value
+	eksNodeGroupNameTag = "eks:nodegroup-name"
+)
+
+// NodeGroupName returns the name of the EKS managed node group the ASG backs, empty for self managed ASGs
+func (a AutoScalingGroup) NodeGroupName() string {
+	return a.Tags[eksNodeGroupNameTag]
+}
+
+// AutoScalingGroupCapacity holds the size bounds of an ASG
+type AutoScalingGroupCapacity struct {
+	Min     int
+	Max     int
+	Desired int
+}
+
+// VerifyClusterOwnership returns an error unless the ASG carries the kubernetes.io/cluster/<clusterName> tag or the
+// eks:cluster-name tag with the cluster name as value, to avoid touching the ASG of another cluster in the same account
+func (a AutoScalingGroup) VerifyClusterOwnership(clusterName string) error {
+	if _, ok := a.Tags[clusterTagPrefix+clusterName]; ok {
+		return nil
+	}
+	if a.Tags[eksClusterNameTag] == clusterName {
+		return nil
+	}
+	return fmt.Errorf("autoscaling group %s does not belong to cluster %s, it carries neither the %s%s tag nor the %s=%s tag",
+		a.AsgName, clusterName, clusterTagPrefix, clusterName, eksClusterNameTag, clusterName)
+}
+
+// VerifyInstancesRunning returns an error if any instance of the ASG is not running, as it would be left out of the
+// instances tainted, drained or replaced
+func (a AutoScalingGroup) VerifyInstancesRunning() error {
+	if len(a.NotRunningInstances) > 0 {
+		return fmt.Errorf("instances %s of autoscaling group %s are not running, please check the ASG on the console",
+			strings.Join(a.NotRunningInstances, ", "), a.AsgName)
+	}
+	return nil
+}
+
+// Capacity returns the min, max and desired sizes of the ASG
+func (a AutoScalingGroup) Capacity() AutoScalingGroupCapacity {
+	return AutoScalingGroupCapacity{Min: a.MinInstances, Max: a.MaxInstances, Desired: a.DesiredInstances}
 }
 
 type UpdateAutoscalingGroupInterface interface {
-	UpdateAutoScalingGroupCount(ctx context.Context, cfg aws.Config) (*autoscaling.UpdateAutoScalingGroupOutput, error)
+	DescribeAutoScalingGroup(ctx context.Context, cfg aws.Config) (AutoScalingGroup, error)
+	SetAutoScalingGroupCapacity(ctx context.Context, cfg aws.Config, min, max, desired int) error
 }
 
 type AutoScalingGroupClient struct {
 	Asg AutoScalingGroup
 }
 
-func (a *AutoScalingGroupClient) UpdateAutoScalingGroupCount(ctx context.Context, cfg aws.Config) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+// DescribeAutoScalingGroup returns the current min, max and desired sizes of the ASG along with its running instances
+func (a *AutoScalingGroupClient) DescribeAutoScalingGroup(ctx context.Context, cfg aws.Config) (AutoScalingGroup, error) {
+	autoscalingAwsClient := autoscaling.NewFromConfig(cfg)
+	input := &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []string{a.Asg.AsgName},
+	}
+
+	result, err := autoscalingAwsClient.DescribeAutoScalingGroups(ctx, input)
+	if err != nil {
+		return AutoScalingGroup{}, err
+	}
+	if len(result.AutoScalingGroups) == 0 {
+		return AutoScalingGroup{}, fmt.Errorf("autoscaling group %s was not found", a.Asg.AsgName)
+	}
+	return autoScalingGroupFromApi(ctx, cfg, result.AutoScalingGroups[0])
+}
+
+// autoScalingGroupFromApi maps the ASG returned by the AWS API, looking up the private DNS of its running instances
+func autoScalingGroupFromApi(ctx context.Context, cfg aws.Config, group types.AutoScalingGroup) (AutoScalingGroup, error) {
+	asgName := aws.ToString(group.AutoScalingGroupName)
+	var instanceIds []string
+	for _, instance := range group.Instances {
+		instanceIds = append(instanceIds, *instance.InstanceId)
+	}
+	instances, notRunning, err := getRunningInstances(ctx, cfg, asgName, instanceIds)
+	if err != nil {
+		return AutoScalingGroup{}, err
+	}
+
+	tags := map[string]string{}
+	for _, tag := range group.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	launchTemplate := group.LaunchTemplate
+	if launchTemplate == nil && group.MixedInstancesPolicy != nil && group.MixedInstancesPolicy.LaunchTemplate != nil {
+		launchTemplate = group.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	var launchTemplateName, launchTemplateVersion string
+	if launchTemplate != nil {
+		launchTemplateName = aws.ToString(launchTemplate.LaunchTemplateName)
+		launchTemplateVersion = aws.ToString(launchTemplate.Version)
+	}
+
+	return AutoScalingGroup{
+		AsgName:               asgName,
+		Tags:                  tags,
+		Instances:             instances,
+		NotRunningInstances:   notRunning,
+		DesiredInstances:      int(aws.ToInt32(group.DesiredCapacity)),
+		MinInstances:          int(aws.ToInt32(group.MinSize)),
+		MaxInstances:          int(aws.ToInt32(group.MaxSize)),
+		LaunchTemplateName:    launchTemplateName,
+		LaunchTemplateVersion: launchTemplateVersion,
+	}, nil
+}
+
+// SetAutoScalingGroupCapacity sets the min, max and desired sizes of the ASG
+func (a *AutoScalingGroupClient) SetAutoScalingGroupCapacity(ctx context.Context, cfg aws.Config, min, max, desired int) error {
 	autoscalingAwsClient := autoscaling.NewFromConfig(cfg)
 	input := &autoscaling.UpdateAutoScalingGroupInput{
 		AutoScalingGroupName: aws.String(a.Asg.AsgName),
-		MaxSize:              aws.Int32(int32(a.Asg.DesiredInstances)),
+		MinSize:              aws.Int32(int32(min)),
+		MaxSize:              aws.Int32(int32(max)),
+		DesiredCapacity:      aws.Int32(int32(desired)),
 	}
 
-	result, err := autoscalingAwsClient.UpdateAutoScalingGroup(ctx, input)
+	_, err := autoscalingAwsClient.UpdateAutoScalingGroup(ctx, input)
 	if err != nil {
-		log.Println("There was an error updating the autoscaling group's max instance count")
-		return &autoscaling.UpdateAutoScalingGroupOutput{}, err
+		return fmt.Errorf("error updating the capacity of autoscaling group %s: %v", a.Asg.AsgName, err)
 	}
-	return result, nil
+	return nil
+}
+
+// TerminateInstance terminates the instance in the ASG, decrementing the desired capacity so that it is not replaced
+func (a *AutoScalingGroupClient) TerminateInstance(ctx context.Context, cfg aws.Config, instanceId string) error {
+	autoscalingAwsClient := autoscaling.NewFromConfig(cfg)
+	input := &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+		InstanceId:                     aws.String(instanceId),
+		ShouldDecrementDesiredCapacity: aws.Bool(true),
+	}
+
+	_, err := autoscalingAwsClient.TerminateInstanceInAutoScalingGroup(ctx, input)
+	if err != nil {
+		return fmt.Errorf("error terminating instance %s in autoscaling group %s: %v", instanceId, a.Asg.AsgName, err)
+	}
+	return nil
 }
 
 type AutoscalingGroupUpdater struct {
 	UpdateAutoscalingGroupInterface
 }
 
-// Update method updates the current ASG count for max to the current desired count to prevent the ASG being drained and tainted
-// to prevent from getting autoscaled during the upgrade process
-// TODO make this general purpose instead of just updating the Max size to current desired size which it is doing right now
-func (a *AutoscalingGroupUpdater) Update(ctx context.Context, awsConfig aws.Config) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
-	result, err := a.UpdateAutoScalingGroupCount(ctx, awsConfig)
+// Capture returns the current min, max and desired sizes of the ASG, to be restored once the upgrade is done
+func (a *AutoscalingGroupUpdater) Capture(ctx context.Context, awsConfig aws.Config) (AutoScalingGroupCapacity, error) {
+	asg, err := a.DescribeAutoScalingGroup(ctx, awsConfig)
 	if err != nil {
-		return nil, err
+		return AutoScalingGroupCapacity{}, err
 	}
-	return result, nil
+	return asg.Capacity(), nil
+}
+
+// Update applies the new sizes to the ASG, its current ones being captured beforehand with Capture, and returns the
+// sizes applied
+func (a *AutoscalingGroupUpdater) Update(ctx context.Context, awsConfig aws.Config, capacity AutoScalingGroupCapacity) (AutoScalingGroupCapacity, error) {
+	err := a.SetAutoScalingGroupCapacity(ctx, awsConfig, capacity.Min, capacity.Max, capacity.Desired)
+	if err != nil {
+		return AutoScalingGroupCapacity{}, err
+	}
+	return capacity, nil
+}
+
+// Restore sets the sizes of the ASG back to the ones captured before the upgrade
+func (a *AutoscalingGroupUpdater) Restore(ctx context.Context, awsConfig aws.Config, original AutoScalingGroupCapacity) error {
+	return a.SetAutoScalingGroupCapacity(ctx, awsConfig, original.Min, original.Max, original.Desired)
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
@@ -14,41 +13,139 @@ type mockAutoScalingGroupApi struct {
 	mock.Mock
 }
 
-func (m *mockAutoScalingGroupApi) UpdateAutoScalingGroupCount(ctx context.Context, cfg aws.Config) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+func (m *mockAutoScalingGroupApi) DescribeAutoScalingGroup(ctx context.Context, cfg aws.Config) (AutoScalingGroup, error) {
 	args := m.Called(ctx, cfg)
-	return args.Get(0).(*autoscaling.UpdateAutoScalingGroupOutput), args.Error(1)
+	return args.Get(0).(AutoScalingGroup), args.Error(1)
 }
 
-func TestAutoscalingGroup_SetMaxInstanceSizeToCurrentDesiredSize(t *testing.T) {
-	t.Run("when the autoscaling group update call is successful", func(t *testing.T) {
+func (m *mockAutoScalingGroupApi) SetAutoScalingGroupCapacity(ctx context.Context, cfg aws.Config, min, max, desired int) error {
+	args := m.Called(ctx, cfg, min, max, desired)
+	return args.Error(0)
+}
+
+func TestAutoscalingGroupUpdater_Capture(t *testing.T) {
+	t.Run("when the autoscaling group is described, it returns back its current capacity", func(t *testing.T) {
 		m := new(mockAutoScalingGroupApi)
+		m.On("DescribeAutoScalingGroup", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).
+			Return(AutoScalingGroup{AsgName: "asg", MinInstances: 1, MaxInstances: 10, DesiredInstances: 3}, nil).Once()
+
+		s := AutoscalingGroupUpdater{m}
+
+		original, err := s.Capture(context.TODO(), aws.Config{})
+
+		assert.Nil(t, err)
+		assert.Equal(t, AutoScalingGroupCapacity{Min: 1, Max: 10, Desired: 3}, original)
+	})
 
-		m.On("UpdateAutoScalingGroupCount",
-			mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("aws.Config")).
-			Return(&autoscaling.UpdateAutoScalingGroupOutput{}, nil).
-			Once()
+	t.Run("when the autoscaling group can't be described", func(t *testing.T) {
+		m := new(mockAutoScalingGroupApi)
+		m.On("DescribeAutoScalingGroup", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).
+			Return(AutoScalingGroup{}, errors.New("some error")).Once()
 
 		s := AutoscalingGroupUpdater{m}
 
-		result, err := s.Update(context.TODO(), aws.Config{})
+		_, err := s.Capture(context.TODO(), aws.Config{})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestAutoscalingGroupUpdater_Update(t *testing.T) {
+	t.Run("when the autoscaling group update call is successful, it returns back the capacity applied", func(t *testing.T) {
+		m := new(mockAutoScalingGroupApi)
+		m.On("SetAutoScalingGroupCapacity", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), 1, 3, 3).
+			Return(nil).Once()
+
+		s := AutoscalingGroupUpdater{m}
+
+		applied, err := s.Update(context.TODO(), aws.Config{}, AutoScalingGroupCapacity{Min: 1, Max: 3, Desired: 3})
 
 		assert.Nil(t, err)
-		assert.IsType(t, result, &autoscaling.UpdateAutoScalingGroupOutput{})
+		assert.Equal(t, AutoScalingGroupCapacity{Min: 1, Max: 3, Desired: 3}, applied)
+		m.AssertExpectations(t)
+		m.AssertNotCalled(t, "DescribeAutoScalingGroup", mock.Anything, mock.Anything)
 	})
 
 	t.Run("when the autoscaling group update call is not successful", func(t *testing.T) {
 		m := new(mockAutoScalingGroupApi)
+		m.On("SetAutoScalingGroupCapacity", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), 1, 3, 3).
+			Return(errors.New("some error")).Once()
 
-		m.On("UpdateAutoScalingGroupCount",
-			mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("aws.Config")).
-			Return(&autoscaling.UpdateAutoScalingGroupOutput{}, errors.New("some error")).
-			Once()
+		s := AutoscalingGroupUpdater{m}
+
+		_, err := s.Update(context.TODO(), aws.Config{}, AutoScalingGroupCapacity{Min: 1, Max: 3, Desired: 3})
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestAutoscalingGroupUpdater_Restore(t *testing.T) {
+	t.Run("when the autoscaling group restore call is successful", func(t *testing.T) {
+		m := new(mockAutoScalingGroupApi)
+		m.On("SetAutoScalingGroupCapacity", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), 1, 10, 3).
+			Return(nil).Once()
+
+		s := AutoscalingGroupUpdater{m}
+
+		err := s.Restore(context.TODO(), aws.Config{}, AutoScalingGroupCapacity{Min: 1, Max: 10, Desired: 3})
+
+		assert.Nil(t, err)
+		m.AssertExpectations(t)
+	})
+
+	t.Run("when the autoscaling group restore call is not successful", func(t *testing.T) {
+		m := new(mockAutoScalingGroupApi)
+		m.On("SetAutoScalingGroupCapacity", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), 1, 10, 3).
+			Return(errors.New("some error")).Once()
 
 		s := AutoscalingGroupUpdater{m}
 
-		result, err := s.Update(context.TODO(), aws.Config{})
+		err := s.Restore(context.TODO(), aws.Config{}, AutoScalingGroupCapacity{Min: 1, Max: 10, Desired: 3})
 
 		assert.NotNil(t, err)
-		assert.Nil(t, result)
+	})
+}
+
+func TestAutoScalingGroup_VerifyClusterOwnership(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    map[string]string
+		wantErr bool
+	}{
+		{"when the ASG carries the kubernetes.io/cluster tag of the cluster", map[string]string{"kubernetes.io/cluster/cluster1": "owned"}, false},
+		{"when the ASG carries the eks:cluster-name tag of the cluster", map[string]string{"eks:cluster-name": "cluster1"}, false},
+		{"when the ASG carries the kubernetes.io/cluster tag of another cluster", map[string]string{"kubernetes.io/cluster/cluster2": "owned"}, true},
+		{"when the ASG carries the eks:cluster-name tag of another cluster", map[string]string{"eks:cluster-name": "cluster2"}, true},
+		{"when the ASG carries no cluster tag", map[string]string{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asg := AutoScalingGroup{AsgName: "asg1", Tags: tt.tags}
+
+			err := asg.VerifyClusterOwnership("cluster1")
+
+			if tt.wantErr {
+				assert.Equal(t, errors.New("autoscaling group asg1 does not belong to cluster cluster1, it carries neither the "+
+					"kubernetes.io/cluster/cluster1 tag nor the eks:cluster-name=cluster1 tag"), err)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestAutoScalingGroup_VerifyInstancesRunning(t *testing.T) {
+	t.Run("when all the instances are running", func(t *testing.T) {
+		asg := AutoScalingGroup{AsgName: "asg1", Instances: AwsInstances{{InstanceId: "i-1"}}}
+
+		assert.Nil(t, asg.VerifyInstancesRunning())
+	})
+
+	t.Run("when some instances are not running, they are listed", func(t *testing.T) {
+		asg := AutoScalingGroup{AsgName: "asg1", Instances: AwsInstances{{InstanceId: "i-1"}},
+			NotRunningInstances: []string{"i-2 (pending)", "i-3 (shutting-down)"}}
+
+		assert.Equal(t, errors.New("instances i-2 (pending), i-3 (shutting-down) of autoscaling group asg1 are not running, "+
+			"please check the ASG on the console"), asg.VerifyInstancesRunning())
 	})
 }
@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/stretchr/testify/assert"
@@ -10,6 +11,9 @@ import (
 	"testing"
 )
 
+// contextType is the concrete type of context.TODO(), which differs across go versions
+var contextType = fmt.Sprintf("%T", context.TODO())
+
 type mockAwsConfig struct {
 	mock.Mock
 }
@@ -24,7 +28,7 @@ func TestAwsConfig_LoadDefaultConfig(t *testing.T) {
 		m := new(mockAwsConfig)
 
 		m.On("LoadDefaultConfig",
-			mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("func(*config.LoadOptions) error"), mock.AnythingOfType("func(*config.LoadOptions) error")).
+			mock.AnythingOfType(contextType), mock.AnythingOfType("func(*config.LoadOptions) error"), mock.AnythingOfType("func(*config.LoadOptions) error")).
 			Return(aws.Config{Region: "correct-region"}, nil).
 			Once()
 
@@ -40,7 +44,7 @@ func TestAwsConfig_LoadDefaultConfig(t *testing.T) {
 		m := new(mockAwsConfig)
 
 		m.On("LoadDefaultConfig",
-			mock.AnythingOfType("*context.emptyCtx"), mock.AnythingOfType("func(*config.LoadOptions) error"), mock.AnythingOfType("func(*config.LoadOptions) error")).
+			mock.AnythingOfType(contextType), mock.AnythingOfType("func(*config.LoadOptions) error"), mock.AnythingOfType("func(*config.LoadOptions) error")).
 			Return(aws.Config{}, errors.New("some aws config error")).
 			Once()
 
@@ -0,0 +1,99 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"sort"
+	"strings"
+)
+
+type ListAutoscalingGroupsInterface interface {
+	ListAutoScalingGroups(ctx context.Context, cfg aws.Config, clusterName string) ([]AutoScalingGroup, error)
+}
+
+type ClusterAutoScalingGroupsClient struct{}
+
+// ListAutoScalingGroups returns the ASGs carrying the kubernetes.io/cluster/<clusterName> tag or the eks:cluster-name
+// tag with the cluster name as value
+func (c *ClusterAutoScalingGroupsClient) ListAutoScalingGroups(ctx context.Context, cfg aws.Config, clusterName string) ([]AutoScalingGroup, error) {
+	autoscalingAwsClient := autoscaling.NewFromConfig(cfg)
+	filters := [][]types.Filter{
+		{{Name: aws.String("tag-key"), Values: []string{clusterTagPrefix + clusterName}}},
+		{{Name: aws.String("tag:" + eksClusterNameTag), Values: []string{clusterName}}},
+	}
+
+	seen := map[string]bool{}
+	var groups []AutoScalingGroup
+	for _, filter := range filters {
+		paginator := autoscaling.NewDescribeAutoScalingGroupsPaginator(autoscalingAwsClient,
+			&autoscaling.DescribeAutoScalingGroupsInput{Filters: filter})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("error listing the autoscaling groups of cluster %s: %v", clusterName, err)
+			}
+			for _, group := range page.AutoScalingGroups {
+				if seen[aws.ToString(group.AutoScalingGroupName)] {
+					continue
+				}
+				seen[aws.ToString(group.AutoScalingGroupName)] = true
+
+				asg, err := autoScalingGroupFromApi(ctx, cfg, group)
+				if err != nil {
+					return nil, err
+				}
+				groups = append(groups, asg)
+			}
+		}
+	}
+	return groups, nil
+}
+
+// AutoscalingGroupFinder discovers the ASGs backing the node groups of a cluster
+type AutoscalingGroupFinder struct {
+	ListAutoscalingGroupsInterface
+}
+
+// ClusterAutoScalingGroups returns the ASGs of the cluster sorted by name
+func (f *AutoscalingGroupFinder) ClusterAutoScalingGroups(ctx context.Context, cfg aws.Config, clusterName string) ([]AutoScalingGroup, error) {
+	groups, err := f.ListAutoScalingGroups(ctx, cfg, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var owned []AutoScalingGroup
+	for _, group := range groups {
+		if group.VerifyClusterOwnership(clusterName) == nil {
+			owned = append(owned, group)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].AsgName < owned[j].AsgName })
+	return owned, nil
+}
+
+// ResolveAutoScalingGroupName returns the name of the ASG of the cluster passed either by its ASG name or by the name of
+// the EKS managed node group it backs
+func (f *AutoscalingGroupFinder) ResolveAutoScalingGroupName(ctx context.Context, cfg aws.Config, clusterName, name string) (string, error) {
+	groups, err := f.ClusterAutoScalingGroups(ctx, cfg, clusterName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, group := range groups {
+		if group.AsgName == name {
+			return group.AsgName, nil
+		}
+	}
+	var names []string
+	for _, group := range groups {
+		if group.NodeGroupName() == name {
+			return group.AsgName, nil
+		}
+		names = append(names, group.AsgName)
+	}
+	return "", fmt.Errorf("no autoscaling group or managed node group named %s found in cluster %s, the autoscaling groups of the cluster are: %s",
+		name, clusterName, strings.Join(names, ", "))
+}
@@ -0,0 +1,92 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type mockListAutoScalingGroupsApi struct {
+	mock.Mock
+}
+
+func (m *mockListAutoScalingGroupsApi) ListAutoScalingGroups(ctx context.Context, cfg aws.Config, clusterName string) ([]AutoScalingGroup, error) {
+	args := m.Called(ctx, cfg, clusterName)
+	return args.Get(0).([]AutoScalingGroup), args.Error(1)
+}
+
+func testClusterAutoScalingGroups() []AutoScalingGroup {
+	return []AutoScalingGroup{
+		{AsgName: "eks-spot-1a2b3c", Tags: map[string]string{"eks:cluster-name": "cluster1", "eks:nodegroup-name": "spot"}},
+		{AsgName: "cluster1-on-demand", Tags: map[string]string{"kubernetes.io/cluster/cluster1": "owned"}},
+		{AsgName: "cluster2-on-demand", Tags: map[string]string{"kubernetes.io/cluster/cluster2": "owned"}},
+	}
+}
+
+func TestAutoscalingGroupFinder_ClusterAutoScalingGroups(t *testing.T) {
+	t.Run("when the ASGs are listed, the ones of the cluster are returned sorted by name", func(t *testing.T) {
+		m := new(mockListAutoScalingGroupsApi)
+		m.On("ListAutoScalingGroups", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), "cluster1").
+			Return(testClusterAutoScalingGroups(), nil).Once()
+
+		f := AutoscalingGroupFinder{m}
+
+		groups, err := f.ClusterAutoScalingGroups(context.TODO(), aws.Config{}, "cluster1")
+
+		assert.Nil(t, err)
+		assert.Len(t, groups, 2)
+		assert.Equal(t, "cluster1-on-demand", groups[0].AsgName)
+		assert.Equal(t, "eks-spot-1a2b3c", groups[1].AsgName)
+	})
+
+	t.Run("when the ASGs can't be listed, it returns back an error", func(t *testing.T) {
+		m := new(mockListAutoScalingGroupsApi)
+		m.On("ListAutoScalingGroups", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), "cluster1").
+			Return([]AutoScalingGroup{}, errors.New("some error")).Once()
+
+		f := AutoscalingGroupFinder{m}
+
+		_, err := f.ClusterAutoScalingGroups(context.TODO(), aws.Config{}, "cluster1")
+
+		assert.Equal(t, errors.New("some error"), err)
+	})
+}
+
+func TestAutoscalingGroupFinder_ResolveAutoScalingGroupName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{"when the ASG name is passed", "cluster1-on-demand", "cluster1-on-demand", nil},
+		{"when the managed node group name is passed", "spot", "eks-spot-1a2b3c", nil},
+		{"when the name of an ASG of another cluster is passed", "cluster2-on-demand", "",
+			errors.New("no autoscaling group or managed node group named cluster2-on-demand found in cluster cluster1, " +
+				"the autoscaling groups of the cluster are: cluster1-on-demand, eks-spot-1a2b3c")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(mockListAutoScalingGroupsApi)
+			m.On("ListAutoScalingGroups", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), "cluster1").
+				Return(testClusterAutoScalingGroups(), nil).Once()
+
+			f := AutoscalingGroupFinder{m}
+
+			got, err := f.ResolveAutoScalingGroupName(context.TODO(), aws.Config{}, "cluster1", tt.input)
+
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAutoScalingGroup_NodeGroupName(t *testing.T) {
+	groups := testClusterAutoScalingGroups()
+
+	assert.Equal(t, "spot", groups[0].NodeGroupName())
+	assert.Equal(t, "", groups[1].NodeGroupName())
+}
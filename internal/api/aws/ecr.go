@@ -0,0 +1,63 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"regexp"
+	"strings"
+)
+
+// ecrRegistryPattern matches the hosts of ECR registries, <account>.dkr.ecr.<region>.amazonaws.com
+var ecrRegistryPattern = regexp.MustCompile(`^[0-9]{12}\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+type EcrAuthorizationTokenInterface interface {
+	GetAuthorizationToken(ctx context.Context, cfg aws.Config) (string, error)
+}
+
+type EcrClient struct{}
+
+// GetAuthorizationToken returns the base64 encoded user:password token to pull images from the ECR registries of the
+// region of the config
+func (e *EcrClient) GetAuthorizationToken(ctx context.Context, cfg aws.Config) (string, error) {
+	ecrAwsClient := ecr.NewFromConfig(cfg)
+	result, err := ecrAwsClient.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return "", fmt.Errorf("error getting an ECR authorization token in region %s: %v", cfg.Region, err)
+	}
+	if len(result.AuthorizationData) == 0 {
+		return "", fmt.Errorf("no ECR authorization token returned in region %s", cfg.Region)
+	}
+	return aws.ToString(result.AuthorizationData[0].AuthorizationToken), nil
+}
+
+type EcrCredentialsGetter struct {
+	EcrAuthorizationTokenInterface
+}
+
+// RegistryCredentials returns the username and password to pull images from the registry when it is an ECR registry,
+// and empty ones for any other registry
+func (e *EcrCredentialsGetter) RegistryCredentials(ctx context.Context, cfg aws.Config, registry string) (string, string, error) {
+	match := ecrRegistryPattern.FindStringSubmatch(registry)
+	if match == nil {
+		return "", "", nil
+	}
+
+	// the token is requested in the region of the registry, which is not always the one of the cluster
+	cfg.Region = match[1]
+	token, err := e.GetAuthorizationToken(ctx, cfg)
+	if err != nil {
+		return "", "", err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return "", "", fmt.Errorf("error decoding the ECR authorization token: %v", err)
+	}
+	credentials := strings.SplitN(string(decoded), ":", 2)
+	if len(credentials) != 2 {
+		return "", "", fmt.Errorf("invalid ECR authorization token")
+	}
+	return credentials[0], credentials[1], nil
+}
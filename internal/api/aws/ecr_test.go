@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type mockEcrApi struct {
+	mock.Mock
+}
+
+func (m *mockEcrApi) GetAuthorizationToken(ctx context.Context, cfg aws.Config) (string, error) {
+	args := m.Called(ctx, cfg)
+	return args.String(0), args.Error(1)
+}
+
+func TestEcrCredentialsGetter_RegistryCredentials(t *testing.T) {
+	token := base64.StdEncoding.EncodeToString([]byte("AWS:secret"))
+
+	t.Run("when the registry is an ECR registry, a token is requested in its region", func(t *testing.T) {
+		m := new(mockEcrApi)
+		m.On("GetAuthorizationToken", mock.AnythingOfType(contextType), aws.Config{Region: "us-west-2"}).
+			Return(token, nil).Once()
+
+		e := EcrCredentialsGetter{m}
+		username, password, err := e.RegistryCredentials(context.TODO(), aws.Config{Region: "eu-west-1"},
+			"602401143452.dkr.ecr.us-west-2.amazonaws.com")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "AWS", username)
+		assert.Equal(t, "secret", password)
+		m.AssertExpectations(t)
+	})
+
+	t.Run("when the registry is not an ECR registry, no token is requested", func(t *testing.T) {
+		m := new(mockEcrApi)
+
+		e := EcrCredentialsGetter{m}
+		username, password, err := e.RegistryCredentials(context.TODO(), aws.Config{}, "k8s.gcr.io")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "", username)
+		assert.Equal(t, "", password)
+		m.AssertNotCalled(t, "GetAuthorizationToken", mock.Anything, mock.Anything)
+	})
+
+	t.Run("when the token can't be requested", func(t *testing.T) {
+		m := new(mockEcrApi)
+		m.On("GetAuthorizationToken", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).
+			Return("", errors.New("access denied")).Once()
+
+		e := EcrCredentialsGetter{m}
+		_, _, err := e.RegistryCredentials(context.TODO(), aws.Config{}, "123456789012.dkr.ecr.eu-west-1.amazonaws.com")
+
+		assert.Equal(t, errors.New("access denied"), err)
+	})
+
+	t.Run("when the token is invalid", func(t *testing.T) {
+		m := new(mockEcrApi)
+		m.On("GetAuthorizationToken", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).
+			Return(base64.StdEncoding.EncodeToString([]byte("no-password")), nil).Once()
+
+		e := EcrCredentialsGetter{m}
+		_, _, err := e.RegistryCredentials(context.TODO(), aws.Config{}, "123456789012.dkr.ecr.eu-west-1.amazonaws.com")
+
+		assert.Equal(t, errors.New("invalid ECR authorization token"), err)
+	})
+}
@@ -0,0 +1,190 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kubernetesVersionPattern matches the major and minor version of the control plane of an EKS cluster, eg: 1.21
+var kubernetesVersionPattern = regexp.MustCompile(`^v?([0-9]+)\.([0-9]+)$`)
+
+// EksCluster is the control plane of an EKS cluster
+type EksCluster struct {
+	Name string
+	// Version is the major and minor Kubernetes version of the control plane, eg: 1.21
+	Version string
+	// Status is one of CREATING, ACTIVE, DELETING, FAILED, UPDATING or PENDING
+	Status string
+}
+
+// EksUpdate is an update of an EKS cluster
+type EksUpdate struct {
+	Id string
+	// Status is one of InProgress, Failed, Cancelled or Successful
+	Status string
+	Errors []string
+}
+
+type EksClusterInterface interface {
+	DescribeCluster(ctx context.Context, cfg aws.Config, clusterName string) (EksCluster, error)
+	UpdateClusterVersion(ctx context.Context, cfg aws.Config, clusterName, version string) (EksUpdate, error)
+	DescribeUpdate(ctx context.Context, cfg aws.Config, clusterName, updateId string) (EksUpdate, error)
+}
+
+type EksClient struct{}
+
+// DescribeCluster returns the version and status of the control plane of the cluster
+func (e *EksClient) DescribeCluster(ctx context.Context, cfg aws.Config, clusterName string) (EksCluster, error) {
+	eksAwsClient := eks.NewFromConfig(cfg)
+	result, err := eksAwsClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	if err != nil {
+		return EksCluster{}, fmt.Errorf("error describing EKS cluster %s: %v", clusterName, err)
+	}
+	return EksCluster{
+		Name:    aws.ToString(result.Cluster.Name),
+		Version: aws.ToString(result.Cluster.Version),
+		Status:  string(result.Cluster.Status),
+	}, nil
+}
+
+// UpdateClusterVersion starts the update of the control plane of the cluster to the Kubernetes version passed
+func (e *EksClient) UpdateClusterVersion(ctx context.Context, cfg aws.Config, clusterName, version string) (EksUpdate, error) {
+	eksAwsClient := eks.NewFromConfig(cfg)
+	result, err := eksAwsClient.UpdateClusterVersion(ctx, &eks.UpdateClusterVersionInput{
+		Name:    aws.String(clusterName),
+		Version: aws.String(version),
+	})
+	if err != nil {
+		return EksUpdate{}, fmt.Errorf("error updating EKS cluster %s to version %s: %v", clusterName, version, err)
+	}
+	return eksUpdateFromApi(result.Update), nil
+}
+
+// DescribeUpdate returns the status of the update of the cluster
+func (e *EksClient) DescribeUpdate(ctx context.Context, cfg aws.Config, clusterName, updateId string) (EksUpdate, error) {
+	eksAwsClient := eks.NewFromConfig(cfg)
+	result, err := eksAwsClient.DescribeUpdate(ctx, &eks.DescribeUpdateInput{
+		Name:     aws.String(clusterName),
+		UpdateId: aws.String(updateId),
+	})
+	if err != nil {
+		return EksUpdate{}, fmt.Errorf("error describing update %s of EKS cluster %s: %v", updateId, clusterName, err)
+	}
+	return eksUpdateFromApi(result.Update), nil
+}
+
+func eksUpdateFromApi(update *types.Update) EksUpdate {
+	if update == nil {
+		return EksUpdate{}
+	}
+	var errors []string
+	for _, updateError := range update.Errors {
+		errors = append(errors, fmt.Sprintf("%s: %s", updateError.ErrorCode, aws.ToString(updateError.ErrorMessage)))
+	}
+	return EksUpdate{Id: aws.ToString(update.Id), Status: string(update.Status), Errors: errors}
+}
+
+// ControlPlaneUpgrader upgrades the control plane of an EKS cluster to the next Kubernetes minor version
+type ControlPlaneUpgrader struct {
+	EksClusterInterface
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// Upgrade updates the control plane of the cluster to the target version, which has to be exactly one minor version
+// ahead of the current one as EKS doesn't skip minor versions, and polls the update until it completes. It returns the
+// version the control plane runs, nothing being updated when the control plane already runs the target version
+func (u *ControlPlaneUpgrader) Upgrade(ctx context.Context, cfg aws.Config, clusterName, targetVersion string) (string, error) {
+	cluster, err := u.DescribeCluster(ctx, cfg, clusterName)
+	if err != nil {
+		return "", err
+	}
+	if cluster.Version == strings.TrimPrefix(targetVersion, "v") {
+		log.Printf("The control plane of cluster %s already runs Kubernetes %s\n", clusterName, cluster.Version)
+		return cluster.Version, nil
+	}
+	if err := ValidateControlPlaneUpgrade(cluster.Version, targetVersion); err != nil {
+		return "", err
+	}
+	if cluster.Status != string(types.ClusterStatusActive) {
+		return "", fmt.Errorf("cluster %s is %s, its control plane can only be upgraded when it is %s",
+			clusterName, cluster.Status, types.ClusterStatusActive)
+	}
+
+	targetVersion = strings.TrimPrefix(targetVersion, "v")
+	update, err := u.UpdateClusterVersion(ctx, cfg, clusterName, targetVersion)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Started update %s of the control plane of cluster %s from Kubernetes %s to %s\n",
+		update.Id, clusterName, cluster.Version, targetVersion)
+	if err := u.waitForUpdate(ctx, cfg, clusterName, update); err != nil {
+		return "", err
+	}
+	log.Printf("The control plane of cluster %s runs Kubernetes %s\n", clusterName, targetVersion)
+	return targetVersion, nil
+}
+
+// waitForUpdate polls the update until it is successful, returning an error when it fails, is cancelled or the timeout
+// is hit
+func (u *ControlPlaneUpgrader) waitForUpdate(ctx context.Context, cfg aws.Config, clusterName string, update EksUpdate) error {
+	deadline := time.Now().Add(u.Timeout)
+	for {
+		switch update.Status {
+		case string(types.UpdateStatusSuccessful):
+			return nil
+		case string(types.UpdateStatusFailed), string(types.UpdateStatusCancelled):
+			return fmt.Errorf("update %s of the control plane of cluster %s is %s: %s",
+				update.Id, clusterName, update.Status, strings.Join(update.Errors, ", "))
+		}
+
+		log.Printf("Waiting for update %s of the control plane of cluster %s: %s\n", update.Id, clusterName, update.Status)
+		if time.Now().Add(u.PollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for update %s of the control plane of cluster %s, it is still %s",
+				u.Timeout, update.Id, clusterName, update.Status)
+		}
+		time.Sleep(u.PollInterval)
+
+		var err error
+		update, err = u.DescribeUpdate(ctx, cfg, clusterName, update.Id)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ValidateControlPlaneUpgrade returns an error unless the target version is exactly one minor version ahead of the
+// current version of the control plane
+func ValidateControlPlaneUpgrade(currentVersion, targetVersion string) error {
+	currentMajor, currentMinor, err := parseKubernetesVersion(currentVersion)
+	if err != nil {
+		return err
+	}
+	targetMajor, targetMinor, err := parseKubernetesVersion(targetVersion)
+	if err != nil {
+		return err
+	}
+	if targetMajor != currentMajor || targetMinor != currentMinor+1 {
+		return fmt.Errorf("the control plane can only be upgraded one minor version at a time, from %s to %d.%d, not to %s",
+			currentVersion, currentMajor, currentMinor+1, targetVersion)
+	}
+	return nil
+}
+
+func parseKubernetesVersion(version string) (int, int, error) {
+	match := kubernetesVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid Kubernetes version %s, expected a major and minor version, eg: 1.22", version)
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	return major, minor, nil
+}
@@ -0,0 +1,153 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+type mockEksApi struct {
+	mock.Mock
+}
+
+func (m *mockEksApi) DescribeCluster(ctx context.Context, cfg aws.Config, clusterName string) (EksCluster, error) {
+	args := m.Called(ctx, cfg, clusterName)
+	return args.Get(0).(EksCluster), args.Error(1)
+}
+
+func (m *mockEksApi) UpdateClusterVersion(ctx context.Context, cfg aws.Config, clusterName, version string) (EksUpdate, error) {
+	args := m.Called(ctx, cfg, clusterName, version)
+	return args.Get(0).(EksUpdate), args.Error(1)
+}
+
+func (m *mockEksApi) DescribeUpdate(ctx context.Context, cfg aws.Config, clusterName, updateId string) (EksUpdate, error) {
+	args := m.Called(ctx, cfg, clusterName, updateId)
+	return args.Get(0).(EksUpdate), args.Error(1)
+}
+
+func TestControlPlaneUpgrader_Upgrade(t *testing.T) {
+	active := EksCluster{Name: "cluster1", Version: "1.21", Status: "ACTIVE"}
+
+	t.Run("when the target is the next minor version, the update is polled until it is successful", func(t *testing.T) {
+		m := new(mockEksApi)
+		m.On("DescribeCluster", mock.AnythingOfType(contextType), aws.Config{}, "cluster1").Return(active, nil).Once()
+		m.On("UpdateClusterVersion", mock.AnythingOfType(contextType), aws.Config{}, "cluster1", "1.22").
+			Return(EksUpdate{Id: "update-1", Status: "InProgress"}, nil).Once()
+		m.On("DescribeUpdate", mock.AnythingOfType(contextType), aws.Config{}, "cluster1", "update-1").
+			Return(EksUpdate{Id: "update-1", Status: "InProgress"}, nil).Once()
+		m.On("DescribeUpdate", mock.AnythingOfType(contextType), aws.Config{}, "cluster1", "update-1").
+			Return(EksUpdate{Id: "update-1", Status: "Successful"}, nil).Once()
+
+		u := ControlPlaneUpgrader{m, time.Second, time.Millisecond}
+		version, err := u.Upgrade(context.TODO(), aws.Config{}, "cluster1", "v1.22")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "1.22", version)
+		m.AssertExpectations(t)
+	})
+
+	t.Run("when the control plane already runs the target version, nothing is updated", func(t *testing.T) {
+		m := new(mockEksApi)
+		m.On("DescribeCluster", mock.AnythingOfType(contextType), aws.Config{}, "cluster1").
+			Return(EksCluster{Name: "cluster1", Version: "1.22", Status: "ACTIVE"}, nil).Once()
+
+		u := ControlPlaneUpgrader{m, time.Second, time.Millisecond}
+		version, err := u.Upgrade(context.TODO(), aws.Config{}, "cluster1", "1.22")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "1.22", version)
+		m.AssertNotCalled(t, "UpdateClusterVersion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when the target skips a minor version, nothing is updated", func(t *testing.T) {
+		m := new(mockEksApi)
+		m.On("DescribeCluster", mock.AnythingOfType(contextType), aws.Config{}, "cluster1").Return(active, nil).Once()
+
+		u := ControlPlaneUpgrader{m, time.Second, time.Millisecond}
+		_, err := u.Upgrade(context.TODO(), aws.Config{}, "cluster1", "1.23")
+
+		assert.EqualError(t, err, "the control plane can only be upgraded one minor version at a time, from 1.21 to 1.22, not to 1.23")
+		m.AssertNotCalled(t, "UpdateClusterVersion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when the cluster is not active, nothing is updated", func(t *testing.T) {
+		m := new(mockEksApi)
+		m.On("DescribeCluster", mock.AnythingOfType(contextType), aws.Config{}, "cluster1").
+			Return(EksCluster{Name: "cluster1", Version: "1.21", Status: "UPDATING"}, nil).Once()
+
+		u := ControlPlaneUpgrader{m, time.Second, time.Millisecond}
+		_, err := u.Upgrade(context.TODO(), aws.Config{}, "cluster1", "1.22")
+
+		assert.EqualError(t, err, "cluster cluster1 is UPDATING, its control plane can only be upgraded when it is ACTIVE")
+		m.AssertNotCalled(t, "UpdateClusterVersion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when the update fails, its errors are returned", func(t *testing.T) {
+		m := new(mockEksApi)
+		m.On("DescribeCluster", mock.AnythingOfType(contextType), aws.Config{}, "cluster1").Return(active, nil).Once()
+		m.On("UpdateClusterVersion", mock.AnythingOfType(contextType), aws.Config{}, "cluster1", "1.22").
+			Return(EksUpdate{Id: "update-1", Status: "InProgress"}, nil).Once()
+		m.On("DescribeUpdate", mock.AnythingOfType(contextType), aws.Config{}, "cluster1", "update-1").
+			Return(EksUpdate{Id: "update-1", Status: "Failed", Errors: []string{"SubnetNotFound: subnet-1 not found"}}, nil).Once()
+
+		u := ControlPlaneUpgrader{m, time.Second, time.Millisecond}
+		_, err := u.Upgrade(context.TODO(), aws.Config{}, "cluster1", "1.22")
+
+		assert.EqualError(t, err, "update update-1 of the control plane of cluster cluster1 is Failed: SubnetNotFound: subnet-1 not found")
+		m.AssertExpectations(t)
+	})
+
+	t.Run("when the update doesn't complete in time", func(t *testing.T) {
+		m := new(mockEksApi)
+		m.On("DescribeCluster", mock.AnythingOfType(contextType), aws.Config{}, "cluster1").Return(active, nil).Once()
+		m.On("UpdateClusterVersion", mock.AnythingOfType(contextType), aws.Config{}, "cluster1", "1.22").
+			Return(EksUpdate{Id: "update-1", Status: "InProgress"}, nil).Once()
+		m.On("DescribeUpdate", mock.AnythingOfType(contextType), aws.Config{}, "cluster1", "update-1").
+			Return(EksUpdate{Id: "update-1", Status: "InProgress"}, nil)
+
+		u := ControlPlaneUpgrader{m, 10 * time.Millisecond, 2 * time.Millisecond}
+		_, err := u.Upgrade(context.TODO(), aws.Config{}, "cluster1", "1.22")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "timed out after 10ms waiting for update update-1 of the control plane of cluster cluster1")
+	})
+
+	t.Run("when the update can't be started", func(t *testing.T) {
+		m := new(mockEksApi)
+		m.On("DescribeCluster", mock.AnythingOfType(contextType), aws.Config{}, "cluster1").Return(active, nil).Once()
+		m.On("UpdateClusterVersion", mock.AnythingOfType(contextType), aws.Config{}, "cluster1", "1.22").
+			Return(EksUpdate{}, errors.New("access denied")).Once()
+
+		u := ControlPlaneUpgrader{m, time.Second, time.Millisecond}
+		_, err := u.Upgrade(context.TODO(), aws.Config{}, "cluster1", "1.22")
+
+		assert.Equal(t, errors.New("access denied"), err)
+	})
+}
+
+func TestValidateControlPlaneUpgrade(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		target  string
+		err     error
+	}{
+		{"when the target is the next minor version", "1.21", "1.22", nil},
+		{"when the target has a v prefix", "1.21", "v1.22", nil},
+		{"when the target is the current version", "1.21", "1.21",
+			errors.New("the control plane can only be upgraded one minor version at a time, from 1.21 to 1.22, not to 1.21")},
+		{"when the target is a downgrade", "1.21", "1.20",
+			errors.New("the control plane can only be upgraded one minor version at a time, from 1.21 to 1.22, not to 1.20")},
+		{"when the target is a patch version", "1.21", "1.22.1",
+			errors.New("invalid Kubernetes version 1.22.1, expected a major and minor version, eg: 1.22")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.err, ValidateControlPlaneUpgrade(tt.current, tt.target))
+		})
+	}
+}
@@ -3,14 +3,16 @@ package aws
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
 	"log"
-	"os/exec"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/aws/aws-sdk-go/aws/awserr"
 
 	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
 )
@@ -35,6 +37,53 @@ func (a AwsInstances) Count() int {
 	return len(a)
 }
 
+// FindByInstanceId returns the instance with the id passed
+func (a AwsInstances) FindByInstanceId(instanceId string) (AwsInstance, bool) {
+	for _, instance := range a {
+		if instance.InstanceId == instanceId {
+			return instance, true
+		}
+	}
+	return AwsInstance{}, false
+}
+
+// InstanceIdFromProviderID returns the id of the EC2 instance a node runs on from the provider id of the node, eg:
+// i-0a1b2c3d4e5f67890 for aws:///eu-west-1a/i-0a1b2c3d4e5f67890
+func InstanceIdFromProviderID(providerID string) (string, error) {
+	if !strings.HasPrefix(providerID, "aws://") {
+		return "", fmt.Errorf("provider id %s is not the one of an AWS instance", providerID)
+	}
+	instanceId := providerID[strings.LastIndex(providerID, "/")+1:]
+	if !strings.HasPrefix(instanceId, "i-") {
+		return "", fmt.Errorf("provider id %s doesn't end with an EC2 instance id", providerID)
+	}
+	return instanceId, nil
+}
+
+// ids returns the ids of the instances
+func (a AwsInstances) ids() []string {
+	ids := make([]string, 0, a.Count())
+	for _, instance := range a {
+		ids = append(ids, instance.InstanceId)
+	}
+	return ids
+}
+
+// withIds returns the instances whose id is one of the ids passed
+func (a AwsInstances) withIds(ids []string) AwsInstances {
+	wanted := map[string]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	instances := AwsInstances{}
+	for _, instance := range a {
+		if wanted[instance.InstanceId] {
+			instances.AppendInstance(instance)
+		}
+	}
+	return instances
+}
+
 // TODO Add a spec for this
 func (a AwsInstances) PrettyPrint() {
 	for _, instance := range a {
@@ -47,96 +96,203 @@ func (a AwsInstances) PrettyPrint() {
 	}
 }
 
-// GetInstancesForASG is a helper function, which interacts with the AWS SDK taking the input of the asgname, awsregion
-// and awsprofile and then calls the DescribeAutoScalingGroups API to get the instances of the ASG and then calling the
-// DescribeInstances API to map the private DNS of the instances and then store it, which will then be used to feed
-// kubectl drain command
-// TODO have this method use the generic config getter helper created in internal/api/aws package to reduce duplication
-// TODO break this method into two helper methods
-// TODO add specs for this
-func (a *AwsInstances) GetInstancesForASG(cfg aws.Config, asgName string, awsRegion string, awsProfile string) {
-	autoscalingAwsClient := autoscaling.NewFromConfig(cfg)
-	input := &autoscaling.DescribeAutoScalingGroupsInput{
-		AutoScalingGroupNames: []string{
-			asgName,
-		},
-	}
-
-	// TODO Add non happy path to give a clear error message if the ASG passed is not present in the AWS PROFILE passed
-	// TODO Describe the tags of the autoscaling group to check the cluster tag and match it and exit out if they don't match
-	describeAutoScalingGroupsResult, err := autoscalingAwsClient.DescribeAutoScalingGroups(context.TODO(), input)
-	if err != nil {
-		log.Println(err.Error())
-		return
-	}
-
-	var awsInstanceIds []string
-	for _, instance := range describeAutoScalingGroupsResult.AutoScalingGroups[0].Instances {
-		awsInstanceIds = append(awsInstanceIds, *instance.InstanceId)
+// getRunningInstances maps the instance ids to their private DNS names, the instances which are not running yet, or
+// anymore, are returned apart along with their state, eg: i-0a1b2c3d4e5f67890 (pending)
+func getRunningInstances(ctx context.Context, cfg aws.Config, asgName string, instanceIds []string) (AwsInstances, []string, error) {
+	instances := AwsInstances{}
+	if len(instanceIds) == 0 {
+		return instances, nil, nil
 	}
 
 	ec2AwsClient := ec2.NewFromConfig(cfg)
-	ec2Input := &ec2.DescribeInstancesInput{
-		InstanceIds: awsInstanceIds,
+	result, err := ec2AwsClient.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: instanceIds})
+	if err != nil {
+		return instances, nil, fmt.Errorf("error describing the instances of autoscaling group %s: %v", asgName, err)
 	}
 
-	ec2result, ec2err := ec2AwsClient.DescribeInstances(context.TODO(), ec2Input)
-	if ec2err != nil {
-		if ec2err, ok := err.(awserr.Error); ok {
-			switch ec2err.Code() {
-			default:
-				log.Println(ec2err.Error())
+	var notRunning []string
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			if instance.State == nil || instance.State.Name != "running" {
+				state := "unknown"
+				if instance.State != nil {
+					state = string(instance.State.Name)
+				}
+				notRunning = append(notRunning, fmt.Sprintf("%s (%s)", aws.ToString(instance.InstanceId), state))
+				continue
 			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			log.Println(ec2err.Error())
+			instances.AppendInstance(AwsInstance{
+				InstanceId: aws.ToString(instance.InstanceId),
+				PrivateDNS: aws.ToString(instance.PrivateDnsName),
+				AsgName:    asgName,
+			})
 		}
-		return
 	}
-	for _, reservations := range ec2result.Reservations {
-		if reservations.Instances[0].State.Name == "running" {
-			awsInstance := AwsInstance{
-				InstanceId: *reservations.Instances[0].InstanceId,
-				PrivateDNS: *reservations.Instances[0].PrivateDnsName,
-				AsgName:    asgName,
-			}
-			a.AppendInstance(awsInstance)
-		} else {
-			log.Fatal("One or many of the instances are not in the running state, please check the ASG on console")
+	return instances, notRunning, nil
+}
+
+// VerifyNodesInCluster returns an error unless the private DNS of every instance is the name of a node of the cluster
+// the client points to, to avoid tainting and draining nodes through the kubeconfig context of another cluster
+func (a AwsInstances) VerifyNodesInCluster(k8sClient kubernetes.Interface, clusterName string) error {
+	nodeNames, err := k8s.GetNodeNames(k8sClient)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, instance := range a {
+		if !nodeNames[instance.PrivateDNS] {
+			missing = append(missing, fmt.Sprintf("%s (%s)", instance.InstanceId, instance.PrivateDNS))
 		}
 	}
+	if len(missing) > 0 {
+		return fmt.Errorf("instances %s don't match any node of cluster %s", strings.Join(missing, ", "), clusterName)
+	}
+	return nil
 }
 
-// TODO Add a spec for this
-func (a AwsInstances) TaintNodes() error {
+// TaintNodes adds the NoSchedule taint to all the nodes, done before draining any of them so that the evicted pods
+// don't get scheduled on the nodes which are drained next. Nodes recorded as tainted in the journal are skipped
+func (a AwsInstances) TaintNodes(k8sClient kubernetes.Interface, journal Journal) error {
+	journal = journalOrNoop(journal)
 	for _, instance := range a {
+		if journal.IsCompleted(nodeTaintedStep(instance.PrivateDNS)) {
+			log.Printf("node/%s already tainted, skipping\n", instance.PrivateDNS)
+			continue
+		}
 		log.Printf("Tainting node: %s\n", instance.PrivateDNS)
-		args := strings.Fields(k8s.KubectlTaintNodeCommand(instance.PrivateDNS))
-
-		output, err := exec.Command(args[0], args[1:]...).Output()
+		err := k8s.TaintNode(k8sClient, instance.PrivateDNS)
+		if err != nil {
+			return err
+		}
+		log.Printf("node/%s tainted\n", instance.PrivateDNS)
+		err = journal.Record(nodeTaintedStep(instance.PrivateDNS), instance.InstanceId)
 		if err != nil {
-			log.Fatal("There was an error while tainting the node: ", err)
 			return err
 		}
-		log.Printf("taint output: \n %s", output)
 	}
 	return nil
 }
 
-// TODO Add a spec for this
-func (a AwsInstances) DrainNodes() error {
+// DrainBatchOptions controls how many nodes are drained at the same time
+type DrainBatchOptions struct {
+	// MaxUnavailable is the number or the percentage of the nodes drained concurrently in a batch, at least one node
+	// is drained per batch
+	MaxUnavailable intstr.IntOrString
+	// PauseBetweenBatches is waited for after a batch is drained and its evicted workloads are ready again
+	PauseBetweenBatches time.Duration
+	// WorkloadReadyTimeout bounds the wait for the workloads evicted in a batch to be ready again before draining the
+	// next one, zero skips the wait
+	WorkloadReadyTimeout time.Duration
+	PollInterval         time.Duration
+}
+
+// Batches splits the instances into batches of at most maxUnavailable instances
+func (a AwsInstances) Batches(maxUnavailable intstr.IntOrString) ([]AwsInstances, error) {
+	size, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, a.Count(), false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid max unavailable value %s: %v", maxUnavailable.String(), err)
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	var batches []AwsInstances
+	for start := 0; start < a.Count(); start += size {
+		end := start + size
+		if end > a.Count() {
+			end = a.Count()
+		}
+		batches = append(batches, a[start:end])
+	}
+	return batches, nil
+}
+
+// DrainNodes drains the nodes in batches, the nodes of a batch being drained concurrently, and returns the per pod
+// outcome for each of them. Before moving on to the next batch, it waits for the workloads evicted in the batch to be
+// ready again. Nodes with pods which couldn't be evicted don't stop the run, it only stops early if a node couldn't be
+// prepared for draining, if it timed out with the abort timeout policy or if the evicted workloads didn't get ready.
+//
+// Nodes recorded as drained in the journal are skipped, the fully drained nodes are recorded as soon as they are
+func (a AwsInstances) DrainNodes(k8sClient kubernetes.Interface, opts k8s.DrainOptions, batchOptions DrainBatchOptions, journal Journal) ([]k8s.NodeDrainResult, error) {
+	journal = journalOrNoop(journal)
+	pending := AwsInstances{}
 	for _, instance := range a {
-		log.Printf("Draining node: %s\n", instance.PrivateDNS)
-		args := strings.Fields(k8s.KubectlDrainNodeCommand(instance.PrivateDNS))
+		if journal.IsCompleted(nodeDrainedStep(instance.PrivateDNS)) {
+			log.Printf("node/%s already drained, skipping\n", instance.PrivateDNS)
+			continue
+		}
+		pending.AppendInstance(instance)
+	}
 
-		output, err := exec.Command(args[0], args[1:]...).Output()
-		if err != nil {
-			log.Fatal("There was an error while draining the node: ", err)
-			return err
+	batches, err := pending.Batches(batchOptions.MaxUnavailable)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []k8s.NodeDrainResult
+	for batchNumber, batch := range batches {
+		log.Printf("Draining batch %d of %d with %d node(s)\n", batchNumber+1, len(batches), batch.Count())
+
+		batchResults := make([]k8s.NodeDrainResult, batch.Count())
+		batchErrors := make([]error, batch.Count())
+		var wg sync.WaitGroup
+		for i, instance := range batch {
+			wg.Add(1)
+			go func(i int, instance AwsInstance) {
+				defer wg.Done()
+				batchResults[i], batchErrors[i] = drainNode(k8sClient, instance, opts)
+				if batchErrors[i] == nil && batchResults[i].Drained() {
+					batchErrors[i] = journal.Record(nodeDrainedStep(instance.PrivateDNS), instance.InstanceId)
+				}
+			}(i, instance)
+		}
+		wg.Wait()
+
+		results = append(results, batchResults...)
+		for _, err := range batchErrors {
+			if err != nil {
+				return results, err
+			}
+		}
+
+		if batchOptions.WorkloadReadyTimeout > 0 {
+			err = k8s.WaitForWorkloadsReady(k8sClient, k8s.EvictedWorkloads(batchResults),
+				batchOptions.WorkloadReadyTimeout, batchOptions.PollInterval)
+			if err != nil {
+				return results, err
+			}
 		}
-		log.Printf("drain output: \n %s", output)
+		if batchOptions.PauseBetweenBatches > 0 && batchNumber < len(batches)-1 {
+			log.Printf("Pausing for %s before draining the next batch\n", batchOptions.PauseBetweenBatches)
+			time.Sleep(batchOptions.PauseBetweenBatches)
+		}
+	}
+	return results, nil
+}
 
+func drainNode(k8sClient kubernetes.Interface, instance AwsInstance, opts k8s.DrainOptions) (k8s.NodeDrainResult, error) {
+	log.Printf("Draining node: %s\n", instance.PrivateDNS)
+	result, err := k8s.DrainNode(k8sClient, instance.PrivateDNS, opts)
+	for _, pod := range result.Pods {
+		switch {
+		case pod.Error != nil:
+			log.Printf("pod/%s/%s %s: %v\n", pod.Namespace, pod.Name, pod.Status, pod.Error)
+		case pod.BlockingPDB != "":
+			log.Printf("pod/%s/%s %s by pdb %s\n", pod.Namespace, pod.Name, pod.Status, pod.BlockingPDB)
+		default:
+			log.Printf("pod/%s/%s %s\n", pod.Namespace, pod.Name, pod.Status)
+		}
 	}
-	return nil
+	if err != nil {
+		return result, err
+	}
+
+	if result.Drained() {
+		log.Printf("node/%s drained\n", instance.PrivateDNS)
+	} else {
+		log.Printf("node/%s not fully drained, %d pod(s) failed, %d pod(s) blocked and %d pod(s) still terminating\n",
+			instance.PrivateDNS, len(result.Failed()), len(result.Blocked()), len(result.Terminating()))
+	}
+	return result, nil
 }
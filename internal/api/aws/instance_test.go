@@ -1,8 +1,20 @@
 package aws
 
 import (
+	"context"
+	"errors"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 	"testing"
+	"time"
 )
 
 func TestAwsInstances_Count(t *testing.T) {
@@ -30,3 +42,198 @@ func TestAwsInstances_Count(t *testing.T) {
 		})
 	}
 }
+
+func TestAwsInstances_FindByInstanceId(t *testing.T) {
+	instances := AwsInstances{{"i-1", "node-1", "asg-1"}, {"i-2", "node-2", "asg-2"}}
+
+	instance, ok := instances.FindByInstanceId("i-2")
+	assert.True(t, ok)
+	assert.Equal(t, AwsInstance{"i-2", "node-2", "asg-2"}, instance)
+
+	_, ok = instances.FindByInstanceId("i-3")
+	assert.False(t, ok)
+}
+
+func TestInstanceIdFromProviderID(t *testing.T) {
+	tests := []struct {
+		name       string
+		providerID string
+		want       string
+		err        error
+	}{
+		{"when the provider id is the one of an EC2 instance", "aws:///eu-west-1a/i-0a1b2c3d4e5f67890", "i-0a1b2c3d4e5f67890", nil},
+		{"when the provider id is the one of a Fargate pod", "aws:///eu-west-1a/2f3d7b1a0e-8c2d4f6e0a1b4c3d9e7f5a6b/fargate-ip-10-0-1-2",
+			"", errors.New("provider id aws:///eu-west-1a/2f3d7b1a0e-8c2d4f6e0a1b4c3d9e7f5a6b/fargate-ip-10-0-1-2 doesn't end with an EC2 instance id")},
+		{"when the node has no provider id", "", "", errors.New("provider id  is not the one of an AWS instance")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InstanceIdFromProviderID(tt.providerID)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestAwsInstances_Batches(t *testing.T) {
+	instances := AwsInstances{
+		{"instanceID1", "privdns.1", "asgname1"},
+		{"instanceID2", "privdns.2", "asgname1"},
+		{"instanceID3", "privdns.3", "asgname1"},
+		{"instanceID4", "privdns.4", "asgname1"},
+		{"instanceID5", "privdns.5", "asgname1"},
+	}
+
+	tests := []struct {
+		name           string
+		maxUnavailable intstr.IntOrString
+		want           []int
+		err            bool
+	}{
+		{"when max unavailable is a count, the batches are of that size", intstr.FromInt(2), []int{2, 2, 1}, false},
+		{"when max unavailable is a percentage, it is rounded down", intstr.FromString("50%"), []int{2, 2, 1}, false},
+		{"when max unavailable rounds down to zero, one node is drained per batch", intstr.FromString("10%"), []int{1, 1, 1, 1, 1}, false},
+		{"when max unavailable is larger than the instances, there is a single batch", intstr.FromInt(10), []int{5}, false},
+		{"when max unavailable is not a valid percentage, it returns back an error", intstr.FromString("foo"), nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, err := instances.Batches(tt.maxUnavailable)
+
+			assert.Equal(t, tt.err, err != nil)
+			var sizes []int
+			for _, batch := range batches {
+				sizes = append(sizes, batch.Count())
+			}
+			assert.Equal(t, tt.want, sizes)
+		})
+	}
+}
+
+// fakeClientWithEvictions returns a fake clientset which deletes the pods on eviction, like the api server does
+func fakeClientWithEvictions(objects ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	})
+	return client
+}
+
+func TestAwsInstances_VerifyNodesInCluster(t *testing.T) {
+	instances := AwsInstances{
+		{"instanceID1", "node-1", "asgname1"},
+		{"instanceID2", "node-2", "asgname1"},
+	}
+
+	t.Run("when all the instances are nodes of the cluster", func(t *testing.T) {
+		client := fake.NewSimpleClientset(readyNode("node-1"), readyNode("node-2"), readyNode("node-3"))
+
+		assert.Nil(t, instances.VerifyNodesInCluster(client, "cluster1"))
+	})
+
+	t.Run("when some of the instances are not nodes of the cluster", func(t *testing.T) {
+		client := fake.NewSimpleClientset(readyNode("node-1"))
+
+		err := instances.VerifyNodesInCluster(client, "cluster1")
+
+		assert.Equal(t, errors.New("instances instanceID2 (node-2) don't match any node of cluster cluster1"), err)
+	})
+}
+
+func TestAwsInstances_TaintNodes(t *testing.T) {
+	instances := AwsInstances{
+		{"instanceID1", "node-1", "asgname1"},
+		{"instanceID2", "node-2", "asgname1"},
+	}
+	client := fake.NewSimpleClientset(readyNode("node-1"), readyNode("node-2"))
+	journal := newFakeJournal()
+	_ = journal.Record(nodeTaintedStep("node-1"), "instanceID1")
+
+	err := instances.TaintNodes(client, journal)
+
+	assert.Nil(t, err)
+	node1, _ := client.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+	assert.Empty(t, node1.Spec.Taints)
+	node2, _ := client.CoreV1().Nodes().Get(context.TODO(), "node-2", metav1.GetOptions{})
+	assert.Len(t, node2.Spec.Taints, 1)
+	assert.True(t, journal.IsCompleted(nodeTaintedStep("node-2")))
+}
+
+func TestAwsInstances_DrainNodes(t *testing.T) {
+	instances := AwsInstances{
+		{"instanceID1", "node-1", "asgname1"},
+		{"instanceID2", "node-2", "asgname1"},
+		{"instanceID3", "node-3", "asgname1"},
+	}
+	drainOptions := k8s.NewDrainOptions(time.Millisecond, time.Millisecond, time.Second, k8s.DrainTimeoutAbort)
+
+	replicas := int32(1)
+	deploymentPod := func(name, node string) *corev1.Pod {
+		isController := true
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default",
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "app", Controller: &isController}}},
+			Spec: corev1.PodSpec{NodeName: node},
+		}
+	}
+	deployment := func(readyReplicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: readyReplicas},
+		}
+	}
+
+	t.Run("when the evicted workloads are ready again, all the batches are drained", func(t *testing.T) {
+		client := fakeClientWithEvictions(readyNode("node-1"), readyNode("node-2"), readyNode("node-3"),
+			deploymentPod("app-1", "node-1"), deployment(1))
+
+		results, err := instances.DrainNodes(client, drainOptions, DrainBatchOptions{
+			MaxUnavailable:       intstr.FromInt(2),
+			WorkloadReadyTimeout: time.Second,
+			PollInterval:         time.Millisecond,
+		}, nil)
+
+		assert.Nil(t, err)
+		assert.Len(t, results, 3)
+		for i, result := range results {
+			assert.Equal(t, instances[i].PrivateDNS, result.NodeName)
+			assert.True(t, result.Drained())
+		}
+	})
+
+	t.Run("when the evicted workloads don't get ready again, the next batch is not drained", func(t *testing.T) {
+		client := fakeClientWithEvictions(readyNode("node-1"), readyNode("node-2"), readyNode("node-3"),
+			deploymentPod("app-1", "node-1"), deployment(0))
+
+		results, err := instances.DrainNodes(client, drainOptions, DrainBatchOptions{
+			MaxUnavailable:       intstr.FromInt(2),
+			WorkloadReadyTimeout: 10 * time.Millisecond,
+			PollInterval:         time.Millisecond,
+		}, nil)
+
+		assert.NotNil(t, err)
+		assert.Len(t, results, 2)
+		node, _ := client.CoreV1().Nodes().Get(context.TODO(), "node-3", metav1.GetOptions{})
+		assert.False(t, node.Spec.Unschedulable)
+	})
+	t.Run("when nodes are recorded as drained in the journal, they are skipped and the others are recorded", func(t *testing.T) {
+		client := fakeClientWithEvictions(readyNode("node-1"), readyNode("node-2"), readyNode("node-3"))
+		journal := newFakeJournal()
+		_ = journal.Record(nodeDrainedStep("node-1"), "instanceID1")
+
+		results, err := instances.DrainNodes(client, drainOptions, DrainBatchOptions{MaxUnavailable: intstr.FromInt(1)}, journal)
+
+		assert.Nil(t, err)
+		assert.Len(t, results, 2)
+		node, _ := client.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+		assert.False(t, node.Spec.Unschedulable)
+		assert.True(t, journal.IsCompleted(nodeDrainedStep("node-2")))
+		assert.True(t, journal.IsCompleted(nodeDrainedStep("node-3")))
+	})
+}
@@ -0,0 +1,43 @@
+package aws
+
+// Steps recorded in the journal of the operations run against an ASG
+const (
+	StepAsgCapacityUpdated  = "asg-capacity-updated"
+	StepAsgScaledOut        = "asg-scaled-out"
+	StepReplacementsReady   = "replacement-nodes-ready"
+	StepAsgCapacityRestored = "asg-capacity-restored"
+)
+
+// Journal records the steps of an operation as they complete, so that an interrupted operation can be resumed from
+// its last completed step instead of starting over
+type Journal interface {
+	IsCompleted(step string) bool
+	Values(step string) []string
+	Record(step string, values ...string) error
+}
+
+// noopJournal is used when no journal is passed, nothing is recorded and no step is ever completed
+type noopJournal struct{}
+
+func (noopJournal) IsCompleted(string) bool        { return false }
+func (noopJournal) Values(string) []string         { return nil }
+func (noopJournal) Record(string, ...string) error { return nil }
+
+func journalOrNoop(journal Journal) Journal {
+	if journal == nil {
+		return noopJournal{}
+	}
+	return journal
+}
+
+func nodeTaintedStep(nodeName string) string {
+	return "node-tainted/" + nodeName
+}
+
+func nodeDrainedStep(nodeName string) string {
+	return "node-drained/" + nodeName
+}
+
+func instanceTerminatedStep(instanceId string) string {
+	return "instance-terminated/" + instanceId
+}
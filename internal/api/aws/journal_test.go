@@ -0,0 +1,37 @@
+package aws
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// fakeJournal keeps the recorded steps in memory
+type fakeJournal struct {
+	steps map[string][]string
+}
+
+func newFakeJournal() *fakeJournal {
+	return &fakeJournal{steps: map[string][]string{}}
+}
+
+func (f *fakeJournal) IsCompleted(step string) bool {
+	_, ok := f.steps[step]
+	return ok
+}
+
+func (f *fakeJournal) Values(step string) []string {
+	return f.steps[step]
+}
+
+func (f *fakeJournal) Record(step string, values ...string) error {
+	f.steps[step] = values
+	return nil
+}
+
+func TestJournalOrNoop(t *testing.T) {
+	journal := journalOrNoop(nil)
+
+	assert.Nil(t, journal.Record(StepAsgCapacityUpdated))
+	assert.False(t, journal.IsCompleted(StepAsgCapacityUpdated))
+	assert.Nil(t, journal.Values(StepAsgCapacityUpdated))
+}
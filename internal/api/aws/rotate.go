@@ -0,0 +1,176 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"k8s.io/client-go/kubernetes"
+	"log"
+	"time"
+)
+
+type RotateAutoscalingGroupInterface interface {
+	UpdateAutoscalingGroupInterface
+	TerminateInstance(ctx context.Context, cfg aws.Config, instanceId string) error
+}
+
+// AutoscalingGroupRotator replaces all the instances of an ASG with new ones, by scaling the ASG out, draining the old
+// nodes once the new ones are Ready and then terminating the old instances
+type AutoscalingGroupRotator struct {
+	RotateAutoscalingGroupInterface
+	K8sClient         kubernetes.Interface
+	DrainOptions      k8s.DrainOptions
+	DrainBatchOptions DrainBatchOptions
+	NodeReadyTimeout  time.Duration
+	PollInterval      time.Duration
+	// Journal records the steps of the rotation, so that an interrupted rotation can be resumed, optional
+	Journal Journal
+}
+
+// RotationResult holds what was done to the ASG during the rotation
+type RotationResult struct {
+	Original             AutoScalingGroup
+	ReplacementInstances AwsInstances
+	DrainResults         []k8s.NodeDrainResult
+	TerminatedInstances  AwsInstances
+}
+
+// Rotate doubles the desired capacity of the ASG, waits for the new instances to register as Ready nodes, taints and
+// drains the old nodes and terminates them while decrementing the desired capacity. The ASG is restored to the original
+// capacity passed at the end.
+//
+// Old instances which couldn't be fully drained are not terminated, in which case the capacity of the ASG is left as is
+// and an error is returned.
+//
+// The steps completed are recorded in the journal. When resuming, the old instances are the ones recorded when the ASG
+// was scaled out and the steps already completed are skipped
+func (r *AutoscalingGroupRotator) Rotate(ctx context.Context, cfg aws.Config, original AutoScalingGroupCapacity) (RotationResult, error) {
+	journal := journalOrNoop(r.Journal)
+	current, err := r.DescribeAutoScalingGroup(ctx, cfg)
+	if err != nil {
+		return RotationResult{}, err
+	}
+	result := RotationResult{Original: current}
+
+	var oldInstances AwsInstances
+	if journal.IsCompleted(StepAsgScaledOut) {
+		oldInstances = current.Instances.withIds(journal.Values(StepAsgScaledOut))
+		log.Printf("Autoscaling group %s was already scaled out, resuming with %d old instances left\n",
+			current.AsgName, oldInstances.Count())
+	} else {
+		oldInstances = current.Instances
+		if oldInstances.Count() == 0 {
+			return result, fmt.Errorf("autoscaling group %s has no running instances to rotate", current.AsgName)
+		}
+
+		surgeDesired := current.DesiredInstances + oldInstances.Count()
+		surgeMax := current.MaxInstances
+		if surgeMax < surgeDesired {
+			surgeMax = surgeDesired
+		}
+		log.Printf("Scaling out autoscaling group %s to %d instances\n", current.AsgName, surgeDesired)
+		err = r.SetAutoScalingGroupCapacity(ctx, cfg, current.MinInstances, surgeMax, surgeDesired)
+		if err != nil {
+			return result, err
+		}
+		err = journal.Record(StepAsgScaledOut, oldInstances.ids()...)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if !journal.IsCompleted(StepReplacementsReady) {
+		result.ReplacementInstances, err = r.waitForReplacementNodes(ctx, cfg, oldInstances)
+		if err != nil {
+			return result, err
+		}
+		err = journal.Record(StepReplacementsReady, result.ReplacementInstances.ids()...)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	err = oldInstances.TaintNodes(r.K8sClient, journal)
+	if err != nil {
+		return result, err
+	}
+	result.DrainResults, err = oldInstances.DrainNodes(r.K8sClient, r.DrainOptions, r.DrainBatchOptions, journal)
+	if err != nil {
+		return result, err
+	}
+
+	drained := map[string]bool{}
+	for _, drainResult := range result.DrainResults {
+		drained[drainResult.NodeName] = drainResult.Drained()
+	}
+	for _, instance := range oldInstances {
+		if !drained[instance.PrivateDNS] && !journal.IsCompleted(nodeDrainedStep(instance.PrivateDNS)) {
+			log.Printf("Not terminating instance %s as node %s was not fully drained\n", instance.InstanceId, instance.PrivateDNS)
+			continue
+		}
+		log.Printf("Terminating instance %s\n", instance.InstanceId)
+		err = r.TerminateInstance(ctx, cfg, instance.InstanceId)
+		if err != nil {
+			return result, err
+		}
+		result.TerminatedInstances.AppendInstance(instance)
+		err = journal.Record(instanceTerminatedStep(instance.InstanceId))
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if result.TerminatedInstances.Count() != oldInstances.Count() {
+		return result, fmt.Errorf("%d of %d old instances were not terminated, the capacity of autoscaling group %s was not restored",
+			oldInstances.Count()-result.TerminatedInstances.Count(), oldInstances.Count(), current.AsgName)
+	}
+
+	log.Printf("Restoring autoscaling group %s to min: %d, max: %d, desired: %d\n",
+		current.AsgName, original.Min, original.Max, original.Desired)
+	updater := &AutoscalingGroupUpdater{r.RotateAutoscalingGroupInterface}
+	err = updater.Restore(ctx, cfg, original)
+	if err != nil {
+		return result, err
+	}
+	return result, journal.Record(StepAsgCapacityRestored)
+}
+
+// waitForReplacementNodes polls the ASG until as many new instances as the old ones have registered as Ready nodes
+func (r *AutoscalingGroupRotator) waitForReplacementNodes(ctx context.Context, cfg aws.Config, oldInstances AwsInstances) (AwsInstances, error) {
+	old := map[string]bool{}
+	for _, instance := range oldInstances {
+		old[instance.InstanceId] = true
+	}
+
+	deadline := time.Now().Add(r.NodeReadyTimeout)
+	for {
+		asg, err := r.DescribeAutoScalingGroup(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		ready := AwsInstances{}
+		for _, instance := range asg.Instances {
+			if old[instance.InstanceId] {
+				continue
+			}
+			isReady, err := k8s.IsNodeReady(r.K8sClient, instance.PrivateDNS)
+			if err != nil {
+				return nil, err
+			}
+			if isReady {
+				ready.AppendInstance(instance)
+			}
+		}
+
+		log.Printf("%d of %d replacement nodes are Ready\n", ready.Count(), oldInstances.Count())
+		if ready.Count() >= oldInstances.Count() {
+			return ready, nil
+		}
+		if time.Now().Add(r.PollInterval).After(deadline) {
+			return ready, fmt.Errorf("timed out after %s waiting for the replacement nodes to be Ready", r.NodeReadyTimeout)
+		}
+		time.Sleep(r.PollInterval)
+	}
+}
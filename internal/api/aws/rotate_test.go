@@ -0,0 +1,135 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+	"time"
+)
+
+type mockRotateAutoScalingGroupApi struct {
+	mock.Mock
+}
+
+func (m *mockRotateAutoScalingGroupApi) DescribeAutoScalingGroup(ctx context.Context, cfg aws.Config) (AutoScalingGroup, error) {
+	args := m.Called(ctx, cfg)
+	return args.Get(0).(AutoScalingGroup), args.Error(1)
+}
+
+func (m *mockRotateAutoScalingGroupApi) SetAutoScalingGroupCapacity(ctx context.Context, cfg aws.Config, min, max, desired int) error {
+	args := m.Called(ctx, cfg, min, max, desired)
+	return args.Error(0)
+}
+
+func (m *mockRotateAutoScalingGroupApi) TerminateInstance(ctx context.Context, cfg aws.Config, instanceId string) error {
+	args := m.Called(ctx, cfg, instanceId)
+	return args.Error(0)
+}
+
+func readyNode(name string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}},
+	}
+}
+
+func TestAutoscalingGroupRotator_Rotate(t *testing.T) {
+	oldInstances := AwsInstances{
+		{"i-old-1", "old-1.internal", "asg"},
+		{"i-old-2", "old-2.internal", "asg"},
+	}
+	newInstances := AwsInstances{
+		{"i-new-1", "new-1.internal", "asg"},
+		{"i-new-2", "new-2.internal", "asg"},
+	}
+	original := AutoScalingGroup{AsgName: "asg", Instances: oldInstances, MinInstances: 1, MaxInstances: 3, DesiredInstances: 2}
+	scaledOut := AutoScalingGroup{AsgName: "asg", Instances: append(append(AwsInstances{}, oldInstances...), newInstances...),
+		MinInstances: 1, MaxInstances: 4, DesiredInstances: 4}
+	drainOptions := k8s.NewDrainOptions(time.Millisecond, time.Millisecond, time.Second, k8s.DrainTimeoutAbort)
+
+	t.Run("when the replacement nodes get Ready, the old instances are drained, terminated and the capacity is restored", func(t *testing.T) {
+		m := new(mockRotateAutoScalingGroupApi)
+		m.On("DescribeAutoScalingGroup", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).Return(original, nil).Once()
+		m.On("DescribeAutoScalingGroup", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).Return(scaledOut, nil)
+		m.On("SetAutoScalingGroupCapacity", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), 1, 4, 4).Return(nil).Once()
+		m.On("TerminateInstance", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), "i-old-1").Return(nil).Once()
+		m.On("TerminateInstance", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), "i-old-2").Return(nil).Once()
+		m.On("SetAutoScalingGroupCapacity", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), 1, 3, 2).Return(nil).Once()
+
+		client := fake.NewSimpleClientset(readyNode("old-1.internal"), readyNode("old-2.internal"),
+			readyNode("new-1.internal"), readyNode("new-2.internal"))
+		r := AutoscalingGroupRotator{m, client, drainOptions, DrainBatchOptions{}, time.Second, time.Millisecond, nil}
+
+		result, err := r.Rotate(context.TODO(), aws.Config{}, original.Capacity())
+
+		assert.Nil(t, err)
+		assert.Equal(t, newInstances, result.ReplacementInstances)
+		assert.Equal(t, oldInstances, result.TerminatedInstances)
+		m.AssertExpectations(t)
+	})
+
+	t.Run("when the replacement nodes don't get Ready in time, the old instances are left untouched", func(t *testing.T) {
+		m := new(mockRotateAutoScalingGroupApi)
+		m.On("DescribeAutoScalingGroup", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).Return(original, nil).Once()
+		m.On("DescribeAutoScalingGroup", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).Return(scaledOut, nil)
+		m.On("SetAutoScalingGroupCapacity", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), 1, 4, 4).Return(nil).Once()
+
+		client := fake.NewSimpleClientset(readyNode("old-1.internal"), readyNode("old-2.internal"), readyNode("new-1.internal"))
+		r := AutoscalingGroupRotator{m, client, drainOptions, DrainBatchOptions{}, 10 * time.Millisecond, time.Millisecond, nil}
+
+		result, err := r.Rotate(context.TODO(), aws.Config{}, original.Capacity())
+
+		assert.NotNil(t, err)
+		assert.Empty(t, result.TerminatedInstances)
+		m.AssertNotCalled(t, "TerminateInstance", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("when resuming after the old instances were partly terminated, the remaining ones are terminated", func(t *testing.T) {
+		partlyRotated := AutoScalingGroup{AsgName: "asg", Instances: AwsInstances{oldInstances[1], newInstances[0], newInstances[1]},
+			MinInstances: 1, MaxInstances: 4, DesiredInstances: 3}
+		m := new(mockRotateAutoScalingGroupApi)
+		m.On("DescribeAutoScalingGroup", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).Return(partlyRotated, nil).Once()
+		m.On("TerminateInstance", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), "i-old-2").Return(nil).Once()
+		m.On("SetAutoScalingGroupCapacity", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config"), 1, 3, 2).Return(nil).Once()
+
+		journal := newFakeJournal()
+		_ = journal.Record(StepAsgScaledOut, "i-old-1", "i-old-2")
+		_ = journal.Record(StepReplacementsReady, "i-new-1", "i-new-2")
+		_ = journal.Record(nodeTaintedStep("old-1.internal"), "i-old-1")
+		_ = journal.Record(nodeTaintedStep("old-2.internal"), "i-old-2")
+		_ = journal.Record(nodeDrainedStep("old-1.internal"), "i-old-1")
+		_ = journal.Record(nodeDrainedStep("old-2.internal"), "i-old-2")
+		_ = journal.Record(instanceTerminatedStep("i-old-1"))
+
+		client := fake.NewSimpleClientset(readyNode("old-2.internal"), readyNode("new-1.internal"), readyNode("new-2.internal"))
+		r := AutoscalingGroupRotator{m, client, drainOptions, DrainBatchOptions{}, time.Second, time.Millisecond, journal}
+
+		result, err := r.Rotate(context.TODO(), aws.Config{}, original.Capacity())
+
+		assert.Nil(t, err)
+		assert.Equal(t, AwsInstances{oldInstances[1]}, result.TerminatedInstances)
+		assert.True(t, journal.IsCompleted(StepAsgCapacityRestored))
+		m.AssertExpectations(t)
+		m.AssertNumberOfCalls(t, "SetAutoScalingGroupCapacity", 1)
+	})
+
+	t.Run("when the ASG can't be described, it returns back an error", func(t *testing.T) {
+		m := new(mockRotateAutoScalingGroupApi)
+		m.On("DescribeAutoScalingGroup", mock.AnythingOfType(contextType), mock.AnythingOfType("aws.Config")).
+			Return(AutoScalingGroup{}, errors.New("some error")).Once()
+
+		r := AutoscalingGroupRotator{m, fake.NewSimpleClientset(), drainOptions, DrainBatchOptions{}, time.Second, time.Millisecond, nil}
+
+		_, err := r.Rotate(context.TODO(), aws.Config{}, original.Capacity())
+
+		assert.Equal(t, errors.New("some error"), err)
+		m.AssertNotCalled(t, "SetAutoScalingGroupCapacity", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
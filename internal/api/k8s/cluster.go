@@ -1,72 +1,32 @@
 package k8s
 
 import (
-	"context"
 	"errors"
 	"flag"
 	"fmt"
-	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
-	"k8s.io/client-go/util/retry"
-	"log"
-	"os/exec"
 	"path/filepath"
-	"strings"
+	"sync"
 )
 
-// ParseComponentImage takes in the full container image and returns back the container tag or the container image
-// based on the argument received
+// ParseComponentImage takes in the full container image and returns back the container version, its tag or the digest
+// it is pinned by, or the container image prefix, its registry and repository
 func ParseComponentImage(kubectlExecOutput string, imageSection string) (string, error) {
-	if imageSection == "imageTag" {
-		return strings.Split(kubectlExecOutput, ":")[1], nil
-	} else if imageSection == "imagePrefix" {
-		return strings.Split(kubectlExecOutput, ":")[0], nil
-	} else {
+	if imageSection != "imageTag" && imageSection != "imagePrefix" {
 		return "", errors.New("invalid imageSection Passed")
 	}
-}
-
-// TODO replace this with client-go
-func KubectlTaintNodeCommand(node string) string {
-	// Format: kubectl taint nodes NODE key=value:NoSchedule
-	return fmt.Sprintf(`
-	kubectl
-	taint
-	nodes
-	%s
-	taintkey=k8s-cluster-upgrade-tool:NoSchedule
-	`, node)
-}
-
-// TODO replace this with client-go
-func KubectlDrainNodeCommand(node string) string {
-	// Format: kubectl drain --ignore-daemonsets --force --delete-local-data <node name>
-	return fmt.Sprintf(`
-	kubectl
-	drain
-	--ignore-daemonsets
-	--force
-	--delete-local-data
-	%s
-	`, node)
-}
-
-// TODO replace this with client-go
-func SetK8sContext(clusterName string) {
-	command := "kubectl"
-	arg01 := "config"
-	arg02 := "use-context"
-
-	// TODO: change this to use client-go
-	cmd := exec.Command(command, arg01, arg02, clusterName)
-	err := cmd.Run()
+	reference, err := ParseImageReference(kubectlExecOutput)
 	if err != nil {
-		log.Fatalln("Error setting kube context to the cluster selected")
+		return "", err
 	}
+	if imageSection == "imageTag" {
+		return reference.Version(), nil
+	}
+	return reference.Name(), nil
 }
 
 // buildConfigFromFlags returns the config using which the client will be initialized with the k8s context we want to use
@@ -78,17 +38,30 @@ func buildConfigFromFlags(context, kubeconfigPath string) (*rest.Config, error)
 		}).ClientConfig()
 }
 
-// KubeClientInit returns back clientSet
-func KubeClientInit(kubeContext string) (*kubernetes.Clientset, error) {
-	var kubeConfig *string
-	if home := homedir.HomeDir(); home != "" {
-		kubeConfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
-	} else {
-		kubeConfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
-	}
-	flag.Parse()
+var (
+	kubeConfigOnce sync.Once
+	kubeConfigPath string
+)
 
-	config, err := buildConfigFromFlags(kubeContext, *kubeConfig)
+// kubeConfigFromFlags returns the path of the kubeconfig file passed with the kubeconfig flag, the flag being registered
+// and parsed once so that clients can be initialized for several clusters
+func kubeConfigFromFlags() string {
+	kubeConfigOnce.Do(func() {
+		var kubeConfig *string
+		if home := homedir.HomeDir(); home != "" {
+			kubeConfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
+		} else {
+			kubeConfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
+		}
+		flag.Parse()
+		kubeConfigPath = *kubeConfig
+	})
+	return kubeConfigPath
+}
+
+// KubeClientInit returns back clientSet, it can be called concurrently for several clusters
+func KubeClientInit(kubeContext string) (*kubernetes.Clientset, error) {
+	config, err := buildConfigFromFlags(kubeContext, kubeConfigFromFlags())
 	if err != nil {
 		return &kubernetes.Clientset{}, errors.New("error building the config for building the client-set for client-go")
 	}
@@ -101,104 +74,27 @@ func KubeClientInit(kubeContext string) (*kubernetes.Clientset, error) {
 	return clientSet, nil
 }
 
-// GetContainerImageForK8sObject is used to return  the container image from for the object
-// Supports deployment and Daemonsets as of now for apps/v1 api
-// The clienset would have already been initialized with the specific k8s context to be used with
-//
-// Usage:
-// kubeClient, _ := k8s.KubeClientInit("cluster-name")
-// containerImage, _ := k8s.GetContainerImageForK8sObject(kubeClient, "aws-node", "daemonset", "kube-system")
-func GetContainerImageForK8sObject(k8sClient kubernetes.Interface, k8sObjectName, k8sObject, namespace string) (string, error) {
-	switch k8sObject {
-	case "deployment":
-		// NOTE: Not targeting other api versions for the objects as of now.
-		deployment, err := k8sClient.AppsV1().Deployments(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
-		if k8sErrors.IsNotFound(err) {
-			return "", fmt.Errorf("Deployment %s in namespace %s not found\n", k8sObjectName, namespace)
-		} else if statusError, isStatus := err.(*k8sErrors.StatusError); isStatus {
-			return "", fmt.Errorf("Error getting deployment %s in namespace %s: %v\n",
-				k8sObjectName, namespace, statusError.ErrStatus.Message)
-		} else if err != nil {
-			return "", fmt.Errorf("there was an error while retrieving the container image")
-		}
-
-		// NOTE: This assumes there is only one container in the k8s object, which is true for the components for us at moment
-		return deployment.Spec.Template.Spec.Containers[0].Image, nil
-	case "daemonset":
-		// NOTE: Not targeting other api versions for the objects as of now.
-		daemonSet, err := k8sClient.AppsV1().DaemonSets(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
-		if k8sErrors.IsNotFound(err) {
-			return "", fmt.Errorf("daemonset %s in namespace %s not found\n", k8sObjectName, namespace)
-		} else if statusError, isStatus := err.(*k8sErrors.StatusError); isStatus {
-			return "", fmt.Errorf(fmt.Sprintf("Error getting daemonset %s in namespace %s: %v\n",
-				k8sObjectName, namespace, statusError.ErrStatus.Message))
-		} else if err != nil {
-			return "", fmt.Errorf("there was an error while retrieving the container image")
-		}
+// DynamicClientInit returns a dynamic client for the cluster, reading objects of any API version through the kubeconfig
+// context passed
+func DynamicClientInit(kubeContext string) (dynamic.Interface, error) {
+	config, err := buildConfigFromFlags(kubeContext, kubeConfigFromFlags())
+	if err != nil {
+		return nil, errors.New("error building the config for building the dynamic client")
+	}
 
-		// NOTE: This assumes there is only one container in the k8s object, which is true for the components for us at moment
-		return daemonSet.Spec.Template.Spec.Containers[0].Image, nil
-	default:
-		return "", fmt.Errorf("please choose between Daemonset or Deployment k8sobject as they are currently supported")
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.New("error building the dynamic client for client-go")
 	}
+	return dynamicClient, nil
 }
 
-// SetK8sObjectImage will set the image version for the deployment/daemonset object requested to update for
-func SetK8sObjectImage(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, containerName, containerImage, k8sNamespace string) error {
-	containerFound := false
-	switch k8sObject {
-	case "deployment":
-		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-			// Retrieve the latest version of Deployment before attempting update
-			// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
-			result, getErr := k8sClient.AppsV1().Deployments(k8sNamespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
-			if getErr != nil {
-				return fmt.Errorf("failed to get latest version of Deployment: %v", getErr)
-			}
-
-			for containerNumber, candidateContainer := range result.Spec.Template.Spec.Containers {
-				if candidateContainer.Name == containerName {
-					containerFound = true
-					result.Spec.Template.Spec.Containers[containerNumber].Image = containerImage // update container image
-				}
-			}
-			if !containerFound {
-				return fmt.Errorf("container %s was not found in the deployment object, skipping update", containerName)
-			}
-
-			_, updateErr := k8sClient.AppsV1().Deployments(k8sNamespace).Update(context.TODO(), result, metav1.UpdateOptions{})
-			return updateErr
-		})
-		if retryErr != nil {
-			return fmt.Errorf("container image update failed: %v", retryErr)
-		}
-		return nil
-	case "daemonset":
-		retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-			// Retrieve the latest version of Deployment before attempting update
-			// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
-			result, getErr := k8sClient.AppsV1().DaemonSets(k8sNamespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
-			if getErr != nil {
-				return fmt.Errorf("failed to get latest version of Daemonset: %v", getErr)
-			}
-
-			for containerNumber, candidateContainer := range result.Spec.Template.Spec.Containers {
-				if candidateContainer.Name == containerName {
-					containerFound = true
-					result.Spec.Template.Spec.Containers[containerNumber].Image = containerImage // update container image
-				}
-			}
-			if !containerFound {
-				return fmt.Errorf("container %s was not found in the daemonset object, skipping update", containerName)
-			}
-			_, updateErr := k8sClient.AppsV1().DaemonSets(k8sNamespace).Update(context.TODO(), result, metav1.UpdateOptions{})
-			return updateErr
-		})
-		if retryErr != nil {
-			return fmt.Errorf("container image update failed: %v", retryErr)
-		}
-		return nil
-	default:
-		return errors.New("please pass the k8sObject to be from daemonset or deployment")
+// GetServerVersion returns the version of the API server of the cluster, eg: v1.21.5-eks-bc4871b, failing when the
+// cluster can't be reached
+func GetServerVersion(k8sClient kubernetes.Interface) (string, error) {
+	serverVersion, err := k8sClient.Discovery().ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("error getting the version of the API server: %v", err)
 	}
+	return serverVersion.GitVersion, nil
 }
@@ -2,9 +2,12 @@ package k8s
 
 import (
 	"errors"
+	"fmt"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakeDiscovery "k8s.io/client-go/discovery/fake"
 	"k8s.io/client-go/kubernetes/fake"
 	"testing"
 
@@ -31,6 +34,18 @@ func TestParseComponentImage(t *testing.T) {
 		{"when getComponentImageTag is passed with a valid output and imagePrefix and it returns the image tag",
 			args{"my-hash.dkr.ecr.eu-west-1.amazonaws.com/amazon-k8s-cni:my-version", "foo"},
 			"", errors.New("invalid imageSection Passed")},
+		{"when the registry has a port it returns the image tag",
+			args{"registry:5000/amazon-k8s-cni:my-version", "imageTag"},
+			"my-version", nil},
+		{"when the registry has a port it returns the image prefix along with the port",
+			args{"registry:5000/amazon-k8s-cni:my-version", "imagePrefix"},
+			"registry:5000/amazon-k8s-cni", nil},
+		{"when the image is pinned by digest it returns the digest",
+			args{"amazon-k8s-cni@" + testDigest, "imageTag"},
+			testDigest, nil},
+		{"when the image has no tag it returns latest",
+			args{"amazon-k8s-cni", "imageTag"},
+			"latest", nil},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -41,11 +56,10 @@ func TestParseComponentImage(t *testing.T) {
 	}
 }
 
-func TestGetContainerImageForK8sObjectWhenK8sObjectIsDeployment(t *testing.T) {
+func TestGetContainerImagesForK8sObjectWhenK8sObjectIsDeployment(t *testing.T) {
 	type deploymentArgs struct {
 		k8sObject     string
 		k8sObjectName string
-		kubeContext   string
 		namespace     string
 		deployment    *appsv1.Deployment
 	}
@@ -53,11 +67,11 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDeployment(t *testing.T) {
 		name   string
 		args   deploymentArgs
 		err    error
-		output string
+		output []ContainerImage
 	}{
 		{
-			name: "When the object is of type deployment, the objectname is cluster-autoscaler, object exists and returns back the image",
-			args: deploymentArgs{k8sObject: "deployment", k8sObjectName: "cluster-autoscaler", kubeContext: "test-context", namespace: "kube-system",
+			name: "When the object is of type deployment, the objectname is cluster-autoscaler, object exists and returns back the images",
+			args: deploymentArgs{k8sObject: "deployment", k8sObjectName: "cluster-autoscaler", namespace: "kube-system",
 				deployment: &appsv1.Deployment{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "cluster-autoscaler",
@@ -68,6 +82,7 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDeployment(t *testing.T) {
 							Spec: corev1.PodSpec{
 								Containers: []corev1.Container{
 									{
+										Name:  "cluster-autoscaler",
 										Image: "cluster-autoscaler:v1.0.0",
 									},
 								},
@@ -75,15 +90,27 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDeployment(t *testing.T) {
 						},
 					},
 				}},
-			output: "cluster-autoscaler:v1.0.0",
+			output: []ContainerImage{{Name: "cluster-autoscaler", Image: "cluster-autoscaler:v1.0.0"}},
+			err:    nil,
+		},
+		{
+			name: "When the object is of type deployment and has no containers, returns back no images",
+			args: deploymentArgs{k8sObject: "deployment", k8sObjectName: "cluster-autoscaler", namespace: "kube-system",
+				deployment: &appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "cluster-autoscaler",
+						Namespace: "kube-system",
+					},
+				}},
+			output: nil,
 			err:    nil,
 		},
 		{
 			name: "When the object is of type deployment, the objectname is cluster-autoscaler, object doesn't exist, returns back error",
-			args: deploymentArgs{k8sObject: "deployment", k8sObjectName: "cluster-autoscaler", kubeContext: "test-context", namespace: "kube-system",
+			args: deploymentArgs{k8sObject: "deployment", k8sObjectName: "cluster-autoscaler", namespace: "kube-system",
 				deployment: &appsv1.Deployment{}},
-			output: "",
-			err:    errors.New("Deployment cluster-autoscaler in namespace kube-system not found\n"),
+			output: nil,
+			err:    fmt.Errorf("deployment cluster-autoscaler in namespace kube-system %w", ErrNotFound),
 		},
 	}
 
@@ -91,7 +118,7 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDeployment(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			client := fake.NewSimpleClientset(tt.args.deployment)
 
-			got, err := GetContainerImageForK8sObject(client, tt.args.k8sObjectName, tt.args.k8sObject, tt.args.namespace)
+			got, err := GetContainerImagesForK8sObject(client, tt.args.k8sObject, tt.args.k8sObjectName, tt.args.namespace)
 
 			assert.Equal(t, tt.output, got)
 			assert.Equal(t, tt.err, err)
@@ -99,11 +126,10 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDeployment(t *testing.T) {
 	}
 }
 
-func TestGetContainerImageForK8sObjectWhenK8sObjectIsDaemonSet(t *testing.T) {
+func TestGetContainerImagesForK8sObjectWhenK8sObjectIsDaemonSet(t *testing.T) {
 	type daemonSetArgs struct {
 		k8sObject     string
 		k8sObjectName string
-		kubeContext   string
 		namespace     string
 		daemonSet     *appsv1.DaemonSet
 	}
@@ -111,11 +137,11 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDaemonSet(t *testing.T) {
 		name   string
 		args   daemonSetArgs
 		err    error
-		output string
+		output []ContainerImage
 	}{
 		{
-			name: "When the object is of type daemonset, the objectname is aws-node, object exists and returns back the image",
-			args: daemonSetArgs{k8sObject: "daemonset", k8sObjectName: "aws-node", kubeContext: "test-context", namespace: "kube-system",
+			name: "When the object is of type daemonset, the objectname is aws-node, object exists and returns back the images",
+			args: daemonSetArgs{k8sObject: "daemonset", k8sObjectName: "aws-node", namespace: "kube-system",
 				daemonSet: &appsv1.DaemonSet{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:      "aws-node",
@@ -126,6 +152,7 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDaemonSet(t *testing.T) {
 							Spec: corev1.PodSpec{
 								Containers: []corev1.Container{
 									{
+										Name:  "aws-node",
 										Image: "aws-node:v1.0.0",
 									},
 								},
@@ -133,15 +160,15 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDaemonSet(t *testing.T) {
 						},
 					},
 				}},
-			output: "aws-node:v1.0.0",
+			output: []ContainerImage{{Name: "aws-node", Image: "aws-node:v1.0.0"}},
 			err:    nil,
 		},
 		{
 			name: "When the object is of type daemonset, the objectname is aws-node, object doesn't exist, returns back error",
-			args: daemonSetArgs{k8sObject: "daemonset", k8sObjectName: "aws-node", kubeContext: "test-context", namespace: "kube-system",
+			args: daemonSetArgs{k8sObject: "daemonset", k8sObjectName: "aws-node", namespace: "kube-system",
 				daemonSet: &appsv1.DaemonSet{}},
-			output: "",
-			err:    errors.New("daemonset aws-node in namespace kube-system not found\n"),
+			output: nil,
+			err:    fmt.Errorf("daemonset aws-node in namespace kube-system %w", ErrNotFound),
 		},
 	}
 
@@ -149,7 +176,7 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDaemonSet(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			client := fake.NewSimpleClientset(tt.args.daemonSet)
 
-			got, err := GetContainerImageForK8sObject(client, tt.args.k8sObjectName, tt.args.k8sObject, tt.args.namespace)
+			got, err := GetContainerImagesForK8sObject(client, tt.args.k8sObject, tt.args.k8sObjectName, tt.args.namespace)
 
 			assert.Equal(t, tt.output, got)
 			assert.Equal(t, tt.err, err)
@@ -157,11 +184,10 @@ func TestGetContainerImageForK8sObjectWhenK8sObjectIsDaemonSet(t *testing.T) {
 	}
 }
 
-func TestSetK8sObjectImageWhenObjectIsDeployment(t *testing.T) {
+func TestSetK8sObjectImagesWhenObjectIsDeployment(t *testing.T) {
 	type deploymentArgs struct {
 		k8sObject            string
 		k8sObjectName        string
-		kubeContext          string
 		namespace            string
 		targetContainerImage string
 		targetContainerName  string
@@ -177,7 +203,6 @@ func TestSetK8sObjectImageWhenObjectIsDeployment(t *testing.T) {
 			args: deploymentArgs{
 				k8sObject:            "deployment",
 				k8sObjectName:        "cluster-autoscaler",
-				kubeContext:          "test-kubecontext",
 				namespace:            "kube-system",
 				targetContainerImage: "v1.targetversion",
 				targetContainerName:  "cluster-autoscaler-container",
@@ -206,7 +231,6 @@ func TestSetK8sObjectImageWhenObjectIsDeployment(t *testing.T) {
 			args: deploymentArgs{
 				k8sObject:            "deployment",
 				k8sObjectName:        "cluster-autoscaler",
-				kubeContext:          "test-kubecontext",
 				namespace:            "kube-system",
 				targetContainerImage: "v1.targetversion",
 				targetContainerName:  "incorrect-target-container",
@@ -235,7 +259,6 @@ func TestSetK8sObjectImageWhenObjectIsDeployment(t *testing.T) {
 			args: deploymentArgs{
 				k8sObject:            "deployment",
 				k8sObjectName:        "cluster-autoscaler",
-				kubeContext:          "test-kubecontext",
 				namespace:            "kube-system",
 				targetContainerImage: "v1.targetversion",
 				deployment:           &appsv1.Deployment{}},
@@ -246,18 +269,22 @@ func TestSetK8sObjectImageWhenObjectIsDeployment(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client := fake.NewSimpleClientset(tt.args.deployment)
-			err := SetK8sObjectImage(client, tt.args.k8sObject, tt.args.k8sObjectName, tt.args.targetContainerName, tt.args.targetContainerImage, tt.args.namespace)
+			err := SetK8sObjectImages(client, tt.args.k8sObject, tt.args.k8sObjectName, tt.args.namespace,
+				[]ContainerImage{{Name: tt.args.targetContainerName, Image: tt.args.targetContainerImage}}, nil)
 
 			assert.Equal(t, tt.err, err)
+			if tt.err == nil {
+				images, _ := GetContainerImagesForK8sObject(client, tt.args.k8sObject, tt.args.k8sObjectName, tt.args.namespace)
+				assert.Equal(t, []ContainerImage{{Name: tt.args.targetContainerName, Image: tt.args.targetContainerImage}}, images)
+			}
 		})
 	}
 }
 
-func TestSetK8sObjectImageWhenObjectIsDaemonSet(t *testing.T) {
+func TestSetK8sObjectImagesWhenObjectIsDaemonSet(t *testing.T) {
 	type daemonSetArgs struct {
 		k8sObject            string
 		k8sObjectName        string
-		kubeContext          string
 		namespace            string
 		targetContainerImage string
 		targetContainerName  string
@@ -273,7 +300,6 @@ func TestSetK8sObjectImageWhenObjectIsDaemonSet(t *testing.T) {
 			args: daemonSetArgs{
 				k8sObject:            "daemonset",
 				k8sObjectName:        "aws-node",
-				kubeContext:          "test-kubecontext",
 				namespace:            "kube-system",
 				targetContainerImage: "v1.targetversion",
 				targetContainerName:  "aws-node",
@@ -302,7 +328,6 @@ func TestSetK8sObjectImageWhenObjectIsDaemonSet(t *testing.T) {
 			args: daemonSetArgs{
 				k8sObject:            "daemonset",
 				k8sObjectName:        "aws-node",
-				kubeContext:          "test-kubecontext",
 				namespace:            "kube-system",
 				targetContainerImage: "v1.targetversion",
 				targetContainerName:  "incorrect-target-container-node",
@@ -331,7 +356,6 @@ func TestSetK8sObjectImageWhenObjectIsDaemonSet(t *testing.T) {
 			args: daemonSetArgs{
 				k8sObject:            "daemonset",
 				k8sObjectName:        "aws-node",
-				kubeContext:          "test-kubecontext",
 				namespace:            "kube-system",
 				targetContainerImage: "v1.targetversion",
 				targetContainerName:  "target-container-name",
@@ -343,9 +367,24 @@ func TestSetK8sObjectImageWhenObjectIsDaemonSet(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			client := fake.NewSimpleClientset(tt.args.daemonSet)
-			err := SetK8sObjectImage(client, tt.args.k8sObject, tt.args.k8sObjectName, tt.args.targetContainerName, tt.args.targetContainerImage, tt.args.namespace)
+			err := SetK8sObjectImages(client, tt.args.k8sObject, tt.args.k8sObjectName, tt.args.namespace,
+				[]ContainerImage{{Name: tt.args.targetContainerName, Image: tt.args.targetContainerImage}}, nil)
 
 			assert.Equal(t, tt.err, err)
+			if tt.err == nil {
+				images, _ := GetContainerImagesForK8sObject(client, tt.args.k8sObject, tt.args.k8sObjectName, tt.args.namespace)
+				assert.Equal(t, []ContainerImage{{Name: tt.args.targetContainerName, Image: tt.args.targetContainerImage}}, images)
+			}
 		})
 	}
 }
+
+func TestGetServerVersion(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakeDiscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.21.5-eks-bc4871b"}
+
+	got, err := GetServerVersion(client)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.21.5-eks-bc4871b", got)
+}
@@ -0,0 +1,246 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// SourceTagAnnotation records on the k8s object the tag the digest its images are pinned by was resolved from
+	SourceTagAnnotation = "k8s-cluster-upgrade-tool.deliveryhero.com/source-tag"
+	// PreviousImagesAnnotation records on the k8s object the images it ran before the tool last set them, to roll back to
+	PreviousImagesAnnotation = "k8s-cluster-upgrade-tool.deliveryhero.com/previous-images"
+)
+
+// ErrNotFound is wrapped by the errors returned for the k8s objects which are not in the cluster
+var ErrNotFound = errors.New("not found")
+
+// ContainerImage is the image of a container, or of an init container, of a k8s object
+type ContainerImage struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+	Init  bool   `json:"init,omitempty"`
+}
+
+// ImagesRevision is the images of the containers of a k8s object along with the tag they were pinned from, if any
+type ImagesRevision struct {
+	Images    []ContainerImage `json:"images"`
+	SourceTag string           `json:"sourceTag,omitempty"`
+}
+
+// String returns the container name, prefixed with init: for init containers
+func (c ContainerImage) String() string {
+	if c.Init {
+		return "init:" + c.Name
+	}
+	return c.Name
+}
+
+// GetContainerImagesForK8sObject returns the images of all the containers and init containers of the deployment or
+// daemonset, init containers first
+func GetContainerImagesForK8sObject(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string) ([]ContainerImage, error) {
+	podSpec, err := getPodSpec(k8sClient, k8sObject, k8sObjectName, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []ContainerImage
+	for _, container := range podSpec.InitContainers {
+		images = append(images, ContainerImage{Name: container.Name, Image: container.Image, Init: true})
+	}
+	for _, container := range podSpec.Containers {
+		images = append(images, ContainerImage{Name: container.Name, Image: container.Image})
+	}
+	return images, nil
+}
+
+// SetK8sObjectImages sets the images of the containers and init containers passed in a single update of the deployment
+// or daemonset, so that they are rolled out together, along with the annotations passed, an empty value removing the
+// annotation. Nothing is updated if any of the containers is not found
+func SetK8sObjectImages(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string, images []ContainerImage, annotations map[string]string) error {
+	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		switch k8sObject {
+		case "deployment":
+			result, getErr := k8sClient.AppsV1().Deployments(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
+			if getErr != nil {
+				return fmt.Errorf("failed to get latest version of Deployment: %v", getErr)
+			}
+			if err := setPodSpecImages(&result.Spec.Template.Spec, k8sObject, images); err != nil {
+				return err
+			}
+			setAnnotations(&result.ObjectMeta, annotations)
+			_, updateErr := k8sClient.AppsV1().Deployments(namespace).Update(context.TODO(), result, metav1.UpdateOptions{})
+			return updateErr
+		case "daemonset":
+			result, getErr := k8sClient.AppsV1().DaemonSets(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
+			if getErr != nil {
+				return fmt.Errorf("failed to get latest version of Daemonset: %v", getErr)
+			}
+			if err := setPodSpecImages(&result.Spec.Template.Spec, k8sObject, images); err != nil {
+				return err
+			}
+			setAnnotations(&result.ObjectMeta, annotations)
+			_, updateErr := k8sClient.AppsV1().DaemonSets(namespace).Update(context.TODO(), result, metav1.UpdateOptions{})
+			return updateErr
+		default:
+			return errors.New("please pass the k8sObject to be from daemonset or deployment")
+		}
+	})
+	if retryErr != nil {
+		return fmt.Errorf("container image update failed: %v", retryErr)
+	}
+	return nil
+}
+
+// GetK8sObjectAnnotations returns the annotations of the deployment or daemonset
+func GetK8sObjectAnnotations(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string) (map[string]string, error) {
+	objectMeta, _, err := getK8sObject(k8sClient, k8sObject, k8sObjectName, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return objectMeta.Annotations, nil
+}
+
+// PreviousImagesAnnotationValue returns the value of the PreviousImagesAnnotation recording the revision passed
+func PreviousImagesAnnotationValue(revision ImagesRevision) (string, error) {
+	value, err := json.Marshal(revision)
+	if err != nil {
+		return "", fmt.Errorf("error encoding the previous images: %v", err)
+	}
+	return string(value), nil
+}
+
+// PreviousImagesAnnotations returns the annotations recording the images the deployment or daemonset runs, along with
+// the tag they were pinned from, for it to be rolled back to them once its containers are set to the images passed.
+// No annotation is returned when none of the images passed changes, so that setting the same images again keeps the
+// images recorded by the earlier change to roll back to
+func PreviousImagesAnnotations(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string, images []ContainerImage) (map[string]string, error) {
+	objectMeta, podSpec, err := getK8sObject(k8sClient, k8sObject, k8sObjectName, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var previous []ContainerImage
+	changed := false
+	for _, image := range images {
+		containers := podSpec.Containers
+		if image.Init {
+			containers = podSpec.InitContainers
+		}
+		for _, container := range containers {
+			if container.Name == image.Name {
+				previous = append(previous, ContainerImage{Name: image.Name, Image: container.Image, Init: image.Init})
+				changed = changed || container.Image != image.Image
+			}
+		}
+	}
+	if !changed {
+		return map[string]string{}, nil
+	}
+
+	value, err := PreviousImagesAnnotationValue(ImagesRevision{Images: previous, SourceTag: objectMeta.Annotations[SourceTagAnnotation]})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{PreviousImagesAnnotation: value}, nil
+}
+
+// GetPreviousImages returns the images the deployment or daemonset ran before the tool last set them, as recorded in
+// its PreviousImagesAnnotation
+func GetPreviousImages(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string) (ImagesRevision, error) {
+	annotations, err := GetK8sObjectAnnotations(k8sClient, k8sObject, k8sObjectName, namespace)
+	if err != nil {
+		return ImagesRevision{}, err
+	}
+	value, ok := annotations[PreviousImagesAnnotation]
+	if !ok {
+		return ImagesRevision{}, fmt.Errorf("no previous images recorded on %s %s in namespace %s, its images were not set by the tool or were already rolled back",
+			k8sObject, k8sObjectName, namespace)
+	}
+
+	var revision ImagesRevision
+	if err := json.Unmarshal([]byte(value), &revision); err != nil {
+		return ImagesRevision{}, fmt.Errorf("invalid %s annotation on %s %s in namespace %s: %v",
+			PreviousImagesAnnotation, k8sObject, k8sObjectName, namespace, err)
+	}
+	if len(revision.Images) == 0 {
+		return ImagesRevision{}, fmt.Errorf("no previous images recorded on %s %s in namespace %s", k8sObject, k8sObjectName, namespace)
+	}
+	return revision, nil
+}
+
+func setPodSpecImages(podSpec *corev1.PodSpec, k8sObject string, images []ContainerImage) error {
+	for _, image := range images {
+		containers := podSpec.Containers
+		if image.Init {
+			containers = podSpec.InitContainers
+		}
+
+		containerFound := false
+		for containerNumber := range containers {
+			if containers[containerNumber].Name == image.Name {
+				containerFound = true
+				containers[containerNumber].Image = image.Image
+			}
+		}
+		if !containerFound {
+			return fmt.Errorf("container %s was not found in the %s object, skipping update", image, k8sObject)
+		}
+	}
+	return nil
+}
+
+func setAnnotations(objectMeta *metav1.ObjectMeta, annotations map[string]string) {
+	for key, value := range annotations {
+		if value == "" {
+			delete(objectMeta.Annotations, key)
+			continue
+		}
+		if objectMeta.Annotations == nil {
+			objectMeta.Annotations = map[string]string{}
+		}
+		objectMeta.Annotations[key] = value
+	}
+}
+
+func getPodSpec(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string) (corev1.PodSpec, error) {
+	_, podSpec, err := getK8sObject(k8sClient, k8sObject, k8sObjectName, namespace)
+	return podSpec, err
+}
+
+func getK8sObject(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string) (metav1.ObjectMeta, corev1.PodSpec, error) {
+	var objectMeta metav1.ObjectMeta
+	var podSpec corev1.PodSpec
+	var err error
+	switch k8sObject {
+	case "deployment":
+		var deployment *appsv1.Deployment
+		deployment, err = k8sClient.AppsV1().Deployments(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
+		if err == nil {
+			objectMeta, podSpec = deployment.ObjectMeta, deployment.Spec.Template.Spec
+		}
+	case "daemonset":
+		var daemonSet *appsv1.DaemonSet
+		daemonSet, err = k8sClient.AppsV1().DaemonSets(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
+		if err == nil {
+			objectMeta, podSpec = daemonSet.ObjectMeta, daemonSet.Spec.Template.Spec
+		}
+	default:
+		return objectMeta, podSpec, fmt.Errorf("please choose between Daemonset or Deployment k8sobject as they are currently supported")
+	}
+
+	if k8sErrors.IsNotFound(err) {
+		return objectMeta, podSpec, fmt.Errorf("%s %s in namespace %s %w", k8sObject, k8sObjectName, namespace, ErrNotFound)
+	} else if err != nil {
+		return objectMeta, podSpec, fmt.Errorf("error getting %s %s in namespace %s: %v", k8sObject, k8sObjectName, namespace, err)
+	}
+	return objectMeta, podSpec, nil
+}
@@ -0,0 +1,226 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAwsNodeDaemonSet() *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws-node", Namespace: "kube-system"},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{Name: "aws-vpc-cni-init", Image: "amazon-k8s-cni-init:v1.10.1"},
+					},
+					Containers: []corev1.Container{
+						{Name: "aws-node", Image: "amazon-k8s-cni:v1.10.1"},
+						{Name: "aws-eks-nodeagent", Image: "aws-network-policy-agent:v1.10.1"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestGetContainerImagesForK8sObject(t *testing.T) {
+	t.Run("when the object is present, the images of its init containers and containers are returned", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testAwsNodeDaemonSet())
+
+		images, err := GetContainerImagesForK8sObject(client, "daemonset", "aws-node", "kube-system")
+
+		assert.Nil(t, err)
+		assert.Equal(t, []ContainerImage{
+			{Name: "aws-vpc-cni-init", Image: "amazon-k8s-cni-init:v1.10.1", Init: true},
+			{Name: "aws-node", Image: "amazon-k8s-cni:v1.10.1"},
+			{Name: "aws-eks-nodeagent", Image: "aws-network-policy-agent:v1.10.1"},
+		}, images)
+	})
+
+	t.Run("when the object is not present", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+
+		_, err := GetContainerImagesForK8sObject(client, "deployment", "coredns", "kube-system")
+
+		assert.EqualError(t, err, "deployment coredns in namespace kube-system not found")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("when the object type is not supported", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+
+		_, err := GetContainerImagesForK8sObject(client, "statefulset", "coredns", "kube-system")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSetK8sObjectImages(t *testing.T) {
+	t.Run("when all the containers are present, their images are updated together", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testAwsNodeDaemonSet())
+
+		err := SetK8sObjectImages(client, "daemonset", "aws-node", "kube-system", []ContainerImage{
+			{Name: "aws-vpc-cni-init", Image: "amazon-k8s-cni-init:v1.11.1", Init: true},
+			{Name: "aws-node", Image: "amazon-k8s-cni:v1.11.1"},
+			{Name: "aws-eks-nodeagent", Image: "aws-network-policy-agent:v1.11.1"},
+		}, nil)
+
+		assert.Nil(t, err)
+		daemonSet, _ := client.AppsV1().DaemonSets("kube-system").Get(context.TODO(), "aws-node", metav1.GetOptions{})
+		assert.Equal(t, "amazon-k8s-cni-init:v1.11.1", daemonSet.Spec.Template.Spec.InitContainers[0].Image)
+		assert.Equal(t, "amazon-k8s-cni:v1.11.1", daemonSet.Spec.Template.Spec.Containers[0].Image)
+		assert.Equal(t, "aws-network-policy-agent:v1.11.1", daemonSet.Spec.Template.Spec.Containers[1].Image)
+	})
+
+	t.Run("when one of the containers is not present, nothing is updated", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testAwsNodeDaemonSet())
+
+		err := SetK8sObjectImages(client, "daemonset", "aws-node", "kube-system", []ContainerImage{
+			{Name: "aws-node", Image: "amazon-k8s-cni:v1.11.1"},
+			{Name: "aws-node", Image: "amazon-k8s-cni-init:v1.11.1", Init: true},
+		}, nil)
+
+		assert.Equal(t, errors.New("container image update failed: container init:aws-node was not found in the daemonset object, skipping update"), err)
+		daemonSet, _ := client.AppsV1().DaemonSets("kube-system").Get(context.TODO(), "aws-node", metav1.GetOptions{})
+		assert.Equal(t, "amazon-k8s-cni:v1.10.1", daemonSet.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("when annotations are passed, they are set along with the images and the empty ones removed", func(t *testing.T) {
+		daemonSet := testAwsNodeDaemonSet()
+		daemonSet.Annotations = map[string]string{"stale": "value", "kept": "value"}
+		client := fake.NewSimpleClientset(daemonSet)
+
+		err := SetK8sObjectImages(client, "daemonset", "aws-node", "kube-system", []ContainerImage{
+			{Name: "aws-node", Image: "amazon-k8s-cni@" + testDigest},
+		}, map[string]string{SourceTagAnnotation: "v1.11.1", "stale": ""})
+
+		assert.Nil(t, err)
+		daemonSet, _ = client.AppsV1().DaemonSets("kube-system").Get(context.TODO(), "aws-node", metav1.GetOptions{})
+		assert.Equal(t, "amazon-k8s-cni@"+testDigest, daemonSet.Spec.Template.Spec.Containers[0].Image)
+		assert.Equal(t, map[string]string{SourceTagAnnotation: "v1.11.1", "kept": "value"}, daemonSet.Annotations)
+	})
+
+	t.Run("when the deployment is not present", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+
+		err := SetK8sObjectImages(client, "deployment", "coredns", "kube-system", []ContainerImage{{Name: "coredns", Image: "coredns:1.8.4"}}, nil)
+
+		assert.Equal(t, errors.New("container image update failed: failed to get latest version of Deployment: deployments.apps \"coredns\" not found"), err)
+	})
+}
+
+func TestPreviousImagesAnnotations(t *testing.T) {
+	t.Run("when an image changes, the images the object runs are recorded along with their source tag", func(t *testing.T) {
+		daemonSet := testAwsNodeDaemonSet()
+		daemonSet.Annotations = map[string]string{SourceTagAnnotation: "v1.10.1"}
+		client := fake.NewSimpleClientset(daemonSet)
+
+		got, err := PreviousImagesAnnotations(client, "daemonset", "aws-node", "kube-system", []ContainerImage{
+			{Name: "aws-vpc-cni-init", Image: "amazon-k8s-cni-init:v1.10.1", Init: true},
+			{Name: "aws-node", Image: "amazon-k8s-cni:v1.11.4"},
+		})
+
+		assert.Nil(t, err)
+		want, _ := PreviousImagesAnnotationValue(ImagesRevision{Images: []ContainerImage{
+			{Name: "aws-vpc-cni-init", Image: "amazon-k8s-cni-init:v1.10.1", Init: true},
+			{Name: "aws-node", Image: "amazon-k8s-cni:v1.10.1"},
+		}, SourceTag: "v1.10.1"})
+		assert.Equal(t, map[string]string{PreviousImagesAnnotation: want}, got)
+	})
+
+	t.Run("when no image changes, nothing is recorded", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testAwsNodeDaemonSet())
+
+		got, err := PreviousImagesAnnotations(client, "daemonset", "aws-node", "kube-system", []ContainerImage{
+			{Name: "aws-node", Image: "amazon-k8s-cni:v1.10.1"},
+		})
+
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]string{}, got)
+	})
+
+	t.Run("when the images are set twice, rolling back returns to the images before the first change", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testAwsNodeDaemonSet())
+		images := []ContainerImage{{Name: "aws-node", Image: "amazon-k8s-cni:v1.11.4"}}
+		for i := 0; i < 2; i++ {
+			annotations, err := PreviousImagesAnnotations(client, "daemonset", "aws-node", "kube-system", images)
+			assert.Nil(t, err)
+			assert.Nil(t, SetK8sObjectImages(client, "daemonset", "aws-node", "kube-system", images, annotations))
+		}
+
+		previous, err := GetPreviousImages(client, "daemonset", "aws-node", "kube-system")
+		assert.Nil(t, err)
+		err = SetK8sObjectImages(client, "daemonset", "aws-node", "kube-system", previous.Images,
+			map[string]string{PreviousImagesAnnotation: ""})
+
+		assert.Nil(t, err)
+		daemonSet, _ := client.AppsV1().DaemonSets("kube-system").Get(context.TODO(), "aws-node", metav1.GetOptions{})
+		assert.Equal(t, "amazon-k8s-cni:v1.10.1", daemonSet.Spec.Template.Spec.Containers[0].Image)
+	})
+
+	t.Run("when the object is not present", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+
+		_, err := PreviousImagesAnnotations(client, "daemonset", "aws-node", "kube-system", nil)
+
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+}
+
+func TestGetPreviousImages(t *testing.T) {
+	revision := ImagesRevision{
+		Images: []ContainerImage{
+			{Name: "aws-node", Image: "amazon-k8s-cni:v1.10.1"},
+			{Name: "aws-vpc-cni-init", Image: "amazon-k8s-cni-init:v1.10.1", Init: true},
+		},
+		SourceTag: "v1.10.1",
+	}
+	value, err := PreviousImagesAnnotationValue(revision)
+	assert.Nil(t, err)
+
+	t.Run("when the previous images are recorded on the object, they are returned", func(t *testing.T) {
+		daemonSet := testAwsNodeDaemonSet()
+		daemonSet.Annotations = map[string]string{PreviousImagesAnnotation: value}
+		client := fake.NewSimpleClientset(daemonSet)
+
+		got, err := GetPreviousImages(client, "daemonset", "aws-node", "kube-system")
+
+		assert.Nil(t, err)
+		assert.Equal(t, revision, got)
+	})
+
+	t.Run("when no previous images are recorded on the object", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testAwsNodeDaemonSet())
+
+		_, err := GetPreviousImages(client, "daemonset", "aws-node", "kube-system")
+
+		assert.Equal(t, errors.New("no previous images recorded on daemonset aws-node in namespace kube-system, its images were not set by the tool or were already rolled back"), err)
+	})
+
+	t.Run("when the annotation is not valid", func(t *testing.T) {
+		daemonSet := testAwsNodeDaemonSet()
+		daemonSet.Annotations = map[string]string{PreviousImagesAnnotation: "amazon-k8s-cni:v1.10.1"}
+		client := fake.NewSimpleClientset(daemonSet)
+
+		_, err := GetPreviousImages(client, "daemonset", "aws-node", "kube-system")
+
+		assert.NotNil(t, err)
+	})
+
+	t.Run("when the object is not present", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+
+		_, err := GetPreviousImages(client, "daemonset", "aws-node", "kube-system")
+
+		assert.EqualError(t, err, "daemonset aws-node in namespace kube-system not found")
+		assert.True(t, errors.Is(err, ErrNotFound))
+	})
+}
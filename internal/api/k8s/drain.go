@@ -0,0 +1,409 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"math"
+	"time"
+)
+
+const (
+	// NodeTaintKey and NodeTaintValue make up the NoSchedule taint which is added to the nodes being drained
+	NodeTaintKey   = "taintkey"
+	NodeTaintValue = "k8s-cluster-upgrade-tool"
+
+	// mirrorPodAnnotation is set by the kubelet on the api server copy of static pods, these can't be evicted
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+type PodEvictionStatus string
+
+const (
+	PodEvicted          PodEvictionStatus = "evicted"
+	PodDeleted          PodEvictionStatus = "deleted"
+	PodSkippedDaemonSet PodEvictionStatus = "skipped-daemonset"
+	PodSkippedMirror    PodEvictionStatus = "skipped-mirror"
+	PodEvictionBlocked  PodEvictionStatus = "blocked"
+	PodEvictionFailed   PodEvictionStatus = "failed"
+	// PodTerminating is the status of the pods evicted or deleted which were still terminating when the drain timed out
+	PodTerminating PodEvictionStatus = "terminating"
+)
+
+// DrainTimeoutPolicy decides what happens to the pods which are still blocked from eviction when the node drain times out
+type DrainTimeoutPolicy string
+
+const (
+	// DrainTimeoutAbort stops draining any further nodes
+	DrainTimeoutAbort DrainTimeoutPolicy = "abort"
+	// DrainTimeoutSkip leaves the blocked pods running and moves on to the next node
+	DrainTimeoutSkip DrainTimeoutPolicy = "skip"
+	// DrainTimeoutDelete deletes the blocked pods, bypassing their PodDisruptionBudgets
+	DrainTimeoutDelete DrainTimeoutPolicy = "delete"
+)
+
+// ErrNodeDrainTimeout is returned by DrainNode when the node could not be drained in time with the abort timeout policy
+var ErrNodeDrainTimeout = errors.New("timed out draining node")
+
+// ParseDrainTimeoutPolicy validates the policy passed as a flag value
+func ParseDrainTimeoutPolicy(policy string) (DrainTimeoutPolicy, error) {
+	switch DrainTimeoutPolicy(policy) {
+	case DrainTimeoutAbort, DrainTimeoutSkip, DrainTimeoutDelete:
+		return DrainTimeoutPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("invalid drain timeout policy %s, please choose between abort, skip or delete", policy)
+	}
+}
+
+// DrainOptions controls how the pods are evicted off a node
+type DrainOptions struct {
+	// EvictionBackoff is the delay between the rounds of eviction retries, for the evictions refused with a 429 due to a
+	// PodDisruptionBudget
+	EvictionBackoff wait.Backoff
+	// Timeout bounds the time spent draining a single node, zero means no timeout
+	Timeout time.Duration
+	// TimeoutPolicy is applied to the pods still blocked when the Timeout is hit
+	TimeoutPolicy DrainTimeoutPolicy
+	// DeletionPollInterval is the delay between the checks of the evicted pods being deleted
+	DeletionPollInterval time.Duration
+}
+
+// NewDrainOptions returns DrainOptions with an exponential eviction backoff, starting from retryInterval and capped to
+// maxRetryInterval, the evicted pods being checked for deletion every retryInterval
+func NewDrainOptions(retryInterval, maxRetryInterval, timeout time.Duration, timeoutPolicy DrainTimeoutPolicy) DrainOptions {
+	return DrainOptions{
+		EvictionBackoff: wait.Backoff{
+			Duration: retryInterval,
+			Factor:   2,
+			Steps:    math.MaxInt32,
+			Cap:      maxRetryInterval,
+		},
+		Timeout:              timeout,
+		TimeoutPolicy:        timeoutPolicy,
+		DeletionPollInterval: retryInterval,
+	}
+}
+
+// PodEvictionResult is the outcome of draining a single pod off a node
+type PodEvictionResult struct {
+	Namespace string
+	Name      string
+	Status    PodEvictionStatus
+	// BlockingPDB is the namespace/name of the PodDisruptionBudget which refused the eviction of the pod, if any. Error
+	// holds why it could not be found for a blocked pod whose PodDisruptionBudgets could not be listed
+	BlockingPDB string
+	// Owner is the controller of the pod, nil for bare pods
+	Owner *WorkloadRef
+	Error error
+}
+
+// NodeDrainResult holds the per pod outcome for a drained node
+type NodeDrainResult struct {
+	NodeName string
+	TimedOut bool
+	Pods     []PodEvictionResult
+}
+
+// Failed returns the pods which could not be evicted from the node
+func (n NodeDrainResult) Failed() []PodEvictionResult {
+	return n.podsWithStatus(PodEvictionFailed)
+}
+
+// Blocked returns the pods whose eviction was still refused by a PodDisruptionBudget when the node drain timed out
+func (n NodeDrainResult) Blocked() []PodEvictionResult {
+	return n.podsWithStatus(PodEvictionBlocked)
+}
+
+// Terminating returns the pods which were evicted or deleted but were still terminating when the node drain timed out
+func (n NodeDrainResult) Terminating() []PodEvictionResult {
+	return n.podsWithStatus(PodTerminating)
+}
+
+// Drained is true when no pods are left behind on the node
+func (n NodeDrainResult) Drained() bool {
+	return len(n.Failed()) == 0 && len(n.Blocked()) == 0 && len(n.Terminating()) == 0
+}
+
+func (n NodeDrainResult) podsWithStatus(status PodEvictionStatus) []PodEvictionResult {
+	var pods []PodEvictionResult
+	for _, pod := range n.Pods {
+		if pod.Status == status {
+			pods = append(pods, pod)
+		}
+	}
+	return pods
+}
+
+// CordonNode marks the node as unschedulable
+func CordonNode(k8sClient kubernetes.Interface, nodeName string) error {
+	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, getErr := k8sClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		if getErr != nil {
+			return fmt.Errorf("failed to get latest version of node: %v", getErr)
+		}
+		if node.Spec.Unschedulable {
+			return nil
+		}
+
+		node.Spec.Unschedulable = true
+		_, updateErr := k8sClient.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+		return updateErr
+	})
+	if retryErr != nil {
+		return fmt.Errorf("cordoning node %s failed: %v", nodeName, retryErr)
+	}
+	return nil
+}
+
+// TaintNode adds the NoSchedule taint of the tool to the node, it is a no-op if the node is already tainted
+func TaintNode(k8sClient kubernetes.Interface, nodeName string) error {
+	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		node, getErr := k8sClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+		if getErr != nil {
+			return fmt.Errorf("failed to get latest version of node: %v", getErr)
+		}
+		for _, taint := range node.Spec.Taints {
+			if taint.Key == NodeTaintKey && taint.Effect == corev1.TaintEffectNoSchedule {
+				return nil
+			}
+		}
+
+		node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{
+			Key:    NodeTaintKey,
+			Value:  NodeTaintValue,
+			Effect: corev1.TaintEffectNoSchedule,
+		})
+		_, updateErr := k8sClient.CoreV1().Nodes().Update(context.TODO(), node, metav1.UpdateOptions{})
+		return updateErr
+	})
+	if retryErr != nil {
+		return fmt.Errorf("tainting node %s failed: %v", nodeName, retryErr)
+	}
+	return nil
+}
+
+// DrainNode cordons and taints the node and then evicts the pods running on it via the Eviction API.
+// DaemonSet managed pods and mirror pods are skipped, same as what `kubectl drain --ignore-daemonsets` does.
+//
+// Evictions refused with a 429 because of a PodDisruptionBudget are retried with the backoff from the options until the
+// node timeout, after which the timeout policy is applied to the pods still blocked. Same as `kubectl drain`, the node is
+// only drained once the evicted and deleted pods are gone, which is polled until the node timeout: a pod is gone once it
+// is not found anymore or has been replaced by a pod of the same name, as StatefulSets do. The returned error is set
+// when the node itself could not be prepared for the drain or when the drain timed out with the abort policy, failures
+// to evict individual pods are reported in the NodeDrainResult
+func DrainNode(k8sClient kubernetes.Interface, nodeName string, opts DrainOptions) (NodeDrainResult, error) {
+	result := NodeDrainResult{NodeName: nodeName}
+
+	if err := CordonNode(k8sClient, nodeName); err != nil {
+		return result, err
+	}
+	if err := TaintNode(k8sClient, nodeName); err != nil {
+		return result, err
+	}
+
+	pods, err := k8sClient.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return result, fmt.Errorf("listing pods on node %s failed: %v", nodeName, err)
+	}
+
+	var pending []corev1.Pod
+	for _, pod := range pods.Items {
+		// the field selector is applied on the server, this guards against clients which don't honour it
+		if pod.Spec.NodeName != nodeName {
+			continue
+		}
+
+		switch {
+		case isMirrorPod(pod):
+			result.Pods = append(result.Pods, PodEvictionResult{Namespace: pod.Namespace, Name: pod.Name, Status: PodSkippedMirror})
+		case isDaemonSetPod(pod):
+			result.Pods = append(result.Pods, PodEvictionResult{Namespace: pod.Namespace, Name: pod.Name, Status: PodSkippedDaemonSet})
+		default:
+			pending = append(pending, pod)
+		}
+	}
+
+	var deadline time.Time
+	if opts.Timeout > 0 {
+		deadline = time.Now().Add(opts.Timeout)
+	}
+	backoff := opts.EvictionBackoff
+	blockedBy := map[string]string{}
+	blockedByErrs := map[string]error{}
+	var evicted []corev1.Pod
+
+	// every round tries to evict all the pending pods, the ones refused by a PodDisruptionBudget are retried in the next one
+	for len(pending) > 0 {
+		var stillBlocked []corev1.Pod
+		for _, pod := range pending {
+			err := EvictPod(k8sClient, pod.Name, pod.Namespace)
+			switch {
+			case err == nil:
+				evicted = append(evicted, pod)
+				result.Pods = append(result.Pods, PodEvictionResult{Namespace: pod.Namespace, Name: pod.Name, Status: PodEvicted, Owner: podOwner(pod)})
+			case k8sErrors.IsNotFound(err):
+				result.Pods = append(result.Pods, PodEvictionResult{Namespace: pod.Namespace, Name: pod.Name, Status: PodEvicted, Owner: podOwner(pod)})
+			case k8sErrors.IsTooManyRequests(err):
+				blockedBy[podKey(pod)], blockedByErrs[podKey(pod)] = findBlockingPDB(k8sClient, pod)
+				stillBlocked = append(stillBlocked, pod)
+			default:
+				result.Pods = append(result.Pods, PodEvictionResult{Namespace: pod.Namespace, Name: pod.Name, Status: PodEvictionFailed, Error: err})
+			}
+		}
+		pending = stillBlocked
+		if len(pending) == 0 {
+			break
+		}
+
+		delay := backoff.Step()
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				result.TimedOut = true
+				break
+			}
+			// the last round of evictions is tried when the timeout is hit rather than one backoff step before it
+			if delay > remaining {
+				delay = remaining
+			}
+		}
+		time.Sleep(delay)
+	}
+
+	for _, pod := range pending {
+		podResult := PodEvictionResult{Namespace: pod.Namespace, Name: pod.Name, Status: PodEvictionBlocked,
+			BlockingPDB: blockedBy[podKey(pod)], Error: blockedByErrs[podKey(pod)]}
+		if opts.TimeoutPolicy == DrainTimeoutDelete {
+			err := k8sClient.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{})
+			if err != nil && !k8sErrors.IsNotFound(err) {
+				podResult.Status = PodEvictionFailed
+				podResult.Error = err
+			} else {
+				podResult.Status = PodDeleted
+				podResult.Owner = podOwner(pod)
+				podResult.Error = nil
+				evicted = append(evicted, pod)
+			}
+		}
+		result.Pods = append(result.Pods, podResult)
+	}
+
+	terminating := waitForPodsDeleted(k8sClient, evicted, deadline, opts.DeletionPollInterval)
+	for _, pod := range terminating {
+		for i := range result.Pods {
+			if result.Pods[i].Namespace == pod.Namespace && result.Pods[i].Name == pod.Name {
+				result.Pods[i].Status = PodTerminating
+			}
+		}
+	}
+	if len(terminating) > 0 {
+		result.TimedOut = true
+	}
+
+	if result.TimedOut && opts.TimeoutPolicy == DrainTimeoutAbort {
+		return result, fmt.Errorf("%w %s after %s, %d pod(s) still blocked and %d pod(s) still terminating",
+			ErrNodeDrainTimeout, nodeName, opts.Timeout, len(result.Blocked()), len(terminating))
+	}
+	return result, nil
+}
+
+// waitForPodsDeleted polls the pods until all of them are gone or the deadline is hit, a zero deadline meaning no
+// deadline, and returns the pods still terminating. A pod is gone once it is not found anymore or once a pod of the
+// same name but another UID replaced it
+func waitForPodsDeleted(k8sClient kubernetes.Interface, pods []corev1.Pod, deadline time.Time, pollInterval time.Duration) []corev1.Pod {
+	pending := pods
+	for len(pending) > 0 {
+		var terminating []corev1.Pod
+		for _, pod := range pending {
+			current, err := k8sClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+			if k8sErrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+				continue
+			}
+			terminating = append(terminating, pod)
+		}
+		pending = terminating
+		if len(pending) == 0 {
+			break
+		}
+
+		delay := pollInterval
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				break
+			}
+			if delay > remaining {
+				delay = remaining
+			}
+		}
+		time.Sleep(delay)
+	}
+	return pending
+}
+
+// EvictPod creates an eviction for the pod, which lets the api server honour the PodDisruptionBudgets for it
+func EvictPod(k8sClient kubernetes.Interface, podName, namespace string) error {
+	return k8sClient.CoreV1().Pods(namespace).Evict(context.TODO(), &policyv1beta1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: namespace,
+		},
+	})
+}
+
+// findBlockingPDB returns the namespace/name of the PodDisruptionBudget selecting the pod, preferring the ones which
+// don't allow any disruptions at the moment
+func findBlockingPDB(k8sClient kubernetes.Interface, pod corev1.Pod) (string, error) {
+	pdbs, err := ListPodDisruptionBudgets(k8sClient, pod.Namespace)
+	if err != nil {
+		return "", err
+	}
+
+	blocking := ""
+	for _, pdb := range pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		if pdb.Status.DisruptionsAllowed == 0 {
+			return pdb.Namespace + "/" + pdb.Name, nil
+		}
+		if blocking == "" {
+			blocking = pdb.Namespace + "/" + pdb.Name
+		}
+	}
+	return blocking, nil
+}
+
+func podKey(pod corev1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
+
+func podOwner(pod corev1.Pod) *WorkloadRef {
+	controllerRef := metav1.GetControllerOf(&pod)
+	if controllerRef == nil {
+		return nil
+	}
+	return &WorkloadRef{Kind: controllerRef.Kind, Namespace: pod.Namespace, Name: controllerRef.Name}
+}
+
+func isMirrorPod(pod corev1.Pod) bool {
+	_, found := pod.Annotations[mirrorPodAnnotation]
+	return found
+}
+
+func isDaemonSetPod(pod corev1.Pod) bool {
+	controllerRef := metav1.GetControllerOf(&pod)
+	return controllerRef != nil && controllerRef.Kind == "DaemonSet"
+}
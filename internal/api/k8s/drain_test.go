@@ -0,0 +1,353 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testNode(name string) *corev1.Node {
+	return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func testDrainOptions(policy DrainTimeoutPolicy) DrainOptions {
+	return NewDrainOptions(time.Millisecond, 5*time.Millisecond, 50*time.Millisecond, policy)
+}
+
+func testPod(name, namespace, nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       corev1.PodSpec{NodeName: nodeName},
+	}
+}
+
+// evictionReactor deletes the pod on eviction like the api server does, unless the pod is listed in failing
+func evictionReactor(client *fake.Clientset, failing map[string]error) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		if err, found := failing[eviction.Name]; found {
+			return true, nil, err
+		}
+		return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+	}
+}
+
+func TestCordonNode(t *testing.T) {
+	t.Run("when the node exists, it is marked unschedulable", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"))
+
+		err := CordonNode(client, "node-1")
+
+		assert.Nil(t, err)
+		node, _ := client.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+		assert.True(t, node.Spec.Unschedulable)
+	})
+
+	t.Run("when the node doesn't exist, it returns back an error", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+
+		err := CordonNode(client, "node-1")
+
+		assert.Equal(t, errors.New("cordoning node node-1 failed: failed to get latest version of node: nodes \"node-1\" not found"), err)
+	})
+}
+
+func TestTaintNode(t *testing.T) {
+	t.Run("when the node isn't tainted yet, the taint is added", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"))
+
+		err := TaintNode(client, "node-1")
+
+		assert.Nil(t, err)
+		node, _ := client.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+		assert.Equal(t, []corev1.Taint{{Key: NodeTaintKey, Value: NodeTaintValue, Effect: corev1.TaintEffectNoSchedule}}, node.Spec.Taints)
+	})
+
+	t.Run("when the node is already tainted, the taint is not added again", func(t *testing.T) {
+		node := testNode("node-1")
+		node.Spec.Taints = []corev1.Taint{{Key: NodeTaintKey, Value: NodeTaintValue, Effect: corev1.TaintEffectNoSchedule}}
+		client := fake.NewSimpleClientset(node)
+
+		err := TaintNode(client, "node-1")
+
+		assert.Nil(t, err)
+		updated, _ := client.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+		assert.Len(t, updated.Spec.Taints, 1)
+	})
+
+	t.Run("when the node doesn't exist, it returns back an error", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+
+		err := TaintNode(client, "node-1")
+
+		assert.Equal(t, errors.New("tainting node node-1 failed: failed to get latest version of node: nodes \"node-1\" not found"), err)
+	})
+}
+
+func TestDrainNode(t *testing.T) {
+	isController := true
+
+	daemonSetPod := testPod("aws-node-abcde", "kube-system", "node-1")
+	daemonSetPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "aws-node", Controller: &isController}}
+
+	mirrorPod := testPod("kube-proxy-node-1", "kube-system", "node-1")
+	mirrorPod.Annotations = map[string]string{mirrorPodAnnotation: "hash"}
+
+	t.Run("when the node has regular, daemonset and mirror pods, only the regular pods are evicted", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"), testPod("app-1", "default", "node-1"),
+			testPod("app-2", "default", "node-2"), daemonSetPod, mirrorPod)
+		client.PrependReactor("create", "pods", evictionReactor(client, nil))
+
+		result, err := DrainNode(client, "node-1", testDrainOptions(DrainTimeoutAbort))
+
+		assert.Nil(t, err)
+		assert.Equal(t, "node-1", result.NodeName)
+		assert.ElementsMatch(t, []PodEvictionResult{
+			{Namespace: "default", Name: "app-1", Status: PodEvicted},
+			{Namespace: "kube-system", Name: "aws-node-abcde", Status: PodSkippedDaemonSet},
+			{Namespace: "kube-system", Name: "kube-proxy-node-1", Status: PodSkippedMirror},
+		}, result.Pods)
+		assert.Empty(t, result.Failed())
+
+		node, _ := client.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{})
+		assert.True(t, node.Spec.Unschedulable)
+		assert.Len(t, node.Spec.Taints, 1)
+
+		_, getErr := client.CoreV1().Pods("default").Get(context.TODO(), "app-2", metav1.GetOptions{})
+		assert.Nil(t, getErr)
+	})
+
+	t.Run("when a pod eviction fails, it is reported in the result without stopping the drain", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"), testPod("app-1", "default", "node-1"), testPod("app-2", "default", "node-1"))
+		evictionErr := errors.New("some eviction error")
+		client.PrependReactor("create", "pods", evictionReactor(client, map[string]error{"app-1": evictionErr}))
+
+		result, err := DrainNode(client, "node-1", testDrainOptions(DrainTimeoutAbort))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []PodEvictionResult{{Namespace: "default", Name: "app-1", Status: PodEvictionFailed, Error: evictionErr}}, result.Failed())
+		assert.Len(t, result.Pods, 2)
+	})
+
+	t.Run("when the node doesn't exist, it returns back an error", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+
+		result, err := DrainNode(client, "node-1", testDrainOptions(DrainTimeoutAbort))
+
+		assert.NotNil(t, err)
+		assert.Empty(t, result.Pods)
+	})
+}
+
+func TestDrainNodeWhenEvictionIsBlockedByPodDisruptionBudget(t *testing.T) {
+	blockedPod := testPod("app-1", "default", "node-1")
+	blockedPod.Labels = map[string]string{"app": "app"}
+
+	minAvailable := intstr.FromInt(1)
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-pdb", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}},
+		},
+	}
+	tooManyRequests := k8sErrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+
+	t.Run("when the eviction is refused a few times before going through, the pod is evicted", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"), blockedPod, pdb)
+		attempts := 0
+		client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() != "eviction" {
+				return false, nil, nil
+			}
+			attempts++
+			if attempts < 3 {
+				return true, nil, tooManyRequests
+			}
+			return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), "default", "app-1")
+		})
+
+		result, err := DrainNode(client, "node-1", testDrainOptions(DrainTimeoutAbort))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.False(t, result.TimedOut)
+		assert.Equal(t, []PodEvictionResult{{Namespace: "default", Name: "app-1", Status: PodEvicted}}, result.Pods)
+	})
+
+	t.Run("when the backoff step is longer than the time left, the eviction is retried when the timeout is hit", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"), blockedPod, pdb)
+		attempts := 0
+		client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() != "eviction" {
+				return false, nil, nil
+			}
+			attempts++
+			if attempts < 2 {
+				return true, nil, tooManyRequests
+			}
+			return true, nil, client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), "default", "app-1")
+		})
+
+		result, err := DrainNode(client, "node-1", NewDrainOptions(time.Second, time.Second, 20*time.Millisecond, DrainTimeoutAbort))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, []PodEvictionResult{{Namespace: "default", Name: "app-1", Status: PodEvicted}}, result.Pods)
+	})
+
+	tests := []struct {
+		name       string
+		policy     DrainTimeoutPolicy
+		wantStatus PodEvictionStatus
+		wantErr    bool
+		podExists  bool
+	}{
+		{"when the drain times out with the abort policy, the blocked pod is reported and an error is returned",
+			DrainTimeoutAbort, PodEvictionBlocked, true, true},
+		{"when the drain times out with the skip policy, the blocked pod is reported without an error",
+			DrainTimeoutSkip, PodEvictionBlocked, false, true},
+		{"when the drain times out with the delete policy, the blocked pod is deleted",
+			DrainTimeoutDelete, PodDeleted, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(testNode("node-1"), blockedPod, pdb)
+			client.PrependReactor("create", "pods", evictionReactor(client, map[string]error{"app-1": tooManyRequests}))
+
+			result, err := DrainNode(client, "node-1", testDrainOptions(tt.policy))
+
+			assert.Equal(t, tt.wantErr, err != nil)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrNodeDrainTimeout))
+			}
+			assert.True(t, result.TimedOut)
+			assert.Equal(t, []PodEvictionResult{
+				{Namespace: "default", Name: "app-1", Status: tt.wantStatus, BlockingPDB: "default/app-pdb"},
+			}, result.Pods)
+
+			_, getErr := client.CoreV1().Pods("default").Get(context.TODO(), "app-1", metav1.GetOptions{})
+			assert.Equal(t, tt.podExists, getErr == nil)
+		})
+	}
+
+	t.Run("when the API server serves policy/v1 only, the blocking PodDisruptionBudget is found through it", func(t *testing.T) {
+		client := fakeClientServingPolicyVersion("v1", testNode("node-1"), blockedPod, pdb)
+		client.PrependReactor("create", "pods", evictionReactor(client, map[string]error{"app-1": tooManyRequests}))
+
+		result, _ := DrainNode(client, "node-1", testDrainOptions(DrainTimeoutSkip))
+
+		assert.Equal(t, []PodEvictionResult{
+			{Namespace: "default", Name: "app-1", Status: PodEvictionBlocked, BlockingPDB: "default/app-pdb"},
+		}, result.Pods)
+	})
+
+	t.Run("when the PodDisruptionBudgets can't be listed, the error is reported along with the blocked pod", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"), blockedPod, pdb)
+		client.PrependReactor("create", "pods", evictionReactor(client, map[string]error{"app-1": tooManyRequests}))
+		client.PrependReactor("list", "poddisruptionbudgets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New("connection refused")
+		})
+
+		result, _ := DrainNode(client, "node-1", testDrainOptions(DrainTimeoutSkip))
+
+		assert.Equal(t, []PodEvictionResult{
+			{Namespace: "default", Name: "app-1", Status: PodEvictionBlocked,
+				Error: errors.New("error listing the PodDisruptionBudgets: connection refused")},
+		}, result.Pods)
+	})
+}
+
+func TestDrainNodeWaitsForEvictedPodsToBeDeleted(t *testing.T) {
+	terminatingPod := func() *corev1.Pod {
+		pod := testPod("app-1", "default", "node-1")
+		pod.UID = "uid-1"
+		return pod
+	}
+	// acceptingReactor accepts the evictions without deleting the pods, leaving them terminating
+	acceptingReactor := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return action.GetSubresource() == "eviction", nil, nil
+	}
+
+	t.Run("when the evicted pod is deleted after its grace period, the node is drained once it is gone", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"), terminatingPod())
+		client.PrependReactor("create", "pods", acceptingReactor)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			_ = client.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), "default", "app-1")
+		}()
+
+		result, err := DrainNode(client, "node-1", NewDrainOptions(time.Millisecond, time.Millisecond, time.Second, DrainTimeoutAbort))
+
+		assert.Nil(t, err)
+		assert.True(t, result.Drained())
+		_, getErr := client.CoreV1().Pods("default").Get(context.TODO(), "app-1", metav1.GetOptions{})
+		assert.True(t, k8sErrors.IsNotFound(getErr))
+	})
+
+	t.Run("when the evicted pod is replaced by a pod of the same name, the node is drained", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"), terminatingPod())
+		client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if action.GetSubresource() != "eviction" {
+				return false, nil, nil
+			}
+			replacement := testPod("app-1", "default", "node-2")
+			replacement.UID = "uid-2"
+			return true, nil, client.Tracker().Update(corev1.SchemeGroupVersion.WithResource("pods"), replacement, "default")
+		})
+
+		result, err := DrainNode(client, "node-1", testDrainOptions(DrainTimeoutAbort))
+
+		assert.Nil(t, err)
+		assert.Equal(t, []PodEvictionResult{{Namespace: "default", Name: "app-1", Status: PodEvicted}}, result.Pods)
+	})
+
+	t.Run("when the evicted pod is still terminating at the timeout, the node is not drained", func(t *testing.T) {
+		client := fake.NewSimpleClientset(testNode("node-1"), terminatingPod())
+		client.PrependReactor("create", "pods", acceptingReactor)
+
+		result, err := DrainNode(client, "node-1", testDrainOptions(DrainTimeoutAbort))
+
+		assert.True(t, errors.Is(err, ErrNodeDrainTimeout))
+		assert.True(t, result.TimedOut)
+		assert.False(t, result.Drained())
+		assert.Equal(t, []PodEvictionResult{{Namespace: "default", Name: "app-1", Status: PodTerminating}}, result.Terminating())
+	})
+}
+
+func TestParseDrainTimeoutPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   DrainTimeoutPolicy
+		err    error
+	}{
+		{"when the policy is abort", "abort", DrainTimeoutAbort, nil},
+		{"when the policy is skip", "skip", DrainTimeoutSkip, nil},
+		{"when the policy is delete", "delete", DrainTimeoutDelete, nil},
+		{"when the policy is not supported", "foo", "",
+			errors.New("invalid drain timeout policy foo, please choose between abort, skip or delete")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDrainTimeoutPolicy(tt.policy)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// digestPattern matches the content digests images are pinned by, eg: sha256:<64 hex characters>
+var digestPattern = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+
+// ImageReference is a container image split into the registry host (along with its port), the repository path, the
+// tag and the digest. Registry is empty for images pulled from Docker Hub without naming it, Tag and Digest are empty
+// when the image doesn't carry them
+type ImageReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseImageReference parses images of the form [registry[:port]/]repository[:tag][@digest]
+func ParseImageReference(image string) (ImageReference, error) {
+	var reference ImageReference
+	name := image
+	if index := strings.Index(name, "@"); index != -1 {
+		reference.Digest = name[index+1:]
+		name = name[:index]
+		if !IsDigest(reference.Digest) {
+			return ImageReference{}, fmt.Errorf("invalid digest %s in image %s", reference.Digest, image)
+		}
+	}
+
+	// the tag is whatever follows the last colon of the last path component, a colon before it is the registry port
+	if index := strings.LastIndex(name, ":"); index != -1 && index > strings.LastIndex(name, "/") {
+		reference.Tag = name[index+1:]
+		name = name[:index]
+		if reference.Tag == "" {
+			return ImageReference{}, fmt.Errorf("empty tag in image %s", image)
+		}
+	}
+
+	// the first path component is the registry only when it looks like a host, as docker.io/library is implied otherwise
+	if index := strings.Index(name, "/"); index != -1 {
+		host := name[:index]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			reference.Registry = host
+			name = name[index+1:]
+		}
+	}
+
+	if name == "" || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || strings.Contains(name, "//") {
+		return ImageReference{}, fmt.Errorf("invalid repository in image %s", image)
+	}
+	reference.Repository = name
+	return reference, nil
+}
+
+// IsDigest returns true if the version passed is a content digest rather than a tag
+func IsDigest(version string) bool {
+	return digestPattern.MatchString(version)
+}
+
+// Name returns the registry and repository of the image, without its tag or digest
+func (r ImageReference) Name() string {
+	if r.Registry == "" {
+		return r.Repository
+	}
+	return r.Registry + "/" + r.Repository
+}
+
+// String returns the image back in the [registry[:port]/]repository[:tag][@digest] form
+func (r ImageReference) String() string {
+	image := r.Name()
+	if r.Tag != "" {
+		image += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		image += "@" + r.Digest
+	}
+	return image
+}
+
+// IsPinnedByDigest returns true if the image is pulled by its digest, any tag it carries being ignored by the runtime
+func (r ImageReference) IsPinnedByDigest() bool {
+	return r.Digest != ""
+}
+
+// Version returns the digest of the images pinned by digest and the tag otherwise, latest being implied for the images
+// carrying neither
+func (r ImageReference) Version() string {
+	if r.IsPinnedByDigest() {
+		return r.Digest
+	}
+	if r.Tag == "" {
+		return "latest"
+	}
+	return r.Tag
+}
+
+// IsOnVersion returns true if the version passed is the tag or the digest the image is pulled by. A tag is never
+// matched by an image pinned by digest, even one carrying that tag, as the runtime ignores the tag: the digest the tag
+// resolves to has to be compared instead
+func (r ImageReference) IsOnVersion(version string) bool {
+	if IsDigest(version) {
+		return r.Digest == version
+	}
+	if r.IsPinnedByDigest() {
+		return false
+	}
+	return r.Tag == version || (r.Tag == "" && version == "latest")
+}
+
+// WithVersion returns the image with only its version replaced, keeping its registry and repository. A digest replaces
+// the tag and the digest of the image, as the tag would not match the new digest anymore, while a tag replaces the tag
+// and drops the digest the image was pinned by as the runtime would keep pulling the image by the old digest otherwise
+func (r ImageReference) WithVersion(version string) ImageReference {
+	if IsDigest(version) {
+		r.Tag = ""
+		r.Digest = version
+		return r
+	}
+	r.Tag = version
+	r.Digest = ""
+	return r
+}
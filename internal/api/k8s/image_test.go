@@ -0,0 +1,120 @@
+package k8s
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDigest = "sha256:4bcbd1e1b6b7b9ed25e2cc5d2c4a1b7a8ffb2f5c8d7e3d5a6b1c9e0f7a2d3c4b"
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		name  string
+		image string
+		want  ImageReference
+		err   error
+	}{
+		{"when the image is on docker hub with a tag", "nginx:1.21",
+			ImageReference{Repository: "nginx", Tag: "1.21"}, nil},
+		{"when the image has no tag", "nginx",
+			ImageReference{Repository: "nginx"}, nil},
+		{"when the image is on ecr with a tag",
+			"602401143452.dkr.ecr.eu-west-1.amazonaws.com/amazon-k8s-cni:v1.11.0",
+			ImageReference{Registry: "602401143452.dkr.ecr.eu-west-1.amazonaws.com", Repository: "amazon-k8s-cni", Tag: "v1.11.0"}, nil},
+		{"when the registry has a port", "registry:5000/team/repo:tag",
+			ImageReference{Registry: "registry:5000", Repository: "team/repo", Tag: "tag"}, nil},
+		{"when the registry has a port and the image has no tag", "registry:5000/repo",
+			ImageReference{Registry: "registry:5000", Repository: "repo"}, nil},
+		{"when the registry is localhost", "localhost/repo:tag",
+			ImageReference{Registry: "localhost", Repository: "repo", Tag: "tag"}, nil},
+		{"when the first path component is not a host it is part of the repository", "bitnami/external-dns:0.11.0",
+			ImageReference{Repository: "bitnami/external-dns", Tag: "0.11.0"}, nil},
+		{"when the image is pinned by digest", "k8s.gcr.io/coredns/coredns@" + testDigest,
+			ImageReference{Registry: "k8s.gcr.io", Repository: "coredns/coredns", Digest: testDigest}, nil},
+		{"when the image has both a tag and a digest", "registry:5000/repo:v1@" + testDigest,
+			ImageReference{Registry: "registry:5000", Repository: "repo", Tag: "v1", Digest: testDigest}, nil},
+		{"when the digest is invalid", "repo@sha256:foo",
+			ImageReference{}, errors.New("invalid digest sha256:foo in image repo@sha256:foo")},
+		{"when the tag is empty", "repo:",
+			ImageReference{}, errors.New("empty tag in image repo:")},
+		{"when the repository is empty", "registry:5000/",
+			ImageReference{}, errors.New("invalid repository in image registry:5000/")},
+		{"when the image is empty", "",
+			ImageReference{}, errors.New("invalid repository in image ")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImageReference(tt.image)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.err, err)
+			if err == nil {
+				assert.Equal(t, tt.image, got.String())
+			}
+		})
+	}
+}
+
+func TestImageReference_Version(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference ImageReference
+		want      string
+	}{
+		{"when the image has a tag", ImageReference{Repository: "repo", Tag: "v1"}, "v1"},
+		{"when the image has no tag latest is implied", ImageReference{Repository: "repo"}, "latest"},
+		{"when the image is pinned by digest", ImageReference{Repository: "repo", Digest: testDigest}, testDigest},
+		{"when the image has a tag and a digest the digest wins", ImageReference{Repository: "repo", Tag: "v1", Digest: testDigest}, testDigest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.reference.Version())
+		})
+	}
+}
+
+func TestImageReference_IsOnVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference ImageReference
+		version   string
+		want      bool
+	}{
+		{"when the tag matches", ImageReference{Repository: "repo", Tag: "v1"}, "v1", true},
+		{"when the tag differs", ImageReference{Repository: "repo", Tag: "v1"}, "v2", false},
+		{"when the image has no tag and latest is expected", ImageReference{Repository: "repo"}, "latest", true},
+		{"when the digest matches", ImageReference{Repository: "repo", Digest: testDigest}, testDigest, true},
+		{"when a digest is expected and the image is pulled by tag", ImageReference{Repository: "repo", Tag: "v1"}, testDigest, false},
+		{"when a tag is expected and the image is only pinned by digest", ImageReference{Repository: "repo", Digest: testDigest}, "v1", false},
+		{"when a tag is expected and the image carries it along with its digest", ImageReference{Repository: "repo", Tag: "v1", Digest: testDigest}, "v1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.reference.IsOnVersion(tt.version))
+		})
+	}
+}
+
+func TestImageReference_WithVersion(t *testing.T) {
+	otherDigest := "sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name    string
+		image   string
+		version string
+		want    string
+	}{
+		{"when the tag is replaced", "registry:5000/team/repo:v1", "v2", "registry:5000/team/repo:v2"},
+		{"when a tag is set on an untagged image", "registry:5000/repo", "v2", "registry:5000/repo:v2"},
+		{"when the digest is replaced", "k8s.gcr.io/coredns/coredns@" + testDigest, otherDigest, "k8s.gcr.io/coredns/coredns@" + otherDigest},
+		{"when the digest is replaced the tag is dropped", "repo:v1@" + testDigest, otherDigest, "repo@" + otherDigest},
+		{"when a tag is set on an image pinned by digest the digest is dropped", "repo:v1@" + testDigest, "v2", "repo:v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reference, err := ParseImageReference(tt.image)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, reference.WithVersion(tt.version).String())
+		})
+	}
+}
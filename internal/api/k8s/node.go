@@ -0,0 +1,100 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sort"
+)
+
+// IsNodeReady returns true when the node has registered with the cluster and its Ready condition is true, a node which
+// hasn't registered yet is reported as not ready without an error
+func IsNodeReady(k8sClient kubernetes.Interface, nodeName string) (bool, error) {
+	node, err := k8sClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if k8sErrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("error getting node %s: %v", nodeName, err)
+	}
+
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue, nil
+		}
+	}
+	return false, nil
+}
+
+// GetNodeNames returns the names of all the nodes registered with the cluster
+func GetNodeNames(k8sClient kubernetes.Interface) (map[string]bool, error) {
+	nodes, err := k8sClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the nodes: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, node := range nodes.Items {
+		names[node.Name] = true
+	}
+	return names, nil
+}
+
+// GetCordonedNodeNames returns the names of the nodes registered with the cluster which are cordoned
+func GetCordonedNodeNames(k8sClient kubernetes.Interface) (map[string]bool, error) {
+	nodes, err := k8sClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the nodes: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable {
+			names[node.Name] = true
+		}
+	}
+	return names, nil
+}
+
+// GetNodeKubeletVersions returns the kubelet version of every node registered with the cluster, keyed by node name
+func GetNodeKubeletVersions(k8sClient kubernetes.Interface) (map[string]string, error) {
+	nodes, err := k8sClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the nodes: %v", err)
+	}
+
+	versions := map[string]string{}
+	for _, node := range nodes.Items {
+		versions[node.Name] = node.Status.NodeInfo.KubeletVersion
+	}
+	return versions, nil
+}
+
+// NodeVersion is a node along with the provider id of the instance it runs on, eg: aws:///eu-west-1a/i-0a1b2c3d4e5f67890,
+// and its kubelet version
+type NodeVersion struct {
+	Name           string
+	ProviderID     string
+	KubeletVersion string
+}
+
+// GetNodeVersions returns the provider id and the kubelet version of every node registered with the cluster, sorted by
+// node name
+func GetNodeVersions(k8sClient kubernetes.Interface) ([]NodeVersion, error) {
+	nodes, err := k8sClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the nodes: %v", err)
+	}
+
+	versions := make([]NodeVersion, 0, len(nodes.Items))
+	for _, node := range nodes.Items {
+		versions = append(versions, NodeVersion{Name: node.Name, ProviderID: node.Spec.ProviderID,
+			KubeletVersion: node.Status.NodeInfo.KubeletVersion})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Name < versions[j].Name
+	})
+	return versions, nil
+}
@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testNodeWithReadyCondition(name string, status corev1.ConditionStatus) *corev1.Node {
+	node := testNode(name)
+	node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}
+	return node
+}
+
+func TestIsNodeReady(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []*corev1.Node
+		want  bool
+	}{
+		{"when the node is registered and ready", []*corev1.Node{testNodeWithReadyCondition("node-1", corev1.ConditionTrue)}, true},
+		{"when the node is registered but not ready", []*corev1.Node{testNodeWithReadyCondition("node-1", corev1.ConditionFalse)}, false},
+		{"when the node is registered without any conditions yet", []*corev1.Node{testNode("node-1")}, false},
+		{"when the node has not registered yet", []*corev1.Node{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			for _, node := range tt.nodes {
+				_ = client.Tracker().Add(node)
+			}
+
+			got, err := IsNodeReady(client, "node-1")
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGetNodeNames(t *testing.T) {
+	client := fake.NewSimpleClientset(testNode("node-1"), testNode("node-2"))
+
+	got, err := GetNodeNames(client)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"node-1": true, "node-2": true}, got)
+}
+
+func TestGetCordonedNodeNames(t *testing.T) {
+	node1 := testNode("node-1")
+	node1.Spec.Unschedulable = true
+	client := fake.NewSimpleClientset(node1, testNode("node-2"))
+
+	got, err := GetCordonedNodeNames(client)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"node-1": true}, got)
+}
+
+func TestGetNodeKubeletVersions(t *testing.T) {
+	node1 := testNode("node-1")
+	node1.Status.NodeInfo.KubeletVersion = "v1.21.5-eks-9017834"
+	node2 := testNode("node-2")
+	node2.Status.NodeInfo.KubeletVersion = "v1.20.11-eks-f17b81"
+	client := fake.NewSimpleClientset(node1, node2)
+
+	got, err := GetNodeKubeletVersions(client)
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"node-1": "v1.21.5-eks-9017834", "node-2": "v1.20.11-eks-f17b81"}, got)
+}
+
+func TestGetNodeVersions(t *testing.T) {
+	node2 := testNode("node-2")
+	node2.Spec.ProviderID = "aws:///eu-west-1b/i-0b2c3d4e5f6a7b8c9"
+	node2.Status.NodeInfo.KubeletVersion = "v1.20.11-eks-f17b81"
+	node1 := testNode("node-1")
+	node1.Spec.ProviderID = "aws:///eu-west-1a/i-0a1b2c3d4e5f67890"
+	node1.Status.NodeInfo.KubeletVersion = "v1.21.5-eks-9017834"
+	client := fake.NewSimpleClientset(node2, node1)
+
+	got, err := GetNodeVersions(client)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []NodeVersion{
+		{Name: "node-1", ProviderID: "aws:///eu-west-1a/i-0a1b2c3d4e5f67890", KubeletVersion: "v1.21.5-eks-9017834"},
+		{Name: "node-2", ProviderID: "aws:///eu-west-1b/i-0b2c3d4e5f6a7b8c9", KubeletVersion: "v1.20.11-eks-f17b81"},
+	}, got)
+}
@@ -0,0 +1,53 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ListPodDisruptionBudgets returns the PodDisruptionBudgets of the namespace, of all the namespaces for
+// metav1.NamespaceAll. They are listed through policy/v1, the only version served from Kubernetes 1.25 on, falling back
+// to policy/v1beta1 for the API servers which don't serve policy/v1 yet
+func ListPodDisruptionBudgets(k8sClient kubernetes.Interface, namespace string) ([]policyv1.PodDisruptionBudget, error) {
+	pdbs, err := k8sClient.PolicyV1().PodDisruptionBudgets(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err == nil {
+		return pdbs.Items, nil
+	} else if !k8sErrors.IsNotFound(err) {
+		return nil, fmt.Errorf("error listing the PodDisruptionBudgets: %v", err)
+	}
+
+	betaPdbs, err := k8sClient.PolicyV1beta1().PodDisruptionBudgets(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the PodDisruptionBudgets: %v", err)
+	}
+	converted := make([]policyv1.PodDisruptionBudget, 0, len(betaPdbs.Items))
+	for _, pdb := range betaPdbs.Items {
+		converted = append(converted, fromV1beta1(pdb))
+	}
+	return converted, nil
+}
+
+func fromV1beta1(pdb policyv1beta1.PodDisruptionBudget) policyv1.PodDisruptionBudget {
+	return policyv1.PodDisruptionBudget{
+		ObjectMeta: pdb.ObjectMeta,
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MinAvailable:   pdb.Spec.MinAvailable,
+			Selector:       pdb.Spec.Selector,
+			MaxUnavailable: pdb.Spec.MaxUnavailable,
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{
+			ObservedGeneration: pdb.Status.ObservedGeneration,
+			DisruptedPods:      pdb.Status.DisruptedPods,
+			DisruptionsAllowed: pdb.Status.DisruptionsAllowed,
+			CurrentHealthy:     pdb.Status.CurrentHealthy,
+			DesiredHealthy:     pdb.Status.DesiredHealthy,
+			ExpectedPods:       pdb.Status.ExpectedPods,
+			Conditions:         pdb.Status.Conditions,
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClientServingPolicyVersion returns a fake clientset on which only the version passed of the policy API is
+// served, the listing of the PodDisruptionBudgets of the other version being refused with a 404 like an API server does
+func fakeClientServingPolicyVersion(version string, objects ...runtime.Object) *fake.Clientset {
+	client := fake.NewSimpleClientset(objects...)
+	client.PrependReactor("list", "poddisruptionbudgets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetResource().Version == version {
+			return false, nil, nil
+		}
+		return true, nil, k8sErrors.NewNotFound(action.GetResource().GroupResource(), "")
+	})
+	return client
+}
+
+func TestListPodDisruptionBudgets(t *testing.T) {
+	t.Run("when the API server serves policy/v1 only, the PodDisruptionBudgets are listed through it", func(t *testing.T) {
+		client := fakeClientServingPolicyVersion("v1", &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1, ExpectedPods: 3},
+		})
+
+		pdbs, err := ListPodDisruptionBudgets(client, metav1.NamespaceAll)
+
+		assert.Nil(t, err)
+		assert.Len(t, pdbs, 1)
+		assert.Equal(t, "app", pdbs[0].Name)
+		assert.Equal(t, int32(3), pdbs[0].Status.ExpectedPods)
+	})
+
+	t.Run("when the API server doesn't serve policy/v1, the PodDisruptionBudgets are listed through policy/v1beta1", func(t *testing.T) {
+		client := fakeClientServingPolicyVersion("v1beta1", &policyv1beta1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			Spec:       policyv1beta1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}}},
+			Status:     policyv1beta1.PodDisruptionBudgetStatus{DisruptionsAllowed: 0, ExpectedPods: 2},
+		})
+
+		pdbs, err := ListPodDisruptionBudgets(client, "default")
+
+		assert.Nil(t, err)
+		assert.Len(t, pdbs, 1)
+		assert.Equal(t, map[string]string{"app": "app"}, pdbs[0].Spec.Selector.MatchLabels)
+		assert.Equal(t, int32(2), pdbs[0].Status.ExpectedPods)
+	})
+}
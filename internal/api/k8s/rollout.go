@@ -0,0 +1,236 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unhealthyWaitingReasons are the reasons a container of an updated pod waits for which the rollout is not expected to
+// recover from on its own, along with the number of consecutive polls the reason has to be seen for before the rollout
+// is failed. A failed image pull or a missing config map may be transient, the kubelet moving the container to
+// ImagePullBackOff once the pull keeps failing
+var unhealthyWaitingReasons = map[string]int{
+	"CrashLoopBackOff":           1,
+	"ImagePullBackOff":           1,
+	"InvalidImageName":           1,
+	"ErrImagePull":               3,
+	"CreateContainerConfigError": 3,
+}
+
+// waitingContainer is a container of a pod waiting to start
+type waitingContainer struct {
+	Name   string
+	Reason string
+}
+
+func (w waitingContainer) String() string {
+	return fmt.Sprintf("container %s is in %s", w.Name, w.Reason)
+}
+
+// RolloutStatus is the progress of the rollout of the pod template of a deployment or daemonset
+type RolloutStatus struct {
+	// Observed is false until the controller has seen the last update of the object
+	Observed  bool
+	Desired   int32
+	Updated   int32
+	Available int32
+	// Old is the count of pods still running the previous pod template
+	Old int32
+	// Stalled is set when the controller reports the rollout as not progressing anymore
+	Stalled string
+}
+
+// Done returns true when all the desired pods run the new pod template and are available
+func (r RolloutStatus) Done() bool {
+	return r.Observed && r.Updated >= r.Desired && r.Available >= r.Desired && r.Old == 0
+}
+
+func (r RolloutStatus) String() string {
+	if !r.Observed {
+		return "waiting for the controller to observe the update"
+	}
+	return fmt.Sprintf("%d of %d pods updated, %d available, %d old pods pending termination",
+		r.Updated, r.Desired, r.Available, r.Old)
+}
+
+// GetRolloutStatus returns the progress of the rollout of the deployment or daemonset
+func GetRolloutStatus(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string) (RolloutStatus, error) {
+	switch k8sObject {
+	case "deployment":
+		deployment, err := k8sClient.AppsV1().Deployments(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
+		if err != nil {
+			return RolloutStatus{}, fmt.Errorf("error getting deployment %s in namespace %s: %v", k8sObjectName, namespace, err)
+		}
+		return deploymentRolloutStatus(deployment), nil
+	case "daemonset":
+		daemonSet, err := k8sClient.AppsV1().DaemonSets(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
+		if err != nil {
+			return RolloutStatus{}, fmt.Errorf("error getting daemonset %s in namespace %s: %v", k8sObjectName, namespace, err)
+		}
+		return daemonSetRolloutStatus(daemonSet), nil
+	default:
+		return RolloutStatus{}, errors.New("please choose between Daemonset or Deployment k8sobject as they are currently supported")
+	}
+}
+
+func deploymentRolloutStatus(deployment *appsv1.Deployment) RolloutStatus {
+	status := RolloutStatus{
+		Observed:  deployment.Status.ObservedGeneration >= deployment.Generation,
+		Desired:   replicasOrDefault(deployment.Spec.Replicas),
+		Updated:   deployment.Status.UpdatedReplicas,
+		Available: deployment.Status.AvailableReplicas,
+		Old:       deployment.Status.Replicas - deployment.Status.UpdatedReplicas,
+	}
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			status.Stalled = condition.Message
+		}
+	}
+	return status
+}
+
+func daemonSetRolloutStatus(daemonSet *appsv1.DaemonSet) RolloutStatus {
+	return RolloutStatus{
+		Observed:  daemonSet.Status.ObservedGeneration >= daemonSet.Generation,
+		Desired:   daemonSet.Status.DesiredNumberScheduled,
+		Updated:   daemonSet.Status.UpdatedNumberScheduled,
+		Available: daemonSet.Status.NumberAvailable,
+		Old:       daemonSet.Status.CurrentNumberScheduled - daemonSet.Status.UpdatedNumberScheduled,
+	}
+}
+
+// GetUnhealthyPods returns the pods of the deployment or daemonset running the images of its pod template which have a
+// container crash looping or failing to pull its image, along with the reason
+func GetUnhealthyPods(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string) (map[string]string, error) {
+	waiting, err := getUnhealthyContainers(k8sClient, k8sObject, k8sObjectName, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	unhealthy := map[string]string{}
+	for pod, container := range waiting {
+		unhealthy[pod] = container.String()
+	}
+	return unhealthy, nil
+}
+
+func getUnhealthyContainers(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string) (map[string]waitingContainer, error) {
+	var selector *metav1.LabelSelector
+	var podSpec corev1.PodSpec
+	switch k8sObject {
+	case "deployment":
+		deployment, err := k8sClient.AppsV1().Deployments(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting deployment %s in namespace %s: %v", k8sObjectName, namespace, err)
+		}
+		selector, podSpec = deployment.Spec.Selector, deployment.Spec.Template.Spec
+	case "daemonset":
+		daemonSet, err := k8sClient.AppsV1().DaemonSets(namespace).Get(context.TODO(), k8sObjectName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting daemonset %s in namespace %s: %v", k8sObjectName, namespace, err)
+		}
+		selector, podSpec = daemonSet.Spec.Selector, daemonSet.Spec.Template.Spec
+	default:
+		return nil, errors.New("please choose between Daemonset or Deployment k8sobject as they are currently supported")
+	}
+
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector of %s %s in namespace %s: %v", k8sObject, k8sObjectName, namespace, err)
+	}
+	pods, err := k8sClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: labelSelector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the pods of %s %s in namespace %s: %v", k8sObject, k8sObjectName, namespace, err)
+	}
+
+	templateImages := map[string]string{}
+	for _, container := range podSpec.InitContainers {
+		templateImages[container.Name] = container.Image
+	}
+	for _, container := range podSpec.Containers {
+		templateImages[container.Name] = container.Image
+	}
+
+	unhealthy := map[string]waitingContainer{}
+	for _, pod := range pods.Items {
+		var statuses []corev1.ContainerStatus
+		statuses = append(statuses, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			// only the pods of the new pod template count, the old ones are on their way out
+			if status.State.Waiting == nil || unhealthyWaitingReasons[status.State.Waiting.Reason] == 0 ||
+				!podRunsImage(pod, status.Name, templateImages[status.Name]) {
+				continue
+			}
+			unhealthy[pod.Name] = waitingContainer{Name: status.Name, Reason: status.State.Waiting.Reason}
+		}
+	}
+	return unhealthy, nil
+}
+
+func podRunsImage(pod corev1.Pod, containerName, image string) bool {
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			if container.Name == containerName {
+				return container.Image == image
+			}
+		}
+	}
+	return false
+}
+
+// WaitForRollout polls the deployment or daemonset until all its desired pods run the new pod template and are
+// available, logging the progress of the rollout. It returns an error as soon as the rollout stalls or one of the new
+// pods crash loops or keeps failing to pull its image, or once the timeout is hit
+func WaitForRollout(k8sClient kubernetes.Interface, k8sObject, k8sObjectName, namespace string, timeout, pollInterval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	// the number of consecutive polls each pod was seen waiting for the same reason
+	waitingPolls := map[string]int{}
+	for {
+		status, err := GetRolloutStatus(k8sClient, k8sObject, k8sObjectName, namespace)
+		if err != nil {
+			return err
+		}
+		if status.Done() {
+			log.Printf("Rollout of %s %s complete: %s\n", k8sObject, k8sObjectName, status)
+			return nil
+		}
+		if status.Stalled != "" {
+			return fmt.Errorf("rollout of %s %s stalled: %s", k8sObject, k8sObjectName, status.Stalled)
+		}
+
+		waiting, err := getUnhealthyContainers(k8sClient, k8sObject, k8sObjectName, namespace)
+		if err != nil {
+			return err
+		}
+		polls := map[string]int{}
+		var reasons []string
+		for pod, container := range waiting {
+			key := pod + "/" + container.String()
+			polls[key] = waitingPolls[key] + 1
+			if polls[key] >= unhealthyWaitingReasons[container.Reason] {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", pod, container))
+			}
+		}
+		waitingPolls = polls
+		if len(reasons) > 0 {
+			sort.Strings(reasons)
+			return fmt.Errorf("rollout of %s %s failed, pods are unhealthy: %s", k8sObject, k8sObjectName, strings.Join(reasons, ", "))
+		}
+
+		log.Printf("Waiting for rollout of %s %s: %s\n", k8sObject, k8sObjectName, status)
+		if time.Now().Add(pollInterval).After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for the rollout of %s %s: %s", timeout, k8sObject, k8sObjectName, status)
+		}
+		time.Sleep(pollInterval)
+	}
+}
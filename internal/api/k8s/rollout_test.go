@@ -0,0 +1,196 @@
+package k8s
+
+import (
+	"errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCoreDnsDeployment(status appsv1.DeploymentStatus) *appsv1.Deployment {
+	replicas := int32(2)
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "coredns", Namespace: "kube-system", Generation: 2},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "kube-dns"}},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "coredns", Image: "coredns:1.8.4"}},
+			}},
+		},
+		Status: status,
+	}
+}
+
+func testCoreDnsPod(name, image, waitingReason string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system", Labels: map[string]string{"k8s-app": "kube-dns"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "coredns", Image: image}}},
+	}
+	if waitingReason != "" {
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "coredns",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: waitingReason}}}}
+	}
+	return pod
+}
+
+func TestGetRolloutStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   runtime.Object
+		k8sObj   string
+		k8sName  string
+		wantDone bool
+		want     RolloutStatus
+	}{
+		{
+			name:     "when the deployment update has not been observed yet",
+			object:   testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			k8sObj:   "deployment",
+			k8sName:  "coredns",
+			wantDone: false,
+			want:     RolloutStatus{Observed: false, Desired: 2, Updated: 2, Available: 2},
+		},
+		{
+			name:     "when old pods of the deployment are still running",
+			object:   testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			k8sObj:   "deployment",
+			k8sName:  "coredns",
+			wantDone: false,
+			want:     RolloutStatus{Observed: true, Desired: 2, Updated: 2, Available: 2, Old: 1},
+		},
+		{
+			name:     "when all the pods of the deployment are updated and available",
+			object:   testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}),
+			k8sObj:   "deployment",
+			k8sName:  "coredns",
+			wantDone: true,
+			want:     RolloutStatus{Observed: true, Desired: 2, Updated: 2, Available: 2},
+		},
+		{
+			name: "when the deployment exceeded its progress deadline",
+			object: testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1,
+				Conditions: []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, Reason: "ProgressDeadlineExceeded",
+					Message: "ReplicaSet \"coredns-abc\" has timed out progressing."}}}),
+			k8sObj:   "deployment",
+			k8sName:  "coredns",
+			wantDone: false,
+			want: RolloutStatus{Observed: true, Desired: 2, Updated: 1, Old: 2,
+				Stalled: "ReplicaSet \"coredns-abc\" has timed out progressing."},
+		},
+		{
+			name: "when the daemonset is partially rolled out",
+			object: &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "aws-node", Namespace: "kube-system", Generation: 3},
+				Status: appsv1.DaemonSetStatus{ObservedGeneration: 3, DesiredNumberScheduled: 3, CurrentNumberScheduled: 3,
+					UpdatedNumberScheduled: 1, NumberAvailable: 3},
+			},
+			k8sObj:   "daemonset",
+			k8sName:  "aws-node",
+			wantDone: false,
+			want:     RolloutStatus{Observed: true, Desired: 3, Updated: 1, Available: 3, Old: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.object)
+
+			got, err := GetRolloutStatus(client, tt.k8sObj, tt.k8sName, "kube-system")
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantDone, got.Done())
+		})
+	}
+}
+
+func TestGetUnhealthyPods(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		testCoreDnsDeployment(appsv1.DeploymentStatus{}),
+		testCoreDnsPod("coredns-new-1", "coredns:1.8.4", "CrashLoopBackOff"),
+		testCoreDnsPod("coredns-new-2", "coredns:1.8.4", "ContainerCreating"),
+		testCoreDnsPod("coredns-old-1", "coredns:1.8.3", "ImagePullBackOff"),
+	)
+
+	unhealthy, err := GetUnhealthyPods(client, "deployment", "coredns", "kube-system")
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"coredns-new-1": "container coredns is in CrashLoopBackOff"}, unhealthy)
+}
+
+func TestWaitForRollout(t *testing.T) {
+	t.Run("when the rollout is complete", func(t *testing.T) {
+		client := fake.NewSimpleClientset(
+			testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2}))
+
+		err := WaitForRollout(client, "deployment", "coredns", "kube-system", time.Second, time.Millisecond)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("when a new pod fails to pull its image, it fails without waiting for the timeout", func(t *testing.T) {
+		client := fake.NewSimpleClientset(
+			testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2}),
+			testCoreDnsPod("coredns-new-1", "coredns:1.8.4", "ImagePullBackOff"))
+
+		err := WaitForRollout(client, "deployment", "coredns", "kube-system", time.Hour, time.Millisecond)
+
+		assert.Equal(t, errors.New("rollout of deployment coredns failed, pods are unhealthy: coredns-new-1: container coredns is in ImagePullBackOff"), err)
+	})
+
+	t.Run("when a new pod keeps failing to pull its image, it fails once the error persists over several polls", func(t *testing.T) {
+		client := fake.NewSimpleClientset(
+			testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2}),
+			testCoreDnsPod("coredns-new-1", "coredns:1.8.4", "ErrImagePull"))
+		polls := 0
+		client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			polls++
+			return false, nil, nil
+		})
+
+		err := WaitForRollout(client, "deployment", "coredns", "kube-system", time.Hour, time.Millisecond)
+
+		assert.Equal(t, errors.New("rollout of deployment coredns failed, pods are unhealthy: coredns-new-1: container coredns is in ErrImagePull"), err)
+		assert.Equal(t, 3, polls)
+	})
+
+	t.Run("when a new pod fails to pull its image once, the rollout goes on", func(t *testing.T) {
+		client := fake.NewSimpleClientset(
+			testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2}),
+			testCoreDnsPod("coredns-new-1", "coredns:1.8.4", "ErrImagePull"))
+		client.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			// the image is pulled on the retry and the rollout completes
+			deployment := testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2})
+			_ = client.Tracker().Update(appsv1.SchemeGroupVersion.WithResource("deployments"), deployment, "kube-system")
+			return false, nil, nil
+		})
+
+		err := WaitForRollout(client, "deployment", "coredns", "kube-system", time.Hour, time.Millisecond)
+
+		assert.Nil(t, err)
+	})
+
+	t.Run("when the rollout doesn't converge before the timeout", func(t *testing.T) {
+		client := fake.NewSimpleClientset(
+			testCoreDnsDeployment(appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 1, AvailableReplicas: 2}))
+
+		err := WaitForRollout(client, "deployment", "coredns", "kube-system", 5*time.Millisecond, time.Millisecond)
+
+		assert.Equal(t, errors.New("timed out after 5ms waiting for the rollout of deployment coredns: 1 of 2 pods updated, 2 available, 2 old pods pending termination"), err)
+	})
+
+	t.Run("when the deployment is not present", func(t *testing.T) {
+		client := fake.NewSimpleClientset()
+
+		err := WaitForRollout(client, "deployment", "coredns", "kube-system", time.Second, time.Millisecond)
+
+		assert.Equal(t, errors.New("error getting deployment coredns in namespace kube-system: deployments.apps \"coredns\" not found"), err)
+	})
+}
@@ -0,0 +1,183 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"log"
+	"time"
+)
+
+// WorkloadRef identifies the controller owning a pod
+type WorkloadRef struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (w WorkloadRef) String() string {
+	return fmt.Sprintf("%s/%s/%s", w.Kind, w.Namespace, w.Name)
+}
+
+// EvictedWorkloads returns the distinct controllers of the pods which were evicted or deleted off the drained nodes,
+// including the ones still terminating
+func EvictedWorkloads(results []NodeDrainResult) []WorkloadRef {
+	seen := map[WorkloadRef]bool{}
+	var workloads []WorkloadRef
+	for _, result := range results {
+		for _, pod := range result.Pods {
+			if pod.Status != PodEvicted && pod.Status != PodDeleted && pod.Status != PodTerminating {
+				continue
+			}
+			if pod.Owner == nil || seen[*pod.Owner] {
+				continue
+			}
+			seen[*pod.Owner] = true
+			workloads = append(workloads, *pod.Owner)
+		}
+	}
+	return workloads
+}
+
+// IsWorkloadReady returns true when all the replicas of the Deployment, ReplicaSet or StatefulSet are ready. Pods owned
+// by a ReplicaSet are checked against the Deployment owning the ReplicaSet, if any. Other kinds of controllers and
+// workloads which don't exist anymore are reported as ready.
+//
+// The status of the workload lags behind the evictions, still counting the evicted pods as ready until the controller
+// observes their deletion, so the ready pods selected by the workload, which are not being deleted, are counted as well
+func IsWorkloadReady(k8sClient kubernetes.Interface, workload WorkloadRef) (bool, error) {
+	ready, err := isWorkloadStatusReady(k8sClient, workload)
+	if err != nil || !ready.ready || ready.selector == nil {
+		return ready.ready, err
+	}
+	readyPods, err := countReadyPods(k8sClient, ready.namespace, ready.selector)
+	if err != nil {
+		return false, err
+	}
+	return readyPods >= ready.replicas, nil
+}
+
+// workloadReadiness is the readiness of a workload as per its status, along with what is needed to count its ready pods
+type workloadReadiness struct {
+	ready     bool
+	namespace string
+	selector  *metav1.LabelSelector
+	replicas  int32
+}
+
+func isWorkloadStatusReady(k8sClient kubernetes.Interface, workload WorkloadRef) (workloadReadiness, error) {
+	switch workload.Kind {
+	case "Deployment":
+		deployment, err := k8sClient.AppsV1().Deployments(workload.Namespace).Get(context.TODO(), workload.Name, metav1.GetOptions{})
+		if k8sErrors.IsNotFound(err) {
+			return workloadReadiness{ready: true}, nil
+		} else if err != nil {
+			return workloadReadiness{}, fmt.Errorf("error getting deployment %s in namespace %s: %v", workload.Name, workload.Namespace, err)
+		}
+		replicas := replicasOrDefault(deployment.Spec.Replicas)
+		return workloadReadiness{
+			ready: deployment.Status.ObservedGeneration >= deployment.Generation &&
+				deployment.Status.ReadyReplicas >= replicas,
+			namespace: deployment.Namespace, selector: deployment.Spec.Selector, replicas: replicas,
+		}, nil
+	case "ReplicaSet":
+		replicaSet, err := k8sClient.AppsV1().ReplicaSets(workload.Namespace).Get(context.TODO(), workload.Name, metav1.GetOptions{})
+		if k8sErrors.IsNotFound(err) {
+			return workloadReadiness{ready: true}, nil
+		} else if err != nil {
+			return workloadReadiness{}, fmt.Errorf("error getting replicaset %s in namespace %s: %v", workload.Name, workload.Namespace, err)
+		}
+		if owner := metav1.GetControllerOf(replicaSet); owner != nil && owner.Kind == "Deployment" {
+			return isWorkloadStatusReady(k8sClient, WorkloadRef{Kind: owner.Kind, Namespace: workload.Namespace, Name: owner.Name})
+		}
+		replicas := replicasOrDefault(replicaSet.Spec.Replicas)
+		return workloadReadiness{
+			ready: replicaSet.Status.ObservedGeneration >= replicaSet.Generation &&
+				replicaSet.Status.ReadyReplicas >= replicas,
+			namespace: replicaSet.Namespace, selector: replicaSet.Spec.Selector, replicas: replicas,
+		}, nil
+	case "StatefulSet":
+		statefulSet, err := k8sClient.AppsV1().StatefulSets(workload.Namespace).Get(context.TODO(), workload.Name, metav1.GetOptions{})
+		if k8sErrors.IsNotFound(err) {
+			return workloadReadiness{ready: true}, nil
+		} else if err != nil {
+			return workloadReadiness{}, fmt.Errorf("error getting statefulset %s in namespace %s: %v", workload.Name, workload.Namespace, err)
+		}
+		replicas := replicasOrDefault(statefulSet.Spec.Replicas)
+		return workloadReadiness{
+			ready: statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+				statefulSet.Status.ReadyReplicas >= replicas,
+			namespace: statefulSet.Namespace, selector: statefulSet.Spec.Selector, replicas: replicas,
+		}, nil
+	default:
+		return workloadReadiness{ready: true}, nil
+	}
+}
+
+// countReadyPods returns the number of pods selected by the selector which are ready and not being deleted
+func countReadyPods(k8sClient kubernetes.Interface, namespace string, selector *metav1.LabelSelector) (int32, error) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return 0, fmt.Errorf("invalid selector %s: %v", selector.String(), err)
+	}
+	pods, err := k8sClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: labelSelector.String()})
+	if err != nil {
+		return 0, fmt.Errorf("error listing the pods in namespace %s: %v", namespace, err)
+	}
+
+	var ready int32
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				ready++
+			}
+		}
+	}
+	return ready, nil
+}
+
+// WaitForWorkloadsReady polls the workloads until all of them are ready or the timeout is hit
+func WaitForWorkloadsReady(k8sClient kubernetes.Interface, workloads []WorkloadRef, timeout, pollInterval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	pending := workloads
+	for {
+		var notReady []WorkloadRef
+		for _, workload := range pending {
+			ready, err := IsWorkloadReady(k8sClient, workload)
+			if err != nil {
+				return err
+			}
+			if !ready {
+				notReady = append(notReady, workload)
+			}
+		}
+		if len(notReady) == 0 {
+			return nil
+		}
+		pending = notReady
+
+		log.Printf("Waiting for %d evicted workload(s) to be ready again\n", len(pending))
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("timed out after %s waiting for the workloads to be ready: %v", timeout, pending)
+		}
+		if pollInterval < remaining {
+			remaining = pollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
+// replicasOrDefault returns the replica count of the spec, which defaults to 1 when unset
+func replicasOrDefault(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
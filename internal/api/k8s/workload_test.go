@@ -0,0 +1,211 @@
+package k8s
+
+import (
+	"context"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsWorkloadReady(t *testing.T) {
+	replicas := int32(2)
+	isController := true
+	labels := map[string]string{"app": "app"}
+	selector := &metav1.LabelSelector{MatchLabels: labels}
+	terminatingPod := readyPod("app-2", labels)
+	terminatingPod.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+
+	tests := []struct {
+		name     string
+		workload WorkloadRef
+		objects  []runtime.Object
+		want     bool
+	}{
+		{
+			name:     "when all the replicas of the deployment are ready",
+			workload: WorkloadRef{Kind: "Deployment", Namespace: "default", Name: "app"},
+			objects: []runtime.Object{&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+				Status:     appsv1.DeploymentStatus{ReadyReplicas: 2},
+			}},
+			want: true,
+		},
+		{
+			name:     "when the replicaset is owned by a deployment which is not ready yet",
+			workload: WorkloadRef{Kind: "ReplicaSet", Namespace: "default", Name: "app-hash"},
+			objects: []runtime.Object{
+				&appsv1.ReplicaSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "app-hash", Namespace: "default",
+						OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "app", Controller: &isController}}},
+					Spec:   appsv1.ReplicaSetSpec{Replicas: &replicas},
+					Status: appsv1.ReplicaSetStatus{ReadyReplicas: 2},
+				},
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+					Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+					Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+				},
+			},
+			want: false,
+		},
+		{
+			name:     "when the statefulset is not ready",
+			workload: WorkloadRef{Kind: "StatefulSet", Namespace: "default", Name: "db"},
+			objects: []runtime.Object{&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+				Status:     appsv1.StatefulSetStatus{ReadyReplicas: 1},
+			}},
+			want: false,
+		},
+		{
+			name:     "when the statefulset status was not updated for its last generation yet",
+			workload: WorkloadRef{Kind: "StatefulSet", Namespace: "default", Name: "db"},
+			objects: []runtime.Object{&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 2},
+				Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+				Status:     appsv1.StatefulSetStatus{ObservedGeneration: 1, ReadyReplicas: 2},
+			}},
+			want: false,
+		},
+		{
+			name:     "when the deployment status still counts an evicted pod which is not replaced yet",
+			workload: WorkloadRef{Kind: "Deployment", Namespace: "default", Name: "app"},
+			objects: []runtime.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+					Spec:       appsv1.DeploymentSpec{Replicas: &replicas, Selector: selector},
+					Status:     appsv1.DeploymentStatus{ReadyReplicas: 2},
+				},
+				readyPod("app-1", labels), notReadyPod("app-3", labels),
+			},
+			want: false,
+		},
+		{
+			name:     "when the deployment status still counts an evicted pod which is terminating",
+			workload: WorkloadRef{Kind: "Deployment", Namespace: "default", Name: "app"},
+			objects: []runtime.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+					Spec:       appsv1.DeploymentSpec{Replicas: &replicas, Selector: selector},
+					Status:     appsv1.DeploymentStatus{ReadyReplicas: 2},
+				},
+				readyPod("app-1", labels), terminatingPod,
+			},
+			want: false,
+		},
+		{
+			name:     "when the evicted pod of the deployment is replaced by a ready pod",
+			workload: WorkloadRef{Kind: "Deployment", Namespace: "default", Name: "app"},
+			objects: []runtime.Object{
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+					Spec:       appsv1.DeploymentSpec{Replicas: &replicas, Selector: selector},
+					Status:     appsv1.DeploymentStatus{ReadyReplicas: 2},
+				},
+				readyPod("app-1", labels), readyPod("app-3", labels), readyPod("other", map[string]string{"app": "other"}),
+			},
+			want: true,
+		},
+		{
+			name:     "when the workload doesn't exist anymore",
+			workload: WorkloadRef{Kind: "Deployment", Namespace: "default", Name: "app"},
+			want:     true,
+		},
+		{
+			name:     "when the workload is of a kind which is not waited for",
+			workload: WorkloadRef{Kind: "Job", Namespace: "default", Name: "job"},
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.objects...)
+
+			got, err := IsWorkloadReady(client, tt.workload)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func readyPod(name string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+			{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+		}},
+	}
+}
+
+func notReadyPod(name string, labels map[string]string) *corev1.Pod {
+	pod := readyPod(name, labels)
+	pod.Status.Conditions[0].Status = corev1.ConditionFalse
+	return pod
+}
+
+func TestWaitForWorkloadsReady(t *testing.T) {
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+	client := fake.NewSimpleClientset(deployment)
+
+	err := WaitForWorkloadsReady(client, []WorkloadRef{{Kind: "Deployment", Namespace: "default", Name: "app"}},
+		10*time.Millisecond, time.Millisecond)
+
+	assert.NotNil(t, err)
+}
+
+func TestWaitForWorkloadsReadyWaitsForTheEvictedPodsToBeReplaced(t *testing.T) {
+	replicas := int32(1)
+	labels := map[string]string{"app": "app"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas, Selector: &metav1.LabelSelector{MatchLabels: labels}},
+		// the status still counts the evicted pod, which the controller has not observed the deletion of yet
+		Status: appsv1.DeploymentStatus{ReadyReplicas: 1},
+	}
+	client := fake.NewSimpleClientset(deployment, notReadyPod("app-2", labels))
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = client.CoreV1().Pods("default").UpdateStatus(context.TODO(), readyPod("app-2", labels), metav1.UpdateOptions{})
+	}()
+
+	start := time.Now()
+	err := WaitForWorkloadsReady(client, []WorkloadRef{{Kind: "Deployment", Namespace: "default", Name: "app"}},
+		time.Second, time.Millisecond)
+
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+}
+
+func TestEvictedWorkloads(t *testing.T) {
+	app := WorkloadRef{Kind: "ReplicaSet", Namespace: "default", Name: "app-hash"}
+	db := WorkloadRef{Kind: "StatefulSet", Namespace: "default", Name: "db"}
+	node := WorkloadRef{Kind: "DaemonSet", Namespace: "kube-system", Name: "aws-node"}
+
+	results := []NodeDrainResult{
+		{NodeName: "node-1", Pods: []PodEvictionResult{
+			{Name: "app-hash-1", Status: PodEvicted, Owner: &app},
+			{Name: "aws-node-1", Status: PodSkippedDaemonSet, Owner: &node},
+			{Name: "bare-pod", Status: PodEvicted},
+		}},
+		{NodeName: "node-2", Pods: []PodEvictionResult{
+			{Name: "app-hash-2", Status: PodEvicted, Owner: &app},
+			{Name: "db-0", Status: PodDeleted, Owner: &db},
+		}},
+	}
+
+	assert.Equal(t, []WorkloadRef{app, db}, EvictedWorkloads(results))
+}
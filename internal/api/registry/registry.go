@@ -0,0 +1,246 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// dockerHubRegistry is the host serving the distribution API of Docker Hub, the images named without a registry are
+	// pulled from
+	dockerHubRegistry = "registry-1.docker.io"
+	// digestHeader is the header carrying the digest of the manifest returned by the registry
+	digestHeader = "Docker-Content-Digest"
+)
+
+// manifestMediaTypes are the manifests accepted from the registry, the manifest lists and indexes first so that the
+// digest of a multi-arch image is the one of the image rather than the one of a single platform
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+type DigestResolverInterface interface {
+	ResolveDigest(ctx context.Context, registry, repository, tag string) (string, error)
+}
+
+// CredentialsFunc returns the username and password to authenticate against the registry with, an empty username
+// meaning anonymous access
+type CredentialsFunc func(ctx context.Context, registry string) (username, password string, err error)
+
+// Client resolves the tags of images to their digest using the distribution v2 API of OCI registries
+type Client struct {
+	HTTPClient  *http.Client
+	Credentials CredentialsFunc
+}
+
+// ResolveDigest returns the digest of the manifest the tag of the repository points to in the registry, the registry
+// being Docker Hub when empty
+func (c *Client) ResolveDigest(ctx context.Context, registry, repository, tag string) (string, error) {
+	registry, repository = normalize(registry, repository)
+	manifestUrl := fmt.Sprintf("https://%s/v2/%s/manifests/%s", registry, repository, tag)
+
+	response, err := c.manifestRequest(ctx, http.MethodHead, manifestUrl, registry, repository)
+	if err != nil {
+		return "", fmt.Errorf("error resolving the digest of %s/%s:%s: %v", registry, repository, tag, err)
+	}
+	response.Body.Close()
+	if digest := response.Header.Get(digestHeader); digest != "" {
+		return digest, nil
+	}
+
+	// the registries not returning the digest on HEAD requests have it computed from the manifest
+	response, err = c.manifestRequest(ctx, http.MethodGet, manifestUrl, registry, repository)
+	if err != nil {
+		return "", fmt.Errorf("error resolving the digest of %s/%s:%s: %v", registry, repository, tag, err)
+	}
+	defer response.Body.Close()
+	if digest := response.Header.Get(digestHeader); digest != "" {
+		return digest, nil
+	}
+	manifest, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading the manifest of %s/%s:%s: %v", registry, repository, tag, err)
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(manifest)), nil
+}
+
+// manifestRequest requests the manifest, authenticating with the scheme the registry asks for when it refuses the
+// anonymous request
+func (c *Client) manifestRequest(ctx context.Context, method, manifestUrl, registry, repository string) (*http.Response, error) {
+	response, err := c.do(ctx, method, manifestUrl, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusUnauthorized {
+		response.Body.Close()
+		authorization, err := c.authorization(ctx, response.Header.Get("WWW-Authenticate"), registry, repository)
+		if err != nil {
+			return nil, err
+		}
+		response, err = c.do(ctx, method, manifestUrl, authorization)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("registry returned %s", response.Status)
+	}
+	return response, nil
+}
+
+func (c *Client) do(ctx context.Context, method, manifestUrl, authorization string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, manifestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	return c.httpClient().Do(request)
+}
+
+// authorization returns the Authorization header answering the challenge of the registry, a bearer token fetched from
+// the token service of the registry or the basic credentials
+func (c *Client) authorization(ctx context.Context, challenge, registry, repository string) (string, error) {
+	username, password, err := c.credentials(ctx, registry)
+	if err != nil {
+		return "", err
+	}
+
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" {
+			return "", fmt.Errorf("registry %s requires credentials", registry)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+	case "bearer":
+		token, err := c.token(ctx, params, repository, username, password)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	default:
+		return "", fmt.Errorf("registry %s asks for an unsupported authentication scheme: %s", registry, challenge)
+	}
+}
+
+// token fetches a pull token for the repository from the token service the registry points to
+func (c *Client) token(ctx context.Context, params map[string]string, repository, username, password string) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid token service realm %s", params["realm"])
+	}
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", repository))
+	realm.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		request.SetBasicAuth(username, password)
+	}
+	response, err := c.httpClient().Do(request)
+	if err != nil {
+		return "", fmt.Errorf("error fetching a token from %s: %v", realm.Host, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error fetching a token from %s: token service returned %s", realm.Host, response.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("error decoding the token returned by %s: %v", realm.Host, err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token returned by %s", realm.Host)
+}
+
+func (c *Client) credentials(ctx context.Context, registry string) (string, string, error) {
+	if c.Credentials == nil {
+		return "", "", nil
+	}
+	username, password, err := c.Credentials(ctx, registry)
+	if err != nil {
+		return "", "", fmt.Errorf("error getting the credentials of registry %s: %v", registry, err)
+	}
+	return username, password, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// normalize returns the host serving the images named without a registry, or named after Docker Hub, along with the
+// library/ namespace of the official images named without one
+func normalize(registry, repository string) (string, string) {
+	if registry != "" && registry != "docker.io" && registry != "index.docker.io" {
+		return registry, repository
+	}
+	if !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+	return dockerHubRegistry, repository
+}
+
+// parseChallenge splits a WWW-Authenticate header of the form Scheme key="value",key="value" into its scheme and
+// parameters
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	challenge = strings.TrimSpace(challenge)
+	index := strings.Index(challenge, " ")
+	if index == -1 {
+		return challenge, params
+	}
+	scheme, rest := challenge[:index], challenge[index+1:]
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		index = strings.Index(rest, "=")
+		if index == -1 {
+			return scheme, params
+		}
+		key, value := strings.ToLower(strings.TrimSpace(rest[:index])), rest[index+1:]
+		if strings.HasPrefix(value, `"`) {
+			value = value[1:]
+			index = strings.Index(value, `"`)
+		} else {
+			index = strings.Index(value, ",")
+		}
+		if index == -1 {
+			params[key] = value
+			return scheme, params
+		}
+		params[key], rest = value[:index], value[index+1:]
+	}
+}
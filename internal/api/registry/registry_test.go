@@ -0,0 +1,159 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testDigest = "sha256:4bcbd1e1b6b7b9ed25e2cc5d2c4a1b7a8ffb2f5c8d7e3d5a6b1c9e0f7a2d3c4b"
+	testToken  = "pull-token"
+)
+
+var testManifest = []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[]}`)
+
+// fakeRegistry is an in-process stand-in for a registry serving the manifest of the v1 tag of team/repo, behind the
+// authentication scheme passed
+type fakeRegistry struct {
+	// auth is one of none, basic or bearer
+	auth string
+	// digestOnHead is false for the registries only returning the manifest, and no digest header, on GET requests
+	digestOnHead bool
+	scopes       []string
+}
+
+func (f *fakeRegistry) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		f.scopes = append(f.scopes, r.URL.Query().Get("scope"))
+		assert.Equal(t, "fake-registry", r.URL.Query().Get("service"))
+		fmt.Fprintf(w, `{"token":"%s"}`, testToken)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json")
+		switch f.auth {
+		case "basic":
+			if username, password, ok := r.BasicAuth(); !ok || username != "AWS" || password != "secret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="fake-registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "bearer":
+			if r.Header.Get("Authorization") != "Bearer "+testToken {
+				w.Header().Set("WWW-Authenticate",
+					fmt.Sprintf(`Bearer realm="https://%s/token",service="fake-registry",scope="repository:team/repo:pull"`, r.Host))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		if r.URL.Path != "/v2/team/repo/manifests/v1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if f.digestOnHead {
+			w.Header().Set(digestHeader, testDigest)
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(testManifest)
+		}
+	})
+	return mux
+}
+
+func TestClient_ResolveDigest(t *testing.T) {
+	ecrCredentials := func(ctx context.Context, registry string) (string, string, error) {
+		return "AWS", "secret", nil
+	}
+	tests := []struct {
+		name        string
+		registry    fakeRegistry
+		credentials CredentialsFunc
+		repository  string
+		tag         string
+		want        string
+		err         string
+	}{
+		{"when the registry allows anonymous pulls and returns the digest on HEAD requests",
+			fakeRegistry{auth: "none", digestOnHead: true}, nil, "team/repo", "v1", testDigest, ""},
+		{"when the registry only returns the manifest the digest is computed from it",
+			fakeRegistry{auth: "none"}, nil, "team/repo", "v1", fmt.Sprintf("sha256:%x", sha256.Sum256(testManifest)), ""},
+		{"when the registry asks for a bearer token it is fetched anonymously",
+			fakeRegistry{auth: "bearer", digestOnHead: true}, nil, "team/repo", "v1", testDigest, ""},
+		{"when the registry asks for basic credentials they are passed",
+			fakeRegistry{auth: "basic", digestOnHead: true}, ecrCredentials, "team/repo", "v1", testDigest, ""},
+		{"when the registry asks for basic credentials and there are none",
+			fakeRegistry{auth: "basic", digestOnHead: true}, nil, "team/repo", "v1", "", "requires credentials"},
+		{"when the credentials can't be fetched",
+			fakeRegistry{auth: "basic", digestOnHead: true},
+			func(ctx context.Context, registry string) (string, string, error) {
+				return "", "", errors.New("no aws profile")
+			},
+			"team/repo", "v1", "", "error getting the credentials of registry"},
+		{"when the tag doesn't exist",
+			fakeRegistry{auth: "bearer", digestOnHead: true}, nil, "team/repo", "v2", "", "registry returned 404 Not Found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(tt.registry.handler(t))
+			defer server.Close()
+			host := strings.TrimPrefix(server.URL, "https://")
+
+			client := &Client{HTTPClient: server.Client(), Credentials: tt.credentials}
+			got, err := client.ResolveDigest(context.TODO(), host, tt.repository, tt.tag)
+			assert.Equal(t, tt.want, got)
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+			}
+			if tt.registry.auth == "bearer" {
+				assert.Contains(t, tt.registry.scopes, "repository:team/repo:pull")
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name           string
+		registry       string
+		repository     string
+		wantRegistry   string
+		wantRepository string
+	}{
+		{"when the image has no registry and no namespace", "", "nginx", "registry-1.docker.io", "library/nginx"},
+		{"when the image has no registry", "", "bitnami/external-dns", "registry-1.docker.io", "bitnami/external-dns"},
+		{"when the image is named after docker hub", "docker.io", "nginx", "registry-1.docker.io", "library/nginx"},
+		{"when the image has a registry", "registry:5000", "repo", "registry:5000", "repo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, repository := normalize(tt.registry, tt.repository)
+			assert.Equal(t, tt.wantRegistry, registry)
+			assert.Equal(t, tt.wantRepository, repository)
+		})
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/nginx:pull"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/nginx:pull",
+	}, params)
+
+	scheme, params = parseChallenge(`Basic realm="https://602401143452.dkr.ecr.eu-west-1.amazonaws.com/",service="ecr.amazonaws.com"`)
+	assert.Equal(t, "Basic", scheme)
+	assert.Equal(t, "ecr.amazonaws.com", params["service"])
+}
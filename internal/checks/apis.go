@@ -0,0 +1,127 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DeprecatedAPI is an API version of a kind which is removed from a Kubernetes version
+type DeprecatedAPI struct {
+	GroupVersion string
+	Kind         string
+	// RemovedIn is the Kubernetes minor version the API version is no longer served from, eg: 1.22
+	RemovedIn string
+	// Replacement is the group version to move the manifests of the kind to
+	Replacement string
+}
+
+// RemovedAPIs are the API versions removed from Kubernetes, as listed in the deprecated API migration guide
+var RemovedAPIs = []DeprecatedAPI{
+	{"extensions/v1beta1", "DaemonSet", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "Deployment", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "ReplicaSet", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "NetworkPolicy", "1.16", "networking.k8s.io/v1"},
+	{"apps/v1beta1", "Deployment", "1.16", "apps/v1"},
+	{"apps/v1beta1", "StatefulSet", "1.16", "apps/v1"},
+	{"apps/v1beta1", "ReplicaSet", "1.16", "apps/v1"},
+	{"apps/v1beta2", "DaemonSet", "1.16", "apps/v1"},
+	{"apps/v1beta2", "Deployment", "1.16", "apps/v1"},
+	{"apps/v1beta2", "StatefulSet", "1.16", "apps/v1"},
+	{"apps/v1beta2", "ReplicaSet", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "Ingress", "1.22", "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", "Ingress", "1.22", "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", "IngressClass", "1.22", "networking.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "MutatingWebhookConfiguration", "1.22", "admissionregistration.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "ValidatingWebhookConfiguration", "1.22", "admissionregistration.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", "1.22", "apiextensions.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", "APIService", "1.22", "apiregistration.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", "CertificateSigningRequest", "1.22", "certificates.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", "Lease", "1.22", "coordination.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRole", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRoleBinding", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "Role", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "RoleBinding", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", "PriorityClass", "1.22", "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSIDriver", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSINode", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "StorageClass", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "VolumeAttachment", "1.22", "storage.k8s.io/v1"},
+	{"batch/v1beta1", "CronJob", "1.25", "batch/v1"},
+	{"discovery.k8s.io/v1beta1", "EndpointSlice", "1.25", "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", "Event", "1.25", "events.k8s.io/v1"},
+	{"autoscaling/v2beta1", "HorizontalPodAutoscaler", "1.25", "autoscaling/v2"},
+	{"policy/v1beta1", "PodDisruptionBudget", "1.25", "policy/v1"},
+	{"policy/v1beta1", "PodSecurityPolicy", "1.25", "none, use Pod Security Admission"},
+	{"node.k8s.io/v1beta1", "RuntimeClass", "1.25", "node.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "FlowSchema", "1.26", "flowcontrol.apiserver.k8s.io/v1beta3"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "PriorityLevelConfiguration", "1.26", "flowcontrol.apiserver.k8s.io/v1beta3"},
+	{"autoscaling/v2beta2", "HorizontalPodAutoscaler", "1.26", "autoscaling/v2"},
+	{"storage.k8s.io/v1beta1", "CSIStorageCapacity", "1.27", "storage.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "FlowSchema", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "PriorityLevelConfiguration", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "FlowSchema", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "PriorityLevelConfiguration", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// RemovedBetween returns the API versions removed after the current Kubernetes version, up to and including the target
+// version
+func RemovedBetween(currentVersion, targetVersion string) ([]DeprecatedAPI, error) {
+	currentMajor, currentMinor, ok := minorVersion(currentVersion)
+	if !ok {
+		return nil, fmt.Errorf("invalid Kubernetes version %s", currentVersion)
+	}
+	targetMajor, targetMinor, ok := minorVersion(targetVersion)
+	if !ok {
+		return nil, fmt.Errorf("invalid Kubernetes version %s", targetVersion)
+	}
+
+	var removed []DeprecatedAPI
+	for _, api := range RemovedAPIs {
+		major, minor, _ := minorVersion(api.RemovedIn)
+		if major == currentMajor && major == targetMajor && minor > currentMinor && minor <= targetMinor {
+			removed = append(removed, api)
+		}
+	}
+	return removed, nil
+}
+
+// DeprecatedAPIs fails when objects of the cluster are still written through API versions removed by the target
+// version, as their manifests or the clients writing them would break once the cluster is upgraded
+type DeprecatedAPIs struct{}
+
+func (DeprecatedAPIs) Name() string {
+	return "deprecated-apis"
+}
+
+func (DeprecatedAPIs) Run(cluster Cluster) Result {
+	if cluster.DynamicClient == nil {
+		return failed(errors.New("no dynamic client to list the objects of the cluster with"))
+	}
+	objects, targetVersion, err := ScanDeprecatedAPIs(cluster.K8sClient, cluster.DynamicClient, cluster.TargetVersion)
+	if err != nil {
+		return failed(err)
+	}
+
+	if len(objects) > 0 {
+		var details []string
+		for _, object := range objects {
+			details = append(details, object.String())
+		}
+		return Result{Status: Fail, Message: fmt.Sprintf("%d objects use API versions removed by %s", len(objects), targetVersion),
+			Details: details}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("no objects use API versions removed by %s", targetVersion)}
+}
+
+// targetVersionOf returns the target version passed, or the minor version following the one of the API server when
+// none is passed
+func targetVersionOf(serverVersion, targetVersion string) (string, error) {
+	if targetVersion != "" {
+		return targetVersion, nil
+	}
+	major, minor, ok := minorVersion(serverVersion)
+	if !ok {
+		return "", fmt.Errorf("invalid version %s of the API server", serverVersion)
+	}
+	return fmt.Sprintf("%d.%d", major, minor+1), nil
+}
@@ -0,0 +1,67 @@
+package checks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemovedBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		target  string
+		want    []string
+		err     error
+	}{
+		{"when no API versions are removed", "1.22", "1.24", nil, nil},
+		{"when API versions are removed by the target version", "v1.25.16-eks-8cb36c9", "1.27",
+			[]string{"flowcontrol.apiserver.k8s.io/v1beta1 FlowSchema", "flowcontrol.apiserver.k8s.io/v1beta1 PriorityLevelConfiguration",
+				"autoscaling/v2beta2 HorizontalPodAutoscaler", "storage.k8s.io/v1beta1 CSIStorageCapacity"}, nil},
+		{"when the current version is invalid", "latest", "1.22", nil, errors.New("invalid Kubernetes version latest")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed, err := RemovedBetween(tt.current, tt.target)
+
+			var got []string
+			for _, api := range removed {
+				got = append(got, api.GroupVersion+" "+api.Kind)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestDeprecatedAPIs_Run(t *testing.T) {
+	tests := []struct {
+		name          string
+		targetVersion string
+		want          Result
+	}{
+		{"when no target version is passed, the next minor version is checked", "",
+			Result{Status: Fail, Message: "1 objects use API versions removed by 1.22", Details: []string{
+				"networking.k8s.io/v1beta1 Ingress default/legacy is removed in 1.22, move to networking.k8s.io/v1, found in last-applied-configuration",
+			}}},
+		{"when the target version removes none of the API versions used", "1.21",
+			Result{Status: Pass, Message: "no objects use API versions removed by 1.21"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, dynamicClient := testScanClients(testIngress("legacy", "networking.k8s.io/v1beta1", nil))
+
+			got := DeprecatedAPIs{}.Run(Cluster{K8sClient: client, DynamicClient: dynamicClient, TargetVersion: tt.targetVersion})
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("when there is no dynamic client", func(t *testing.T) {
+		got := DeprecatedAPIs{}.Run(Cluster{K8sClient: testClient("v1.21.5-eks-bc4871b")})
+
+		assert.Equal(t, Result{Status: Fail,
+			Message: "the check could not run: no dynamic client to list the objects of the cluster with"}, got)
+	})
+}
@@ -0,0 +1,140 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"io"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"text/tabwriter"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	// Pass is the status of the checks finding nothing wrong
+	Pass Status = "pass"
+	// Warn is the status of the checks finding something to look at which doesn't block the upgrade
+	Warn Status = "warn"
+	// Fail is the status of the checks finding something the upgrade will break on, or which could not run
+	Fail Status = "fail"
+)
+
+// Formats are the output formats the report can be written in
+var Formats = []string{"table", "json"}
+
+// Cluster is the cluster the checks run against
+type Cluster struct {
+	Name      string
+	K8sClient kubernetes.Interface
+	// DynamicClient lists the objects of any API version, for the checks of the API versions the objects are written
+	// through
+	DynamicClient dynamic.Interface
+	// TargetVersion is the Kubernetes version the cluster is upgraded to, eg: 1.22, the checks depending on it use the
+	// next minor version of the control plane when empty
+	TargetVersion string
+}
+
+// Check is a readiness check of a cluster run before it is upgraded
+type Check interface {
+	Name() string
+	Run(cluster Cluster) Result
+}
+
+// Result is the outcome of a check, along with the details of what it found, eg: the nodes which are not ready
+type Result struct {
+	Check   string   `json:"check"`
+	Status  Status   `json:"status"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+// Report is the outcome of all the checks run against a cluster
+type Report struct {
+	Cluster string   `json:"cluster"`
+	Status  Status   `json:"status"`
+	Results []Result `json:"results"`
+}
+
+// DefaultChecks returns all the built-in checks
+func DefaultChecks() []Check {
+	return append(MutationChecks(), DeprecatedAPIs{})
+}
+
+// MutationChecks returns the built-in checks run before a command changes the cluster without upgrading it. The
+// deprecated-apis check is left out, as the API versions removed by the next Kubernetes version don't prevent draining
+// nodes or setting components on the current one
+func MutationChecks() []Check {
+	return []Check{
+		NodesReady{},
+		PendingPods{PendingFor: defaultPendingFor},
+		PodDisruptionBudgets{},
+		KubeletSkew{},
+	}
+}
+
+// Run runs the checks against the cluster one after the other, the status of the report being the worst status of the
+// checks
+func Run(cluster Cluster, checks []Check) Report {
+	report := Report{Cluster: cluster.Name, Status: Pass, Results: []Result{}}
+	for _, check := range checks {
+		result := check.Run(cluster)
+		result.Check = check.Name()
+		report.Results = append(report.Results, result)
+		if result.Status == Fail || (result.Status == Warn && report.Status == Pass) {
+			report.Status = result.Status
+		}
+	}
+	return report
+}
+
+// Write writes the report in the format passed, one of Formats
+func (r Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case "table":
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "CHECK\tSTATUS\tMESSAGE")
+		for _, result := range r.Results {
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", result.Check, result.Status, result.Message)
+			for _, detail := range result.Details {
+				fmt.Fprintf(writer, "\t\t  %s\n", detail)
+			}
+		}
+		return writer.Flush()
+	default:
+		return ValidateFormat(format)
+	}
+}
+
+// ValidateFormat returns an error if the format is not one of Formats
+func ValidateFormat(format string) error {
+	for _, valid := range Formats {
+		if format == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format %s, please pass one of %v", format, Formats)
+}
+
+// minorVersion returns the major and minor numbers of a version such as v1.21.14-eks-18ef993 or 1.21
+func minorVersion(version string) (int, int, bool) {
+	minor, ok := config.MinorVersion(version)
+	if !ok {
+		return 0, 0, false
+	}
+	var major, number int
+	if _, err := fmt.Sscanf(minor, "%d.%d", &major, &number); err != nil {
+		return 0, 0, false
+	}
+	return major, number, true
+}
+
+func failed(err error) Result {
+	return Result{Status: Fail, Message: fmt.Sprintf("the check could not run: %v", err)}
+}
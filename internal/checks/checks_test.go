@@ -0,0 +1,94 @@
+package checks
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubCheck struct {
+	name   string
+	result Result
+}
+
+func (s stubCheck) Name() string {
+	return s.name
+}
+
+func (s stubCheck) Run(Cluster) Result {
+	return s.result
+}
+
+func TestRun(t *testing.T) {
+	pass := stubCheck{"check-pass", Result{Status: Pass, Message: "all good"}}
+	warn := stubCheck{"check-warn", Result{Status: Warn, Message: "look at this", Details: []string{"detail"}}}
+	fail := stubCheck{"check-fail", failed(errors.New("boom"))}
+
+	tests := []struct {
+		name   string
+		checks []Check
+		want   Status
+	}{
+		{"when there are no checks", nil, Pass},
+		{"when all the checks pass", []Check{pass, pass}, Pass},
+		{"when a check warns", []Check{pass, warn}, Warn},
+		{"when a check fails, a later warning doesn't hide it", []Check{fail, warn, pass}, Fail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Run(Cluster{Name: "cluster1"}, tt.checks)
+
+			assert.Equal(t, "cluster1", report.Cluster)
+			assert.Equal(t, tt.want, report.Status)
+			assert.Len(t, report.Results, len(tt.checks))
+			for i, check := range tt.checks {
+				assert.Equal(t, check.Name(), report.Results[i].Check)
+			}
+		})
+	}
+}
+
+func TestMutationChecks(t *testing.T) {
+	var names []string
+	for _, check := range MutationChecks() {
+		names = append(names, check.Name())
+	}
+
+	assert.Equal(t, []string{"nodes-ready", "pending-pods", "pod-disruption-budgets", "kubelet-skew"}, names)
+	assert.Len(t, DefaultChecks(), len(names)+1)
+}
+
+func TestReport_Write(t *testing.T) {
+	report := Report{Cluster: "cluster1", Status: Warn, Results: []Result{
+		{Check: "nodes-ready", Status: Pass, Message: "all 2 nodes are ready"},
+		{Check: "pending-pods", Status: Warn, Message: "1 pods have been pending for more than 5m0s",
+			Details: []string{"pod default/app has been pending for 10m0s"}},
+	}}
+
+	t.Run("when the format is table", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		assert.Nil(t, report.Write(&buffer, "table"))
+		assert.Equal(t, `CHECK         STATUS  MESSAGE
+nodes-ready   pass    all 2 nodes are ready
+pending-pods  warn    1 pods have been pending for more than 5m0s
+                        pod default/app has been pending for 10m0s
+`, buffer.String())
+	})
+
+	t.Run("when the format is json", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		assert.Nil(t, report.Write(&buffer, "json"))
+		assert.JSONEq(t, `{"cluster": "cluster1", "status": "warn", "results": [
+			{"check": "nodes-ready", "status": "pass", "message": "all 2 nodes are ready"},
+			{"check": "pending-pods", "status": "warn", "message": "1 pods have been pending for more than 5m0s",
+			 "details": ["pod default/app has been pending for 10m0s"]}]}`, buffer.String())
+	})
+
+	t.Run("when the format is unknown", func(t *testing.T) {
+		assert.EqualError(t, report.Write(&bytes.Buffer{}, "yaml"), "invalid output format yaml, please pass one of [table json]")
+	})
+}
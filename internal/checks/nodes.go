@@ -0,0 +1,139 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+)
+
+// kubeletSkewWidenedMinor is the Kubernetes minor version from which the version skew policy lets a kubelet be 3 minor
+// versions older than the API server instead of 2
+const kubeletSkewWidenedMinor = 28
+
+// NodesReady fails when nodes are not ready, and warns when nodes are cordoned, as draining would move their pods to
+// the remaining nodes
+type NodesReady struct{}
+
+func (NodesReady) Name() string {
+	return "nodes-ready"
+}
+
+func (NodesReady) Run(cluster Cluster) Result {
+	nodes, err := cluster.K8sClient.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return failed(fmt.Errorf("error listing the nodes: %v", err))
+	}
+
+	var notReady, cordoned []string
+	for _, node := range nodes.Items {
+		if !isReady(node) {
+			notReady = append(notReady, fmt.Sprintf("node %s is not ready", node.Name))
+		} else if node.Spec.Unschedulable {
+			cordoned = append(cordoned, fmt.Sprintf("node %s is cordoned", node.Name))
+		}
+	}
+
+	switch {
+	case len(notReady) > 0:
+		return Result{Status: Fail, Message: fmt.Sprintf("%d of %d nodes are not ready", len(notReady), len(nodes.Items)),
+			Details: append(notReady, cordoned...)}
+	case len(cordoned) > 0:
+		return Result{Status: Warn, Message: fmt.Sprintf("%d of %d nodes are cordoned", len(cordoned), len(nodes.Items)),
+			Details: cordoned}
+	default:
+		return Result{Status: Pass, Message: fmt.Sprintf("all %d nodes are ready", len(nodes.Items))}
+	}
+}
+
+func isReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// KubeletSkew fails when kubelets are newer than the API server, or more minor versions older than the target version
+// of the cluster than MaxKubeletSkew allows for it, as they would not be supported once the control plane is upgraded
+type KubeletSkew struct{}
+
+func (KubeletSkew) Name() string {
+	return "kubelet-skew"
+}
+
+func (KubeletSkew) Run(cluster Cluster) Result {
+	serverVersion, err := k8s.GetServerVersion(cluster.K8sClient)
+	if err != nil {
+		return failed(err)
+	}
+	serverMajor, serverMinor, ok := minorVersion(serverVersion)
+	if !ok {
+		return failed(fmt.Errorf("invalid version %s of the API server", serverVersion))
+	}
+	targetMajor, targetMinor := serverMajor, serverMinor
+	if cluster.TargetVersion != "" {
+		if targetMajor, targetMinor, ok = minorVersion(cluster.TargetVersion); !ok {
+			return failed(fmt.Errorf("invalid Kubernetes version %s", cluster.TargetVersion))
+		}
+	}
+
+	versions, err := k8s.GetNodeKubeletVersions(cluster.K8sClient)
+	if err != nil {
+		return failed(err)
+	}
+	var skewed []string
+	for node, version := range versions {
+		if violation := KubeletSkewViolation(serverVersion, cluster.TargetVersion, version); violation != "" {
+			skewed = append(skewed, fmt.Sprintf("node %s runs kubelet %s which %s", node, version, violation))
+		}
+	}
+	sort.Strings(skewed)
+
+	if len(skewed) > 0 {
+		return Result{Status: Fail, Message: fmt.Sprintf("%d of %d kubelets are outside of the supported version skew",
+			len(skewed), len(versions)), Details: skewed}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("all %d kubelets are within %d minor versions of %d.%d",
+		len(versions), MaxKubeletSkew(targetMajor, targetMinor), targetMajor, targetMinor)}
+}
+
+// KubeletSkewViolation returns why the kubelet version is outside of the version skew supported by the API server once
+// the cluster runs the target version, the version of the API server when empty, eg: is newer than the API server 1.21.
+// It returns an empty string when the kubelet version is supported
+func KubeletSkewViolation(serverVersion, targetVersion, kubeletVersion string) string {
+	serverMajor, serverMinor, ok := minorVersion(serverVersion)
+	if !ok {
+		return fmt.Sprintf("can't be compared with the invalid version %s of the API server", serverVersion)
+	}
+	targetMajor, targetMinor := serverMajor, serverMinor
+	if targetVersion != "" {
+		if targetMajor, targetMinor, ok = minorVersion(targetVersion); !ok {
+			return fmt.Sprintf("can't be compared with the invalid Kubernetes version %s", targetVersion)
+		}
+	}
+
+	major, minor, ok := minorVersion(kubeletVersion)
+	switch {
+	case !ok:
+		return "is not a valid version"
+	case major != serverMajor || minor > serverMinor:
+		return fmt.Sprintf("is newer than the API server %d.%d", serverMajor, serverMinor)
+	case major != targetMajor || targetMinor-minor > MaxKubeletSkew(targetMajor, targetMinor):
+		return fmt.Sprintf("is more than %d minor versions older than %d.%d", MaxKubeletSkew(targetMajor, targetMinor),
+			targetMajor, targetMinor)
+	}
+	return ""
+}
+
+// MaxKubeletSkew returns the number of minor versions a kubelet can be older than an API server running the version
+// passed, as per the Kubernetes version skew policy: 3 from 1.28 on and 2 before
+func MaxKubeletSkew(major, minor int) int {
+	if major > 1 || minor >= kubeletSkewWidenedMinor {
+		return 3
+	}
+	return 2
+}
@@ -0,0 +1,125 @@
+package checks
+
+import (
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakeDiscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testNode(name, kubeletVersion string, ready corev1.ConditionStatus, unschedulable bool) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: ready}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: kubeletVersion},
+		},
+	}
+}
+
+func testClient(serverVersion string, nodes ...*corev1.Node) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	for _, node := range nodes {
+		_ = client.Tracker().Add(node)
+	}
+	client.Discovery().(*fakeDiscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: serverVersion}
+	return client
+}
+
+func TestNodesReady_Run(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []*corev1.Node
+		want  Result
+	}{
+		{"when all the nodes are ready",
+			[]*corev1.Node{testNode("node-1", "", corev1.ConditionTrue, false), testNode("node-2", "", corev1.ConditionTrue, false)},
+			Result{Status: Pass, Message: "all 2 nodes are ready"}},
+		{"when a node is cordoned",
+			[]*corev1.Node{testNode("node-1", "", corev1.ConditionTrue, true), testNode("node-2", "", corev1.ConditionTrue, false)},
+			Result{Status: Warn, Message: "1 of 2 nodes are cordoned", Details: []string{"node node-1 is cordoned"}}},
+		{"when a node is not ready",
+			[]*corev1.Node{testNode("node-1", "", corev1.ConditionTrue, true), testNode("node-2", "", corev1.ConditionUnknown, false)},
+			Result{Status: Fail, Message: "1 of 2 nodes are not ready",
+				Details: []string{"node node-2 is not ready", "node node-1 is cordoned"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NodesReady{}.Run(Cluster{K8sClient: testClient("v1.21.5", tt.nodes...)}))
+		})
+	}
+}
+
+func TestKubeletSkew_Run(t *testing.T) {
+	tests := []struct {
+		name          string
+		targetVersion string
+		kubelets      []string
+		want          Result
+	}{
+		{"when all the kubelets are within the skew of the API server", "", []string{"v1.21.5-eks-9017834", "v1.19.15-eks-9c63c4"},
+			Result{Status: Pass, Message: "all 2 kubelets are within 2 minor versions of 1.21"}},
+		{"when a kubelet would be out of the skew of the target version", "1.22", []string{"v1.21.5-eks-9017834", "v1.19.15-eks-9c63c4"},
+			Result{Status: Fail, Message: "1 of 2 kubelets are outside of the supported version skew",
+				Details: []string{"node node-2 runs kubelet v1.19.15-eks-9c63c4 which is more than 2 minor versions older than 1.22"}}},
+		{"when a kubelet is newer than the API server", "", []string{"v1.22.17-eks-0a21954"},
+			Result{Status: Fail, Message: "1 of 1 kubelets are outside of the supported version skew",
+				Details: []string{"node node-1 runs kubelet v1.22.17-eks-0a21954 which is newer than the API server 1.21"}}},
+		{"when the target version is invalid", "latest", []string{"v1.21.5-eks-9017834"},
+			Result{Status: Fail, Message: "the check could not run: invalid Kubernetes version latest"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var nodes []*corev1.Node
+			for i, kubelet := range tt.kubelets {
+				nodes = append(nodes, testNode(fmt.Sprintf("node-%d", i+1), kubelet, corev1.ConditionTrue, false))
+			}
+			client := testClient("v1.21.5-eks-bc4871b", nodes...)
+
+			assert.Equal(t, tt.want, KubeletSkew{}.Run(Cluster{K8sClient: client, TargetVersion: tt.targetVersion}))
+		})
+	}
+}
+
+func TestKubeletSkewViolation(t *testing.T) {
+	tests := []struct {
+		name           string
+		serverVersion  string
+		targetVersion  string
+		kubeletVersion string
+		want           string
+	}{
+		{"when the kubelet runs the version of the API server", "v1.22.17-eks-7f3c7ba", "", "v1.22.17-eks-0a21954", ""},
+		{"when the kubelet is 2 minor versions older than the API server", "v1.22.17-eks-7f3c7ba", "", "v1.20.15-eks-ba74326", ""},
+		{"when the kubelet is 3 minor versions older than the API server", "v1.22.17-eks-7f3c7ba", "", "v1.19.15-eks-9c63c4",
+			"is more than 2 minor versions older than 1.22"},
+		{"when the kubelet is newer than the API server", "v1.22.17-eks-7f3c7ba", "", "v1.23.17-eks-8ccc7ba",
+			"is newer than the API server 1.22"},
+		{"when the kubelet would be too old for the target version", "v1.22.17-eks-7f3c7ba", "1.23", "v1.20.15-eks-ba74326",
+			"is more than 2 minor versions older than 1.23"},
+		{"when the kubelet is 3 minor versions older than an API server from 1.28 on", "v1.28.5-eks-5e0fdde", "", "v1.25.16-eks-5e0fdde", ""},
+		{"when the kubelet is 4 minor versions older than an API server from 1.28 on", "v1.28.5-eks-5e0fdde", "", "v1.24.17-eks-5e0fdde",
+			"is more than 3 minor versions older than 1.28"},
+		{"when the kubelet would be 3 minor versions older than the target version from 1.28 on", "v1.27.9-eks-5e0fdde", "1.28",
+			"v1.25.16-eks-5e0fdde", ""},
+		{"when the kubelet version is invalid", "v1.22.17-eks-7f3c7ba", "", "unknown", "is not a valid version"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, KubeletSkewViolation(tt.serverVersion, tt.targetVersion, tt.kubeletVersion))
+		})
+	}
+}
+
+func TestMaxKubeletSkew(t *testing.T) {
+	assert.Equal(t, 2, MaxKubeletSkew(1, 22))
+	assert.Equal(t, 2, MaxKubeletSkew(1, 27))
+	assert.Equal(t, 3, MaxKubeletSkew(1, 28))
+	assert.Equal(t, 3, MaxKubeletSkew(1, 30))
+}
@@ -0,0 +1,38 @@
+package checks
+
+import (
+	"fmt"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+)
+
+// PodDisruptionBudgets fails when PodDisruptionBudgets don't allow any disruptions, as draining the nodes running the
+// pods they select would never complete
+type PodDisruptionBudgets struct{}
+
+func (PodDisruptionBudgets) Name() string {
+	return "pod-disruption-budgets"
+}
+
+func (PodDisruptionBudgets) Run(cluster Cluster) Result {
+	pdbs, err := k8s.ListPodDisruptionBudgets(cluster.K8sClient, metav1.NamespaceAll)
+	if err != nil {
+		return failed(err)
+	}
+
+	var blocking []string
+	for _, pdb := range pdbs {
+		if pdb.Status.DisruptionsAllowed == 0 && pdb.Status.ExpectedPods > 0 {
+			blocking = append(blocking, fmt.Sprintf("PodDisruptionBudget %s/%s allows 0 disruptions of its %d pods",
+				pdb.Namespace, pdb.Name, pdb.Status.ExpectedPods))
+		}
+	}
+	sort.Strings(blocking)
+
+	if len(blocking) > 0 {
+		return Result{Status: Fail, Message: fmt.Sprintf("%d PodDisruptionBudgets don't allow any disruptions", len(blocking)),
+			Details: blocking}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("all %d PodDisruptionBudgets allow disruptions", len(pdbs))}
+}
@@ -0,0 +1,54 @@
+package checks
+
+import (
+	policyv1 "k8s.io/api/policy/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPDB(name string, disruptionsAllowed, expectedPods int32) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed, ExpectedPods: expectedPods},
+	}
+}
+
+func TestPodDisruptionBudgets_Run(t *testing.T) {
+	tests := []struct {
+		name string
+		pdbs []*policyv1.PodDisruptionBudget
+		want Result
+	}{
+		{"when all the PodDisruptionBudgets allow disruptions", []*policyv1.PodDisruptionBudget{testPDB("app", 1, 3)},
+			Result{Status: Pass, Message: "all 1 PodDisruptionBudgets allow disruptions"}},
+		{"when a PodDisruptionBudget selects no pods", []*policyv1.PodDisruptionBudget{testPDB("app", 0, 0)},
+			Result{Status: Pass, Message: "all 1 PodDisruptionBudgets allow disruptions"}},
+		{"when a PodDisruptionBudget allows no disruptions",
+			[]*policyv1.PodDisruptionBudget{testPDB("app", 1, 3), testPDB("database", 0, 1)},
+			Result{Status: Fail, Message: "1 PodDisruptionBudgets don't allow any disruptions",
+				Details: []string{"PodDisruptionBudget default/database allows 0 disruptions of its 1 pods"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the API server serves policy/v1 only, as from Kubernetes 1.25 on
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("list", "poddisruptionbudgets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetResource().Version == "v1" {
+					return false, nil, nil
+				}
+				return true, nil, k8sErrors.NewNotFound(action.GetResource().GroupResource(), "")
+			})
+			for _, pdb := range tt.pdbs {
+				_ = client.Tracker().Add(pdb)
+			}
+
+			assert.Equal(t, tt.want, PodDisruptionBudgets{}.Run(Cluster{K8sClient: client}))
+		})
+	}
+}
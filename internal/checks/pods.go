@@ -0,0 +1,57 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sort"
+	"time"
+)
+
+const defaultPendingFor = 5 * time.Minute
+
+// PendingPods warns when pods have been pending for longer than PendingFor, as the cluster is then likely short of
+// capacity to reschedule the pods of drained nodes
+type PendingPods struct {
+	PendingFor time.Duration
+	// now returns the current time, time.Now when nil
+	now func() time.Time
+}
+
+func (PendingPods) Name() string {
+	return "pending-pods"
+}
+
+func (p PendingPods) Run(cluster Cluster) Result {
+	pods, err := cluster.K8sClient.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("status.phase", string(corev1.PodPending)).String(),
+	})
+	if err != nil {
+		return failed(fmt.Errorf("error listing the pending pods: %v", err))
+	}
+
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	var stuck []string
+	for _, pod := range pods.Items {
+		// the fake clientset ignores field selectors
+		if pod.Status.Phase != corev1.PodPending {
+			continue
+		}
+		if pendingFor := now().Sub(pod.CreationTimestamp.Time); pendingFor > p.PendingFor {
+			stuck = append(stuck, fmt.Sprintf("pod %s/%s has been pending for %s", pod.Namespace, pod.Name,
+				pendingFor.Round(time.Second)))
+		}
+	}
+	sort.Strings(stuck)
+
+	if len(stuck) > 0 {
+		return Result{Status: Warn, Message: fmt.Sprintf("%d pods have been pending for more than %s", len(stuck), p.PendingFor),
+			Details: stuck}
+	}
+	return Result{Status: Pass, Message: fmt.Sprintf("no pods have been pending for more than %s", p.PendingFor)}
+}
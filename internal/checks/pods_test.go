@@ -0,0 +1,49 @@
+package checks
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPod(name string, phase corev1.PodPhase, createdAt time.Time) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(createdAt)},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestPendingPods_Run(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		pods []*corev1.Pod
+		want Result
+	}{
+		{"when no pods are pending", []*corev1.Pod{testPod("app-1", corev1.PodRunning, now.Add(-time.Hour))},
+			Result{Status: Pass, Message: "no pods have been pending for more than 5m0s"}},
+		{"when pods were created pending recently", []*corev1.Pod{testPod("app-1", corev1.PodPending, now.Add(-time.Minute))},
+			Result{Status: Pass, Message: "no pods have been pending for more than 5m0s"}},
+		{"when pods are stuck in pending",
+			[]*corev1.Pod{testPod("app-2", corev1.PodPending, now.Add(-time.Hour)), testPod("app-1", corev1.PodPending, now.Add(-10*time.Minute))},
+			Result{Status: Warn, Message: "2 pods have been pending for more than 5m0s", Details: []string{
+				"pod default/app-1 has been pending for 10m0s",
+				"pod default/app-2 has been pending for 1h0m0s",
+			}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset()
+			for _, pod := range tt.pods {
+				_ = client.Tracker().Add(pod)
+			}
+			check := PendingPods{PendingFor: defaultPendingFor, now: func() time.Time { return now }}
+
+			assert.Equal(t, tt.want, check.Run(Cluster{K8sClient: client}))
+		})
+	}
+}
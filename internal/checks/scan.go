@@ -0,0 +1,157 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"io"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// lastAppliedConfigurationAnnotation is the annotation kubectl apply records the manifest it applied in
+const lastAppliedConfigurationAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// DeprecatedObject is an object of the cluster written through an API version which is removed by the target version
+type DeprecatedObject struct {
+	APIVersion  string `json:"apiVersion"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	RemovedIn   string `json:"removedIn"`
+	Replacement string `json:"replacement"`
+	// FoundIn are where the API version was found, the last-applied-configuration annotation or the managed fields of
+	// the clients writing the object through it, eg: managed fields of helm
+	FoundIn []string `json:"foundIn"`
+}
+
+func (o DeprecatedObject) String() string {
+	name := o.Name
+	if o.Namespace != "" {
+		name = o.Namespace + "/" + o.Name
+	}
+	return fmt.Sprintf("%s %s %s is removed in %s, move to %s, found in %s",
+		o.APIVersion, o.Kind, name, o.RemovedIn, o.Replacement, strings.Join(o.FoundIn, ", "))
+}
+
+// ScanDeprecatedAPIs returns the objects of the cluster which are still written through API versions removed after the
+// Kubernetes version of the API server, up to and including the target version, the next minor version when empty.
+// Every removed API version the cluster still serves is listed through the dynamic client, an object being reported
+// when its last-applied-configuration annotation or one of its managed fields entries uses the API version, as the
+// API server returns the objects of a kind through any of the API versions it serves
+func ScanDeprecatedAPIs(k8sClient kubernetes.Interface, dynamicClient dynamic.Interface, targetVersion string) ([]DeprecatedObject, string, error) {
+	serverVersion, err := k8s.GetServerVersion(k8sClient)
+	if err != nil {
+		return nil, "", err
+	}
+	targetVersion, err = targetVersionOf(serverVersion, targetVersion)
+	if err != nil {
+		return nil, "", err
+	}
+	removed, err := RemovedBetween(serverVersion, targetVersion)
+	if err != nil {
+		return nil, "", err
+	}
+	served, err := servedResources(k8sClient)
+	if err != nil {
+		return nil, "", err
+	}
+
+	objects := []DeprecatedObject{}
+	for _, api := range removed {
+		resource, ok := served[api.GroupVersion+"/"+api.Kind]
+		if !ok {
+			continue
+		}
+		gvr := schema.FromAPIVersionAndKind(api.GroupVersion, api.Kind).GroupVersion().WithResource(resource.Name)
+		list, err := dynamicClient.Resource(gvr).List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			return nil, "", fmt.Errorf("error listing the %s of %s: %v", resource.Name, api.GroupVersion, err)
+		}
+		sort.Slice(list.Items, func(i, j int) bool {
+			return list.Items[i].GetNamespace()+"/"+list.Items[i].GetName() < list.Items[j].GetNamespace()+"/"+list.Items[j].GetName()
+		})
+		for _, object := range list.Items {
+			foundIn := deprecatedAPIUsages(object, api.GroupVersion)
+			if len(foundIn) == 0 {
+				continue
+			}
+			objects = append(objects, DeprecatedObject{APIVersion: api.GroupVersion, Kind: api.Kind,
+				Namespace: object.GetNamespace(), Name: object.GetName(), RemovedIn: api.RemovedIn,
+				Replacement: api.Replacement, FoundIn: foundIn})
+		}
+	}
+	return objects, targetVersion, nil
+}
+
+// deprecatedAPIUsages returns where the object uses the API version: its last-applied-configuration annotation and the
+// managed fields of the clients writing it through the API version
+func deprecatedAPIUsages(object unstructured.Unstructured, groupVersion string) []string {
+	var foundIn []string
+	if lastApplied, ok := object.GetAnnotations()[lastAppliedConfigurationAnnotation]; ok {
+		var manifest struct {
+			APIVersion string `json:"apiVersion"`
+		}
+		if err := json.Unmarshal([]byte(lastApplied), &manifest); err == nil && manifest.APIVersion == groupVersion {
+			foundIn = append(foundIn, "last-applied-configuration")
+		}
+	}
+	for _, entry := range object.GetManagedFields() {
+		if entry.APIVersion == groupVersion {
+			foundIn = append(foundIn, "managed fields of "+entry.Manager)
+		}
+	}
+	return foundIn
+}
+
+// servedResources returns the resources served by the API server, keyed by group version and kind, eg:
+// networking.k8s.io/v1beta1/Ingress
+func servedResources(k8sClient kubernetes.Interface) (map[string]metav1.APIResource, error) {
+	_, resourceLists, err := k8sClient.Discovery().ServerGroupsAndResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("error discovering the APIs served by the cluster: %v", err)
+	}
+	served := map[string]metav1.APIResource{}
+	for _, resourceList := range resourceLists {
+		for _, resource := range resourceList.APIResources {
+			// subresources such as deployments/status share the kind of their resource
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			served[resourceList.GroupVersion+"/"+resource.Kind] = resource
+		}
+	}
+	return served, nil
+}
+
+// WriteDeprecatedObjects writes the objects in the format passed, one of Formats
+func WriteDeprecatedObjects(w io.Writer, format string, objects []DeprecatedObject) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(objects)
+	case "table":
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "API VERSION\tKIND\tNAMESPACE\tNAME\tREMOVED IN\tREPLACEMENT\tFOUND IN")
+		for _, object := range objects {
+			namespace := object.Namespace
+			if namespace == "" {
+				namespace = "-"
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", object.APIVersion, object.Kind, namespace, object.Name,
+				object.RemovedIn, object.Replacement, strings.Join(object.FoundIn, ", "))
+		}
+		return writer.Flush()
+	default:
+		return ValidateFormat(format)
+	}
+}
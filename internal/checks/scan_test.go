@@ -0,0 +1,132 @@
+package checks
+
+import (
+	"bytes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeDiscovery "k8s.io/client-go/discovery/fake"
+	fakeDynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testListKinds = map[schema.GroupVersionResource]string{
+	{Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"}:     "IngressList",
+	{Group: "extensions", Version: "v1beta1", Resource: "ingresses"}:            "IngressList",
+	{Group: "policy", Version: "v1beta1", Resource: "podsecuritypolicies"}:      "PodSecurityPolicyList",
+	{Group: "rbac.authorization.k8s.io", Version: "v1beta1", Resource: "roles"}: "RoleList",
+}
+
+// testIngress returns an ingress served through networking.k8s.io/v1beta1, applied with the API version passed and
+// written by the managers passed through their API versions
+func testIngress(name, appliedAPIVersion string, managers map[string]string) *unstructured.Unstructured {
+	ingress := &unstructured.Unstructured{}
+	ingress.SetAPIVersion("networking.k8s.io/v1beta1")
+	ingress.SetKind("Ingress")
+	ingress.SetNamespace("default")
+	ingress.SetName(name)
+	if appliedAPIVersion != "" {
+		ingress.SetAnnotations(map[string]string{
+			lastAppliedConfigurationAnnotation: `{"apiVersion":"` + appliedAPIVersion + `","kind":"Ingress"}`,
+		})
+	}
+	var managedFields []metav1.ManagedFieldsEntry
+	for manager, apiVersion := range managers {
+		managedFields = append(managedFields, metav1.ManagedFieldsEntry{Manager: manager, APIVersion: apiVersion})
+	}
+	ingress.SetManagedFields(managedFields)
+	return ingress
+}
+
+func testScanClients(objects ...runtime.Object) (*fake.Clientset, *fakeDynamic.FakeDynamicClient) {
+	client := testClient("v1.21.5-eks-bc4871b")
+	client.Discovery().(*fakeDiscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{GroupVersion: "networking.k8s.io/v1beta1", APIResources: []metav1.APIResource{
+			{Name: "ingresses", Kind: "Ingress", Namespaced: true}, {Name: "ingresses/status", Kind: "Ingress", Namespaced: true}}},
+		{GroupVersion: "networking.k8s.io/v1", APIResources: []metav1.APIResource{{Name: "ingresses", Kind: "Ingress", Namespaced: true}}},
+		{GroupVersion: "policy/v1beta1", APIResources: []metav1.APIResource{{Name: "podsecuritypolicies", Kind: "PodSecurityPolicy"}}},
+	}
+	return client, fakeDynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), testListKinds, objects...)
+}
+
+func TestScanDeprecatedAPIs(t *testing.T) {
+	objects := []runtime.Object{
+		testIngress("migrated", "networking.k8s.io/v1", map[string]string{"kubectl-client-side-apply": "networking.k8s.io/v1"}),
+		testIngress("legacy", "networking.k8s.io/v1beta1", map[string]string{"kubectl-client-side-apply": "networking.k8s.io/v1beta1"}),
+		testIngress("controller", "", map[string]string{"ingress-controller": "networking.k8s.io/v1beta1"}),
+	}
+
+	t.Run("when objects are written through API versions removed by the target version", func(t *testing.T) {
+		client, dynamicClient := testScanClients(objects...)
+
+		got, targetVersion, err := ScanDeprecatedAPIs(client, dynamicClient, "1.22")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "1.22", targetVersion)
+		assert.Equal(t, []DeprecatedObject{
+			{APIVersion: "networking.k8s.io/v1beta1", Kind: "Ingress", Namespace: "default", Name: "controller",
+				RemovedIn: "1.22", Replacement: "networking.k8s.io/v1", FoundIn: []string{"managed fields of ingress-controller"}},
+			{APIVersion: "networking.k8s.io/v1beta1", Kind: "Ingress", Namespace: "default", Name: "legacy",
+				RemovedIn: "1.22", Replacement: "networking.k8s.io/v1",
+				FoundIn: []string{"last-applied-configuration", "managed fields of kubectl-client-side-apply"}},
+		}, got)
+	})
+
+	t.Run("when no target version is passed, the next minor version is scanned", func(t *testing.T) {
+		client, dynamicClient := testScanClients(objects...)
+
+		got, targetVersion, err := ScanDeprecatedAPIs(client, dynamicClient, "")
+
+		assert.Nil(t, err)
+		assert.Equal(t, "1.22", targetVersion)
+		assert.Len(t, got, 2)
+	})
+
+	t.Run("when the target version removes none of the API versions used", func(t *testing.T) {
+		client, dynamicClient := testScanClients(objects...)
+
+		got, _, err := ScanDeprecatedAPIs(client, dynamicClient, "1.21")
+
+		assert.Nil(t, err)
+		assert.Equal(t, []DeprecatedObject{}, got)
+	})
+
+	t.Run("when the target version is invalid", func(t *testing.T) {
+		client, dynamicClient := testScanClients(objects...)
+
+		_, _, err := ScanDeprecatedAPIs(client, dynamicClient, "latest")
+
+		assert.EqualError(t, err, "invalid Kubernetes version latest")
+	})
+}
+
+func TestWriteDeprecatedObjects(t *testing.T) {
+	objects := []DeprecatedObject{
+		{APIVersion: "networking.k8s.io/v1beta1", Kind: "Ingress", Namespace: "default", Name: "legacy", RemovedIn: "1.22",
+			Replacement: "networking.k8s.io/v1", FoundIn: []string{"last-applied-configuration", "managed fields of helm"}},
+		{APIVersion: "policy/v1beta1", Kind: "PodSecurityPolicy", Name: "restricted", RemovedIn: "1.25",
+			Replacement: "none, use Pod Security Admission", FoundIn: []string{"last-applied-configuration"}},
+	}
+
+	t.Run("when the format is table", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		assert.Nil(t, WriteDeprecatedObjects(&buffer, "table", objects))
+		assert.Equal(t, `API VERSION                KIND               NAMESPACE  NAME        REMOVED IN  REPLACEMENT                       FOUND IN
+networking.k8s.io/v1beta1  Ingress            default    legacy      1.22        networking.k8s.io/v1              last-applied-configuration, managed fields of helm
+policy/v1beta1             PodSecurityPolicy  -          restricted  1.25        none, use Pod Security Admission  last-applied-configuration
+`, buffer.String())
+	})
+
+	t.Run("when the format is json", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		assert.Nil(t, WriteDeprecatedObjects(&buffer, "json", objects[1:]))
+		assert.JSONEq(t, `[{"apiVersion": "policy/v1beta1", "kind": "PodSecurityPolicy", "name": "restricted", "removedIn": "1.25",
+			"replacement": "none, use Pod Security Admission", "foundIn": ["last-applied-configuration"]}]`, buffer.String())
+	})
+}
@@ -0,0 +1,183 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Status is the state of a component, or of one of its containers, against the version of the config file
+type Status string
+
+const (
+	UpToDate Status = "up-to-date"
+	Drifted  Status = "drifted"
+	// Missing is the status of the components whose k8s object, or one of its containers, is not in the cluster
+	Missing Status = "missing"
+	Error   Status = "error"
+)
+
+// Exit codes of the commands checking the version of components
+const (
+	ExitUpToDate = 0
+	ExitError    = 1
+	ExitDrift    = 2
+)
+
+// Formats are the output formats the report can be written in, matrix being a table of the clusters by the components
+var Formats = []string{"json", "yaml", "table", "matrix"}
+
+// ContainerVersion is the version one of the containers of a component is on
+type ContainerVersion struct {
+	Container  string `json:"container"`
+	Image      string `json:"image"`
+	CurrentTag string `json:"currentTag"`
+	Status     Status `json:"status"`
+}
+
+// ComponentVersion is the version a component is on in a cluster against the version of the config file. The current
+// image and tag are the ones of its main container, the ones of all its containers being listed under Containers
+type ComponentVersion struct {
+	Cluster      string             `json:"cluster"`
+	Component    string             `json:"component"`
+	Kind         string             `json:"kind"`
+	Namespace    string             `json:"namespace"`
+	CurrentImage string             `json:"currentImage"`
+	CurrentTag   string             `json:"currentTag"`
+	DesiredTag   string             `json:"desiredTag"`
+	Status       Status             `json:"status"`
+	Error        string             `json:"error,omitempty"`
+	Containers   []ContainerVersion `json:"containers,omitempty"`
+}
+
+// ExitCode returns ExitError when one of the components could not be checked or is missing, ExitDrift when one of them
+// is not on the version of the config file and failOnDrift is set, and ExitUpToDate otherwise
+func ExitCode(components []ComponentVersion, failOnDrift bool) int {
+	drifted := false
+	for _, component := range components {
+		switch component.Status {
+		case Error, Missing:
+			return ExitError
+		case Drifted:
+			drifted = true
+		}
+	}
+	if drifted && failOnDrift {
+		return ExitDrift
+	}
+	return ExitUpToDate
+}
+
+// ComponentsWithStatus returns the names of the components with the status passed
+func ComponentsWithStatus(components []ComponentVersion, status Status) []string {
+	var names []string
+	for _, component := range components {
+		if component.Status == status {
+			names = append(names, component.Component)
+		}
+	}
+	return names
+}
+
+// ValidateFormat returns an error unless the format is one of Formats
+func ValidateFormat(format string) error {
+	for _, valid := range Formats {
+		if format == valid {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid output format %s, please pass one of %v", format, Formats)
+}
+
+// Write writes the components in the format passed, one of Formats
+func Write(w io.Writer, format string, components []ComponentVersion) error {
+	if components == nil {
+		components = []ComponentVersion{}
+	}
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(components)
+	case "yaml":
+		out, err := yaml.Marshal(components)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	case "table":
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "CLUSTER\tCOMPONENT\tKIND\tNAMESPACE\tCURRENT IMAGE\tCURRENT TAG\tDESIRED TAG\tSTATUS")
+		for _, component := range components {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", component.Cluster, component.Component, component.Kind,
+				component.Namespace, orNone(component.CurrentImage), orNone(component.CurrentTag), component.DesiredTag, component.Status)
+		}
+		return writer.Flush()
+	case "matrix":
+		return writeMatrix(w, components)
+	default:
+		return ValidateFormat(format)
+	}
+}
+
+// writeMatrix writes a table with a row per cluster and a column per component, each cell holding the current version of
+// the component followed by the desired one when it drifted
+func writeMatrix(w io.Writer, components []ComponentVersion) error {
+	var clusters, componentNames []string
+	cells := map[string]map[string]string{}
+	seenComponents := map[string]bool{}
+	for _, component := range components {
+		if _, ok := cells[component.Cluster]; !ok {
+			clusters = append(clusters, component.Cluster)
+			cells[component.Cluster] = map[string]string{}
+		}
+		if !seenComponents[component.Component] {
+			seenComponents[component.Component] = true
+			componentNames = append(componentNames, component.Component)
+		}
+		cells[component.Cluster][component.Component] = matrixCell(component)
+	}
+	sort.Strings(componentNames)
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(append([]string{"CLUSTER"}, upper(componentNames)...), "\t"))
+	for _, cluster := range clusters {
+		row := []string{cluster}
+		for _, componentName := range componentNames {
+			row = append(row, orNone(cells[cluster][componentName]))
+		}
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+func matrixCell(component ComponentVersion) string {
+	switch component.Status {
+	case UpToDate:
+		return component.CurrentTag + " ✓"
+	case Drifted:
+		return component.CurrentTag + " -> " + component.DesiredTag
+	default:
+		return string(component.Status)
+	}
+}
+
+func upper(values []string) []string {
+	var uppercased []string
+	for _, value := range values {
+		uppercased = append(uppercased, strings.ToUpper(value))
+	}
+	return uppercased
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,161 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testComponents() []ComponentVersion {
+	return []ComponentVersion{
+		{Cluster: "cluster1", Component: "coredns", Kind: "deployment", Namespace: "kube-system",
+			CurrentImage: "coredns:1.8.3", CurrentTag: "1.8.3", DesiredTag: "1.8.4", Status: Drifted,
+			Containers: []ContainerVersion{{Container: "coredns", Image: "coredns:1.8.3", CurrentTag: "1.8.3", Status: Drifted}}},
+		{Cluster: "cluster1", Component: "metrics-server", Kind: "deployment", Namespace: "kube-system",
+			DesiredTag: "v0.6.1", Status: Missing, Error: "deployment metrics-server in namespace kube-system not found"},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{"when the format is json", "json", `[
+  {
+    "cluster": "cluster1",
+    "component": "coredns",
+    "kind": "deployment",
+    "namespace": "kube-system",
+    "currentImage": "coredns:1.8.3",
+    "currentTag": "1.8.3",
+    "desiredTag": "1.8.4",
+    "status": "drifted",
+    "containers": [
+      {
+        "container": "coredns",
+        "image": "coredns:1.8.3",
+        "currentTag": "1.8.3",
+        "status": "drifted"
+      }
+    ]
+  },
+  {
+    "cluster": "cluster1",
+    "component": "metrics-server",
+    "kind": "deployment",
+    "namespace": "kube-system",
+    "currentImage": "",
+    "currentTag": "",
+    "desiredTag": "v0.6.1",
+    "status": "missing",
+    "error": "deployment metrics-server in namespace kube-system not found"
+  }
+]
+`},
+		{"when the format is yaml", "yaml", `- cluster: cluster1
+  component: coredns
+  containers:
+  - container: coredns
+    currentTag: 1.8.3
+    image: coredns:1.8.3
+    status: drifted
+  currentImage: coredns:1.8.3
+  currentTag: 1.8.3
+  desiredTag: 1.8.4
+  kind: deployment
+  namespace: kube-system
+  status: drifted
+- cluster: cluster1
+  component: metrics-server
+  currentImage: ""
+  currentTag: ""
+  desiredTag: v0.6.1
+  error: deployment metrics-server in namespace kube-system not found
+  kind: deployment
+  namespace: kube-system
+  status: missing
+`},
+		{"when the format is table", "table", `CLUSTER   COMPONENT       KIND        NAMESPACE    CURRENT IMAGE  CURRENT TAG  DESIRED TAG  STATUS
+cluster1  coredns         deployment  kube-system  coredns:1.8.3  1.8.3        1.8.4        drifted
+cluster1  metrics-server  deployment  kube-system  -              -            v0.6.1       missing
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+
+			err := Write(&out, tt.format, testComponents())
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, out.String())
+		})
+	}
+
+	t.Run("when the format is matrix, there is a row per cluster and a column per component", func(t *testing.T) {
+		components := append(testComponents(),
+			ComponentVersion{Cluster: "cluster2", Component: "coredns", CurrentTag: "1.8.4", DesiredTag: "1.8.4", Status: UpToDate},
+			ComponentVersion{Cluster: "cluster3", Component: "coredns", DesiredTag: "1.8.4", Status: Error,
+				Error: "cluster unreachable: connection refused"})
+		var out bytes.Buffer
+
+		err := Write(&out, "matrix", components)
+
+		assert.Nil(t, err)
+		assert.Equal(t, `CLUSTER   COREDNS         METRICS-SERVER
+cluster1  1.8.3 -> 1.8.4  missing
+cluster2  1.8.4 ✓         -
+cluster3  error           -
+`, out.String())
+	})
+
+	t.Run("when there are no components, an empty list is written", func(t *testing.T) {
+		var out bytes.Buffer
+
+		err := Write(&out, "json", nil)
+
+		assert.Nil(t, err)
+		assert.Equal(t, "[]\n", out.String())
+	})
+}
+
+func TestValidateFormat(t *testing.T) {
+	assert.Nil(t, ValidateFormat("json"))
+	assert.Nil(t, ValidateFormat("yaml"))
+	assert.Nil(t, ValidateFormat("table"))
+	assert.Nil(t, ValidateFormat("matrix"))
+	assert.Equal(t, errors.New("invalid output format xml, please pass one of [json yaml table matrix]"), ValidateFormat("xml"))
+}
+
+func TestExitCode(t *testing.T) {
+	upToDate := ComponentVersion{Component: "aws-node", Status: UpToDate}
+	drifted := ComponentVersion{Component: "coredns", Status: Drifted}
+	missing := ComponentVersion{Component: "metrics-server", Status: Missing}
+	failed := ComponentVersion{Component: "kube-proxy", Status: Error}
+
+	tests := []struct {
+		name        string
+		components  []ComponentVersion
+		failOnDrift bool
+		want        int
+	}{
+		{"when all the components are up to date", []ComponentVersion{upToDate, upToDate}, true, ExitUpToDate},
+		{"when a component drifted and failOnDrift is set", []ComponentVersion{upToDate, drifted}, true, ExitDrift},
+		{"when a component drifted and failOnDrift is not set", []ComponentVersion{upToDate, drifted}, false, ExitUpToDate},
+		{"when a component could not be checked, it wins over the drift", []ComponentVersion{drifted, failed}, true, ExitError},
+		{"when a component is missing", []ComponentVersion{upToDate, missing}, false, ExitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ExitCode(tt.components, tt.failOnDrift))
+		})
+	}
+}
+
+func TestComponentsWithStatus(t *testing.T) {
+	assert.Equal(t, []string{"coredns"}, ComponentsWithStatus(testComponents(), Drifted))
+	assert.Nil(t, ComponentsWithStatus(testComponents(), UpToDate))
+}
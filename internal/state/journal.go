@@ -0,0 +1,158 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// JournalStep is a completed step of a mutating operation, along with the values needed to resume after it
+type JournalStep struct {
+	Name        string    `json:"name"`
+	Values      []string  `json:"values,omitempty"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+// Journal records the steps of a mutating operation run against a cluster as they complete, so that an interrupted run
+// can be resumed from its last completed step
+type Journal struct {
+	ClusterName string        `json:"clusterName"`
+	Operation   string        `json:"operation"`
+	Target      string        `json:"target"`
+	StartedAt   time.Time     `json:"startedAt"`
+	FinishedAt  *time.Time    `json:"finishedAt,omitempty"`
+	Steps       []JournalStep `json:"steps"`
+
+	// Resumed is true when the journal was picked up from an interrupted run
+	Resumed bool `json:"-"`
+
+	store Store
+	mu    sync.Mutex
+}
+
+// OpenJournal returns the journal for the operation run against the target on the cluster. With resume, the journal
+// of the interrupted run is returned, an error is returned if there is none. Otherwise a new journal is started,
+// replacing the one of an earlier run
+func (s Store) OpenJournal(clusterName, operation, target string, resume bool) (*Journal, error) {
+	existing, err := s.loadJournal(clusterName, operation, target)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if resume {
+		if existing == nil || existing.FinishedAt != nil {
+			return nil, fmt.Errorf("no interrupted %s run found for %s on cluster %s to resume", operation, target, clusterName)
+		}
+		existing.Resumed = true
+		return existing, nil
+	}
+
+	journal := &Journal{
+		ClusterName: clusterName,
+		Operation:   operation,
+		Target:      target,
+		StartedAt:   time.Now().UTC(),
+		Steps:       []JournalStep{},
+		store:       s,
+	}
+	return journal, journal.save()
+}
+
+// IsInterrupted returns true if the last run of the operation against the target on the cluster didn't finish and can
+// be resumed
+func (s Store) IsInterrupted(clusterName, operation, target string) (bool, error) {
+	journal, err := s.loadJournal(clusterName, operation, target)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return journal.FinishedAt == nil, nil
+}
+
+// IsCompleted returns true if the step was recorded as completed
+func (j *Journal) IsCompleted(step string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.step(step) != nil
+}
+
+// Values returns the values recorded along with the step, nil if the step was not completed
+func (j *Journal) Values(step string) []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if completed := j.step(step); completed != nil {
+		return completed.Values
+	}
+	return nil
+}
+
+// LastStep returns the name of the last completed step, empty if no step was completed yet
+func (j *Journal) LastStep() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.Steps) == 0 {
+		return ""
+	}
+	return j.Steps[len(j.Steps)-1].Name
+}
+
+// Record marks the step as completed and persists the journal, recording a step twice is a no-op
+func (j *Journal) Record(step string, values ...string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.step(step) != nil {
+		return nil
+	}
+	j.Steps = append(j.Steps, JournalStep{Name: step, Values: values, CompletedAt: time.Now().UTC()})
+	return j.save()
+}
+
+// Finish marks the operation as done, after which it can't be resumed anymore
+func (j *Journal) Finish() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	finishedAt := time.Now().UTC()
+	j.FinishedAt = &finishedAt
+	return j.save()
+}
+
+func (j *Journal) step(name string) *JournalStep {
+	for i := range j.Steps {
+		if j.Steps[i].Name == name {
+			return &j.Steps[i]
+		}
+	}
+	return nil
+}
+
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling the %s journal: %v", j.Operation, err)
+	}
+	return j.store.writeFile(j.ClusterName, journalFileName(j.Operation, j.Target), data)
+}
+
+func (s Store) loadJournal(clusterName, operation, target string) (*Journal, error) {
+	data, err := ioutil.ReadFile(s.path(clusterName, journalFileName(operation, target)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w for %s of %s on cluster %s", ErrNotFound, operation, target, clusterName)
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading the %s journal: %v", operation, err)
+	}
+
+	journal := &Journal{store: s}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("error un marshaling the %s journal: %v", operation, err)
+	}
+	return journal, nil
+}
+
+func journalFileName(operation, target string) string {
+	return fmt.Sprintf("%s-%s.journal.json", operation, target)
+}
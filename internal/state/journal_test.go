@@ -0,0 +1,96 @@
+package state
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStore_OpenJournal(t *testing.T) {
+	t.Run("when a run is interrupted, resuming it returns back the completed steps", func(t *testing.T) {
+		store := testStore(t)
+		journal, err := store.OpenJournal("cluster1", "taint-and-drain", "asg1", false)
+		assert.Nil(t, err)
+		assert.Nil(t, journal.Record("asg-capacity-updated"))
+		assert.Nil(t, journal.Record("node-drained/node-1", "i-1"))
+
+		resumed, err := store.OpenJournal("cluster1", "taint-and-drain", "asg1", true)
+
+		assert.Nil(t, err)
+		assert.True(t, resumed.Resumed)
+		assert.True(t, resumed.IsCompleted("asg-capacity-updated"))
+		assert.True(t, resumed.IsCompleted("node-drained/node-1"))
+		assert.False(t, resumed.IsCompleted("node-drained/node-2"))
+		assert.Equal(t, []string{"i-1"}, resumed.Values("node-drained/node-1"))
+		assert.Equal(t, "node-drained/node-1", resumed.LastStep())
+	})
+
+	t.Run("when a run is not resumed, a new journal is started", func(t *testing.T) {
+		store := testStore(t)
+		journal, _ := store.OpenJournal("cluster1", "taint-and-drain", "asg1", false)
+		_ = journal.Record("asg-capacity-updated")
+
+		restarted, err := store.OpenJournal("cluster1", "taint-and-drain", "asg1", false)
+
+		assert.Nil(t, err)
+		assert.False(t, restarted.Resumed)
+		assert.False(t, restarted.IsCompleted("asg-capacity-updated"))
+		assert.Equal(t, "", restarted.LastStep())
+	})
+
+	t.Run("when there is no interrupted run, resuming returns back an error", func(t *testing.T) {
+		store := testStore(t)
+
+		_, err := store.OpenJournal("cluster1", "taint-and-drain", "asg1", true)
+
+		assert.Equal(t, errors.New("no interrupted taint-and-drain run found for asg1 on cluster cluster1 to resume"), err)
+	})
+
+	t.Run("when the earlier run finished, resuming returns back an error", func(t *testing.T) {
+		store := testStore(t)
+		journal, _ := store.OpenJournal("cluster1", "taint-and-drain", "asg1", false)
+		_ = journal.Record("asg-capacity-updated")
+		assert.Nil(t, journal.Finish())
+
+		_, err := store.OpenJournal("cluster1", "taint-and-drain", "asg1", true)
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestStore_IsInterrupted(t *testing.T) {
+	store := testStore(t)
+	interrupted, _ := store.OpenJournal("cluster1", "taint-and-drain", "asg1", false)
+	_ = interrupted.Record("asg-capacity-updated")
+	finished, _ := store.OpenJournal("cluster1", "taint-and-drain", "asg2", false)
+	assert.Nil(t, finished.Finish())
+
+	tests := []struct {
+		name   string
+		target string
+		want   bool
+	}{
+		{"when the run didn't finish", "asg1", true},
+		{"when the run finished", "asg2", false},
+		{"when the operation was never run", "asg3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.IsInterrupted("cluster1", "taint-and-drain", tt.target)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestJournal_Record(t *testing.T) {
+	store := testStore(t)
+	journal, _ := store.OpenJournal("cluster1", "rotate", "asg1", false)
+
+	assert.Nil(t, journal.Record("asg-scaled-out", "i-1", "i-2"))
+	assert.Nil(t, journal.Record("asg-scaled-out", "i-3"))
+
+	assert.Len(t, journal.Steps, 1)
+	assert.Equal(t, []string{"i-1", "i-2"}, journal.Values("asg-scaled-out"))
+}
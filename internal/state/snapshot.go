@@ -0,0 +1,114 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"k8s.io/client-go/util/homedir"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNotFound is returned when there is nothing stored for the cluster and the ASG asked for
+var ErrNotFound = errors.New("no state found")
+
+// ErrStaleSnapshot is returned when the snapshot stored for an ASG was left behind by a run earlier than the one saving
+// a snapshot, the capacity it recorded may not be the original one of the ASG anymore
+var ErrStaleSnapshot = errors.New("stale snapshot")
+
+// Store persists the state of the upgrade operations of the tool on the local disk, one directory per cluster
+type Store struct {
+	Dir string
+}
+
+// NewStore returns a Store rooted at $HOME/.k8sclusterupgradetool/state
+func NewStore() Store {
+	return Store{Dir: filepath.Join(homedir.HomeDir(), ".k8sclusterupgradetool", "state")}
+}
+
+// AsgSnapshot is the capacity of an ASG, captured before the tool changed it for the first time
+type AsgSnapshot struct {
+	ClusterName string    `json:"clusterName"`
+	AsgName     string    `json:"asgName"`
+	Min         int       `json:"min"`
+	Max         int       `json:"max"`
+	Desired     int       `json:"desired"`
+	CapturedAt  time.Time `json:"capturedAt"`
+}
+
+// SaveAsgSnapshot stores the snapshot unless there is one already for the ASG, so that the capacity captured by the
+// first of many interrupted runs is the one which is kept. runStartedAt is when the run saving the snapshot started,
+// the one it resumes if any: a snapshot captured before it was left behind by an earlier run and is refused with
+// ErrStaleSnapshot. Returns the snapshot which is stored
+func (s Store) SaveAsgSnapshot(snapshot AsgSnapshot, runStartedAt time.Time) (AsgSnapshot, error) {
+	existing, err := s.LoadAsgSnapshot(snapshot.ClusterName, snapshot.AsgName)
+	if err == nil {
+		if existing.CapturedAt.Before(runStartedAt) {
+			return AsgSnapshot{}, fmt.Errorf("%w %s of autoscaling group %s taken at %s, before the run started at %s",
+				ErrStaleSnapshot, s.path(snapshot.ClusterName, asgSnapshotFileName(snapshot.AsgName)), snapshot.AsgName,
+				existing.CapturedAt, runStartedAt)
+		}
+		return existing, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return AsgSnapshot{}, err
+	}
+
+	if snapshot.CapturedAt.IsZero() {
+		snapshot.CapturedAt = time.Now().UTC()
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return AsgSnapshot{}, fmt.Errorf("error marshaling the snapshot of autoscaling group %s: %v", snapshot.AsgName, err)
+	}
+	if err := s.writeFile(snapshot.ClusterName, asgSnapshotFileName(snapshot.AsgName), data); err != nil {
+		return AsgSnapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// LoadAsgSnapshot returns the stored snapshot of the ASG, ErrNotFound if there is none
+func (s Store) LoadAsgSnapshot(clusterName, asgName string) (AsgSnapshot, error) {
+	data, err := ioutil.ReadFile(s.path(clusterName, asgSnapshotFileName(asgName)))
+	if os.IsNotExist(err) {
+		return AsgSnapshot{}, fmt.Errorf("%w for autoscaling group %s of cluster %s", ErrNotFound, asgName, clusterName)
+	} else if err != nil {
+		return AsgSnapshot{}, fmt.Errorf("error reading the snapshot of autoscaling group %s: %v", asgName, err)
+	}
+
+	var snapshot AsgSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return AsgSnapshot{}, fmt.Errorf("error un marshaling the snapshot of autoscaling group %s: %v", asgName, err)
+	}
+	return snapshot, nil
+}
+
+// DeleteAsgSnapshot removes the snapshot of the ASG, once its capacity has been restored
+func (s Store) DeleteAsgSnapshot(clusterName, asgName string) error {
+	err := os.Remove(s.path(clusterName, asgSnapshotFileName(asgName)))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting the snapshot of autoscaling group %s: %v", asgName, err)
+	}
+	return nil
+}
+
+func (s Store) path(clusterName, fileName string) string {
+	return filepath.Join(s.Dir, clusterName, fileName)
+}
+
+func (s Store) writeFile(clusterName, fileName string, data []byte) error {
+	if err := os.MkdirAll(filepath.Join(s.Dir, clusterName), 0700); err != nil {
+		return fmt.Errorf("error creating the state directory for cluster %s: %v", clusterName, err)
+	}
+	// write to a temporary file first, so that an interruption doesn't leave a truncated file behind
+	tmpPath := s.path(clusterName, fileName) + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("error writing %s: %v", tmpPath, err)
+	}
+	return os.Rename(tmpPath, s.path(clusterName, fileName))
+}
+
+func asgSnapshotFileName(asgName string) string {
+	return fmt.Sprintf("asg-%s.snapshot.json", asgName)
+}
@@ -0,0 +1,80 @@
+package state
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T) Store {
+	dir, err := ioutil.TempDir("", "k8sclusterupgradetool-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return Store{Dir: dir}
+}
+
+func TestStore_SaveAsgSnapshot(t *testing.T) {
+	capturedAt := time.Date(2022, 2, 16, 23, 54, 0, 0, time.UTC)
+
+	t.Run("when there is no snapshot for the ASG, the snapshot is stored", func(t *testing.T) {
+		store := testStore(t)
+		snapshot := AsgSnapshot{ClusterName: "cluster1", AsgName: "asg1", Min: 1, Max: 10, Desired: 3, CapturedAt: capturedAt}
+
+		saved, err := store.SaveAsgSnapshot(snapshot, capturedAt.Add(-time.Minute))
+
+		assert.Nil(t, err)
+		assert.Equal(t, snapshot, saved)
+		loaded, err := store.LoadAsgSnapshot("cluster1", "asg1")
+		assert.Nil(t, err)
+		assert.Equal(t, snapshot, loaded)
+	})
+
+	t.Run("when there is a snapshot for the ASG already taken by the same run, it is kept", func(t *testing.T) {
+		store := testStore(t)
+		runStartedAt := capturedAt.Add(-time.Minute)
+		first := AsgSnapshot{ClusterName: "cluster1", AsgName: "asg1", Min: 1, Max: 10, Desired: 3, CapturedAt: capturedAt}
+		_, _ = store.SaveAsgSnapshot(first, runStartedAt)
+
+		saved, err := store.SaveAsgSnapshot(AsgSnapshot{ClusterName: "cluster1", AsgName: "asg1", Min: 1, Max: 3, Desired: 3}, runStartedAt)
+
+		assert.Nil(t, err)
+		assert.Equal(t, first, saved)
+	})
+
+	t.Run("when there is a snapshot for the ASG left behind by an earlier run, it is refused", func(t *testing.T) {
+		store := testStore(t)
+		first := AsgSnapshot{ClusterName: "cluster1", AsgName: "asg1", Min: 1, Max: 10, Desired: 3, CapturedAt: capturedAt}
+		_, _ = store.SaveAsgSnapshot(first, capturedAt.Add(-time.Minute))
+
+		_, err := store.SaveAsgSnapshot(AsgSnapshot{ClusterName: "cluster1", AsgName: "asg1", Min: 1, Max: 3, Desired: 3},
+			capturedAt.Add(time.Hour))
+
+		assert.True(t, errors.Is(err, ErrStaleSnapshot))
+		loaded, _ := store.LoadAsgSnapshot("cluster1", "asg1")
+		assert.Equal(t, first, loaded)
+	})
+}
+
+func TestStore_LoadAsgSnapshot(t *testing.T) {
+	store := testStore(t)
+
+	_, err := store.LoadAsgSnapshot("cluster1", "asg1")
+
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestStore_DeleteAsgSnapshot(t *testing.T) {
+	store := testStore(t)
+	_, _ = store.SaveAsgSnapshot(AsgSnapshot{ClusterName: "cluster1", AsgName: "asg1", Min: 1, Max: 10, Desired: 3}, time.Time{})
+
+	assert.Nil(t, store.DeleteAsgSnapshot("cluster1", "asg1"))
+	_, err := store.LoadAsgSnapshot("cluster1", "asg1")
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	assert.Nil(t, store.DeleteAsgSnapshot("cluster1", "asg1"))
+}
@@ -0,0 +1,154 @@
+package upgrade
+
+import (
+	"fmt"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/checks"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// notRegistered is the kubelet version reported for the instances of an ASG which are not nodes of the cluster yet
+const notRegistered = "not-registered"
+
+// NodeVersion is a node of the cluster along with the instance and the ASG it runs on, and its kubelet version
+type NodeVersion struct {
+	Name       string
+	InstanceId string
+	// AsgName is empty when the instance of the node is not part of any ASG of the cluster, eg: a Fargate node
+	AsgName        string
+	KubeletVersion string
+	// Skew is why the kubelet version is outside of the version skew supported by the API server, empty when supported
+	Skew string
+}
+
+// NodeGroupVersions is an ASG of the cluster along with the kubelet versions of its nodes
+type NodeGroupVersions struct {
+	AsgName         string
+	Instances       int
+	KubeletVersions []string
+	// Upgraded is true only when every instance of the ASG is a node running the minor version of the API server
+	Upgraded bool
+}
+
+// NodeVersionReport is the kubelet version of every node of a cluster compared with the version of its API server
+type NodeVersionReport struct {
+	Cluster       string
+	ServerVersion string
+	// Nodes are grouped by kubelet version, sorted by node name within a version
+	Nodes      []NodeVersion
+	NodeGroups []NodeGroupVersions
+}
+
+// NewNodeVersionReport maps the nodes to the instances of the ASGs of the cluster through the EC2 instance id of their
+// provider id, and flags the kubelet versions outside of the version skew supported by the API server
+func NewNodeVersionReport(cluster, serverVersion string, nodes []k8s.NodeVersion, instances aws.AwsInstances) NodeVersionReport {
+	report := NodeVersionReport{Cluster: cluster, ServerVersion: serverVersion}
+	kubeletVersions := map[string]string{}
+	for _, node := range nodes {
+		nodeVersion := NodeVersion{Name: node.Name, KubeletVersion: node.KubeletVersion,
+			Skew: checks.KubeletSkewViolation(serverVersion, "", node.KubeletVersion)}
+		if instanceId, err := aws.InstanceIdFromProviderID(node.ProviderID); err == nil {
+			nodeVersion.InstanceId = instanceId
+			kubeletVersions[instanceId] = node.KubeletVersion
+			if instance, ok := instances.FindByInstanceId(instanceId); ok {
+				nodeVersion.AsgName = instance.AsgName
+			}
+		}
+		report.Nodes = append(report.Nodes, nodeVersion)
+	}
+	sort.SliceStable(report.Nodes, func(i, j int) bool {
+		return report.Nodes[i].KubeletVersion < report.Nodes[j].KubeletVersion
+	})
+
+	serverMinor, _ := config.MinorVersion(serverVersion)
+	groups := map[string]*NodeGroupVersions{}
+	var asgNames []string
+	for _, instance := range instances {
+		group, ok := groups[instance.AsgName]
+		if !ok {
+			group = &NodeGroupVersions{AsgName: instance.AsgName, Upgraded: true}
+			groups[instance.AsgName] = group
+			asgNames = append(asgNames, instance.AsgName)
+		}
+		version, ok := kubeletVersions[instance.InstanceId]
+		if !ok {
+			version = notRegistered
+		}
+		group.Instances++
+		if !containsString(group.KubeletVersions, version) {
+			group.KubeletVersions = append(group.KubeletVersions, version)
+		}
+		if minor, ok := config.MinorVersion(version); !ok || minor != serverMinor {
+			group.Upgraded = false
+		}
+	}
+	sort.Strings(asgNames)
+	for _, asgName := range asgNames {
+		sort.Strings(groups[asgName].KubeletVersions)
+		report.NodeGroups = append(report.NodeGroups, *groups[asgName])
+	}
+	return report
+}
+
+// Skewed returns the nodes whose kubelet version is outside of the version skew supported by the API server
+func (r NodeVersionReport) Skewed() []NodeVersion {
+	var skewed []NodeVersion
+	for _, node := range r.Nodes {
+		if node.Skew != "" {
+			skewed = append(skewed, node)
+		}
+	}
+	return skewed
+}
+
+// Write prints the nodes grouped by kubelet version, followed by the ASGs and whether they are fully upgraded
+func (r NodeVersionReport) Write(w io.Writer) error {
+	fmt.Fprintf(w, "API server version of cluster %s: %s\n\n", r.Cluster, r.ServerVersion)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KUBELET VERSION\tNODE\tINSTANCE\tASG\tSKEW")
+	for _, node := range r.Nodes {
+		skew := "supported"
+		if node.Skew != "" {
+			skew = "kubelet " + node.Skew
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", node.KubeletVersion, node.Name, orNone(node.InstanceId),
+			orNone(node.AsgName), skew)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ASG\tINSTANCES\tKUBELET VERSIONS\tUPGRADED")
+	for _, group := range r.NodeGroups {
+		upgraded := "no"
+		if group.Upgraded {
+			upgraded = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", group.AsgName, group.Instances, strings.Join(group.KubeletVersions, ","), upgraded)
+	}
+	return tw.Flush()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package upgrade
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/k8s"
+	"github.com/stretchr/testify/assert"
+)
+
+func testNodeVersionReport() NodeVersionReport {
+	nodes := []k8s.NodeVersion{
+		{Name: "node-1", ProviderID: "aws:///eu-west-1a/i-1", KubeletVersion: "v1.22.17-eks-0a21954"},
+		{Name: "node-2", ProviderID: "aws:///eu-west-1b/i-2", KubeletVersion: "v1.21.14-eks-ba74326"},
+		{Name: "node-3", ProviderID: "aws:///eu-west-1a/i-3", KubeletVersion: "v1.19.15-eks-9c63c4"},
+		{Name: "node-4", ProviderID: "aws:///eu-west-1c/i-4", KubeletVersion: "v1.22.17-eks-0a21954"},
+		{Name: "fargate-ip-10-0-1-2", ProviderID: "aws:///eu-west-1a/2f3d7b1a0e/fargate-ip-10-0-1-2", KubeletVersion: "v1.22.6-eks-7d68063"},
+	}
+	instances := aws.AwsInstances{
+		{InstanceId: "i-1", PrivateDNS: "node-1", AsgName: "asg-new"},
+		{InstanceId: "i-4", PrivateDNS: "node-4", AsgName: "asg-new"},
+		{InstanceId: "i-2", PrivateDNS: "node-2", AsgName: "asg-mixed"},
+		{InstanceId: "i-5", PrivateDNS: "node-5", AsgName: "asg-mixed"},
+		{InstanceId: "i-3", PrivateDNS: "node-3", AsgName: "asg-old"},
+	}
+	return NewNodeVersionReport("cluster1", "v1.22.17-eks-7f3c7ba", nodes, instances)
+}
+
+func TestNewNodeVersionReport(t *testing.T) {
+	report := testNodeVersionReport()
+
+	assert.Equal(t, []NodeVersion{
+		{Name: "node-3", InstanceId: "i-3", AsgName: "asg-old", KubeletVersion: "v1.19.15-eks-9c63c4",
+			Skew: "is more than 2 minor versions older than 1.22"},
+		{Name: "node-2", InstanceId: "i-2", AsgName: "asg-mixed", KubeletVersion: "v1.21.14-eks-ba74326"},
+		{Name: "node-1", InstanceId: "i-1", AsgName: "asg-new", KubeletVersion: "v1.22.17-eks-0a21954"},
+		{Name: "node-4", InstanceId: "i-4", AsgName: "asg-new", KubeletVersion: "v1.22.17-eks-0a21954"},
+		{Name: "fargate-ip-10-0-1-2", KubeletVersion: "v1.22.6-eks-7d68063"},
+	}, report.Nodes)
+	assert.Equal(t, []NodeGroupVersions{
+		{AsgName: "asg-mixed", Instances: 2, KubeletVersions: []string{"not-registered", "v1.21.14-eks-ba74326"}},
+		{AsgName: "asg-new", Instances: 2, KubeletVersions: []string{"v1.22.17-eks-0a21954"}, Upgraded: true},
+		{AsgName: "asg-old", Instances: 1, KubeletVersions: []string{"v1.19.15-eks-9c63c4"}},
+	}, report.NodeGroups)
+	assert.Equal(t, []NodeVersion{report.Nodes[0]}, report.Skewed())
+}
+
+func TestNodeVersionReport_Write(t *testing.T) {
+	var buffer bytes.Buffer
+
+	assert.Nil(t, testNodeVersionReport().Write(&buffer))
+	assert.Equal(t, `API server version of cluster cluster1: v1.22.17-eks-7f3c7ba
+
+KUBELET VERSION       NODE                 INSTANCE  ASG        SKEW
+v1.19.15-eks-9c63c4   node-3               i-3       asg-old    kubelet is more than 2 minor versions older than 1.22
+v1.21.14-eks-ba74326  node-2               i-2       asg-mixed  supported
+v1.22.17-eks-0a21954  node-1               i-1       asg-new    supported
+v1.22.17-eks-0a21954  node-4               i-4       asg-new    supported
+v1.22.6-eks-7d68063   fargate-ip-10-0-1-2  <none>    <none>     supported
+
+ASG        INSTANCES  KUBELET VERSIONS                     UPGRADED
+asg-mixed  2          not-registered,v1.21.14-eks-ba74326  no
+asg-new    2          v1.22.17-eks-0a21954                 yes
+asg-old    1          v1.19.15-eks-9c63c4                  no
+`, buffer.String())
+}
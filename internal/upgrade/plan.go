@@ -0,0 +1,126 @@
+package upgrade
+
+import (
+	"fmt"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/config"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/api/aws"
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/report"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// StepKind is what a step of the upgrade of a cluster acts on
+type StepKind string
+
+const (
+	ControlPlaneStep StepKind = "control-plane"
+	ComponentStep    StepKind = "component"
+	NodeGroupStep    StepKind = "node-group"
+)
+
+// Step is a step of the upgrade of a cluster
+type Step struct {
+	Kind StepKind
+	// Name is the name of the component or of the ASG of the node group, empty for the control plane
+	Name string
+	// From is the version the control plane or the component is on, or the kubelet versions of the nodes of the node
+	// group
+	From string
+	// To is the version the control plane or the component is moved to, or the Kubernetes minor version of the new
+	// nodes of the node group
+	To string
+}
+
+func (s Step) String() string {
+	switch s.Kind {
+	case ControlPlaneStep:
+		return fmt.Sprintf("upgrade the control plane from %s to %s", s.From, s.To)
+	case ComponentStep:
+		return fmt.Sprintf("set %s from %s to %s", s.Name, s.From, s.To)
+	default:
+		return fmt.Sprintf("taint and drain the nodes of %s running kubelet %s", s.Name, s.From)
+	}
+}
+
+// NodeGroup is a node group of the cluster along with the kubelet versions its nodes run
+type NodeGroup struct {
+	AsgName         string
+	KubeletVersions []string
+	// Drained is true when all the instances of the ASG are nodes which are cordoned, as left by an earlier taint and
+	// drain of the node group
+	Drained bool
+}
+
+// Plan is the ordered list of steps upgrading a cluster to a Kubernetes version
+type Plan struct {
+	Cluster           string
+	KubernetesVersion string
+	Steps             []Step
+}
+
+// NewPlan returns the steps upgrading the cluster from the current Kubernetes version of its control plane to the target
+// version: the control plane first, then the components not on the version of the config file in the order of the
+// components passed, and finally the node groups whose nodes don't run the target Kubernetes minor version yet and
+// weren't drained already
+func NewPlan(cluster, currentVersion, targetVersion string, components []report.ComponentVersion, nodeGroups []NodeGroup) (Plan, error) {
+	if targetVersion == "" {
+		targetVersion = currentVersion
+	}
+	targetMinor, ok := config.MinorVersion(targetVersion)
+	if !ok {
+		return Plan{}, fmt.Errorf("invalid Kubernetes version %s", targetVersion)
+	}
+	plan := Plan{Cluster: cluster, KubernetesVersion: targetMinor}
+
+	if currentMinor, _ := config.MinorVersion(currentVersion); currentMinor != targetMinor {
+		if err := aws.ValidateControlPlaneUpgrade(currentMinor, targetMinor); err != nil {
+			return Plan{}, err
+		}
+		plan.Steps = append(plan.Steps, Step{Kind: ControlPlaneStep, From: currentMinor, To: targetMinor})
+	}
+
+	for _, component := range components {
+		switch component.Status {
+		case report.UpToDate:
+			continue
+		case report.Drifted:
+			plan.Steps = append(plan.Steps, Step{Kind: ComponentStep, Name: component.Component,
+				From: component.CurrentTag, To: component.DesiredTag})
+		default:
+			return Plan{}, fmt.Errorf("%s of cluster %s is %s: %s", component.Component, cluster, component.Status, component.Error)
+		}
+	}
+
+	for _, nodeGroup := range nodeGroups {
+		if nodeGroup.Drained {
+			continue
+		}
+		for _, kubeletVersion := range nodeGroup.KubeletVersions {
+			if minor, ok := config.MinorVersion(kubeletVersion); ok && minor != targetMinor {
+				plan.Steps = append(plan.Steps, Step{Kind: NodeGroupStep, Name: nodeGroup.AsgName,
+					From: strings.Join(nodeGroup.KubeletVersions, ","), To: targetMinor})
+				break
+			}
+		}
+	}
+	return plan, nil
+}
+
+// Write prints the steps of the plan as a table
+func (p Plan) Write(w io.Writer) error {
+	if len(p.Steps) == 0 {
+		_, err := fmt.Fprintf(w, "cluster %s is up to date for Kubernetes %s, nothing to apply\n", p.Cluster, p.KubernetesVersion)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tKIND\tNAME\tFROM\tTO")
+	for i, step := range p.Steps {
+		name := step.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", i+1, step.Kind, name, step.From, step.To)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,107 @@
+package upgrade
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/deliveryhero/k8s-cluster-upgrade-tool/internal/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func testComponents() []report.ComponentVersion {
+	return []report.ComponentVersion{
+		{Cluster: "cluster1", Component: "kube-proxy", CurrentTag: "v1.21.2", DesiredTag: "v1.22.11", Status: report.Drifted},
+		{Cluster: "cluster1", Component: "aws-node", CurrentTag: "v1.11.4", DesiredTag: "v1.11.4", Status: report.UpToDate},
+		{Cluster: "cluster1", Component: "coredns", CurrentTag: "v1.8.4", DesiredTag: "v1.8.7", Status: report.Drifted},
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	nodeGroups := []NodeGroup{
+		{AsgName: "asg-old", KubeletVersions: []string{"v1.21.14-eks-ba74326"}},
+		{AsgName: "asg-new", KubeletVersions: []string{"v1.22.17-eks-0a21954"}},
+		{AsgName: "asg-mixed", KubeletVersions: []string{"not-registered", "v1.21.14-eks-ba74326", "v1.22.17-eks-0a21954"}},
+		{AsgName: "asg-empty"},
+		{AsgName: "asg-drained", KubeletVersions: []string{"v1.21.14-eks-ba74326"}, Drained: true},
+	}
+	tests := []struct {
+		name           string
+		currentVersion string
+		targetVersion  string
+		components     []report.ComponentVersion
+		want           []Step
+		err            error
+	}{
+		{"when the control plane is upgraded, it comes first followed by the components and the node groups not drained yet",
+			"1.21", "1.22", testComponents(),
+			[]Step{
+				{Kind: ControlPlaneStep, From: "1.21", To: "1.22"},
+				{Kind: ComponentStep, Name: "kube-proxy", From: "v1.21.2", To: "v1.22.11"},
+				{Kind: ComponentStep, Name: "coredns", From: "v1.8.4", To: "v1.8.7"},
+				{Kind: NodeGroupStep, Name: "asg-old", From: "v1.21.14-eks-ba74326", To: "1.22"},
+				{Kind: NodeGroupStep, Name: "asg-mixed",
+					From: "not-registered,v1.21.14-eks-ba74326,v1.22.17-eks-0a21954", To: "1.22"},
+			}, nil},
+		{"when the control plane already runs the target version, only the components and node groups are left",
+			"1.22", "v1.22", testComponents()[1:],
+			[]Step{
+				{Kind: ComponentStep, Name: "coredns", From: "v1.8.4", To: "v1.8.7"},
+				{Kind: NodeGroupStep, Name: "asg-old", From: "v1.21.14-eks-ba74326", To: "1.22"},
+				{Kind: NodeGroupStep, Name: "asg-mixed",
+					From: "not-registered,v1.21.14-eks-ba74326,v1.22.17-eks-0a21954", To: "1.22"},
+			}, nil},
+		{"when no target version is passed, the one of the control plane is used", "1.22", "", nil,
+			[]Step{
+				{Kind: NodeGroupStep, Name: "asg-old", From: "v1.21.14-eks-ba74326", To: "1.22"},
+				{Kind: NodeGroupStep, Name: "asg-mixed",
+					From: "not-registered,v1.21.14-eks-ba74326,v1.22.17-eks-0a21954", To: "1.22"},
+			}, nil},
+		{"when the target version skips a minor version", "1.21", "1.23", nil, nil,
+			errors.New("the control plane can only be upgraded one minor version at a time, from 1.21 to 1.22, not to 1.23")},
+		{"when the target version is invalid", "1.21", "latest", nil, nil, errors.New("invalid Kubernetes version latest")},
+		{"when a component could not be checked", "1.21", "1.22",
+			[]report.ComponentVersion{{Cluster: "cluster1", Component: "coredns", Status: report.Missing,
+				Error: "deployment coredns in namespace kube-system not found"}},
+			nil, errors.New("coredns of cluster cluster1 is missing: deployment coredns in namespace kube-system not found")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := NewPlan("cluster1", tt.currentVersion, tt.targetVersion, tt.components, nodeGroups)
+			assert.Equal(t, tt.want, plan.Steps)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+}
+
+func TestPlan_Write(t *testing.T) {
+	t.Run("when the plan has steps they are printed in order", func(t *testing.T) {
+		plan := Plan{Cluster: "cluster1", KubernetesVersion: "1.22", Steps: []Step{
+			{Kind: ControlPlaneStep, From: "1.21", To: "1.22"},
+			{Kind: ComponentStep, Name: "kube-proxy", From: "v1.21.2", To: "v1.22.11"},
+			{Kind: NodeGroupStep, Name: "asg-old", From: "v1.21.14-eks-ba74326", To: "1.22"},
+		}}
+		var buffer bytes.Buffer
+
+		assert.Nil(t, plan.Write(&buffer))
+		assert.Equal(t, `STEP  KIND           NAME        FROM                  TO
+1     control-plane  -           1.21                  1.22
+2     component      kube-proxy  v1.21.2               v1.22.11
+3     node-group     asg-old     v1.21.14-eks-ba74326  1.22
+`, buffer.String())
+	})
+
+	t.Run("when the plan has no steps", func(t *testing.T) {
+		var buffer bytes.Buffer
+
+		assert.Nil(t, Plan{Cluster: "cluster1", KubernetesVersion: "1.22"}.Write(&buffer))
+		assert.Equal(t, "cluster cluster1 is up to date for Kubernetes 1.22, nothing to apply\n", buffer.String())
+	})
+}
+
+func TestStep_String(t *testing.T) {
+	assert.Equal(t, "upgrade the control plane from 1.21 to 1.22", Step{Kind: ControlPlaneStep, From: "1.21", To: "1.22"}.String())
+	assert.Equal(t, "set coredns from v1.8.4 to v1.8.7", Step{Kind: ComponentStep, Name: "coredns", From: "v1.8.4", To: "v1.8.7"}.String())
+	assert.Equal(t, "taint and drain the nodes of asg-old running kubelet v1.21.14",
+		Step{Kind: NodeGroupStep, Name: "asg-old", From: "v1.21.14", To: "1.22"}.String())
+}